@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,6 +13,9 @@ import (
 	"github.com/codepnw/go-starter-kit/internal/config"
 	"github.com/codepnw/go-starter-kit/internal/server"
 	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/logger"
+	"github.com/codepnw/go-starter-kit/pkg/tlsconfig"
+	"github.com/codepnw/go-starter-kit/pkg/tracing"
 )
 
 const envPath = ".env"
@@ -23,6 +27,20 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Logger
+	l, closeLog, err := logger.New(cfg.Log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeLog()
+	slog.SetDefault(l)
+
+	// Tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Connect Database
 	db, err := database.ConnectPostgres(cfg)
 	if err != nil {
@@ -37,15 +55,51 @@ func main() {
 	}
 
 	httpSrv := &http.Server{
-		Addr:         cfg.GetAppAddress(),
-		Handler:      srv.Handler(),
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:              cfg.GetAppAddress(),
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+
+	// TLS (optional client certificate verification)
+	if cfg.TLS.Enabled {
+		tlsCfg, err := tlsconfig.NewServerTLS(cfg.TLS)
+		if err != nil {
+			log.Fatal(err)
+		}
+		httpSrv.TLSConfig = tlsCfg
+	}
+
+	// Admin Server: metrics off the public listener
+	var adminSrv *http.Server
+	if cfg.Metrics.Enabled {
+		adminSrv = &http.Server{
+			Addr:              cfg.Metrics.Addr,
+			Handler:           srv.MetricsHandler(),
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			ReadTimeout:       cfg.HTTP.ReadTimeout,
+			WriteTimeout:      cfg.HTTP.WriteTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+		}
+		go func() {
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("admin listen: %v", err)
+			}
+		}()
 	}
 
 	// Start Server
 	go func() {
-		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if cfg.TLS.Enabled {
+			// Certificates are already loaded in TLSConfig
+			err = httpSrv.ListenAndServeTLS("", "")
+		} else {
+			err = httpSrv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("listen: %v", err)
 		}
 	}()
@@ -61,5 +115,13 @@ func main() {
 	if err := httpSrv.Shutdown(ctx); err != nil {
 		log.Fatalf("server forced shutdown: %v", err)
 	}
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(ctx); err != nil {
+			log.Fatalf("admin server forced shutdown: %v", err)
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("flush traces failed", slog.String("error", err.Error()))
+	}
 	log.Println("server existing")
 }
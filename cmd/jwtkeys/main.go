@@ -0,0 +1,264 @@
+// Command jwtkeys rotates the JWT signing keys kept in the env file.
+//
+// Rotation without logging anyone out, run on the env file every instance reads:
+//
+//	jwtkeys add -type access -kid k2      # new key, verify only
+//	(deploy: every instance now accepts k2)
+//	jwtkeys promote -type access -kid k2  # k2 signs new tokens
+//	(deploy, wait for the access token TTL)
+//	jwtkeys retire -type access -kid k1   # tokens signed with k1 are rejected
+//
+// The first add next to JWT_SECRET_KEY / JWT_REFRESH_KEY lists that secret as "legacy"
+// ahead of the new key, so it keeps signing until the promote; retire it with -kid legacy.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/joho/godotenv"
+)
+
+const (
+	envAccessKeys         = "JWT_ACCESS_KEYS"
+	envRefreshKeys        = "JWT_REFRESH_KEYS"
+	envAccessRetiredKeys  = "JWT_ACCESS_RETIRED_KEYS"
+	envRefreshRetiredKeys = "JWT_REFRESH_RETIRED_KEYS"
+	envAccessSecret       = "JWT_SECRET_KEY"
+	envRefreshSecret      = "JWT_REFRESH_KEY"
+)
+
+// keySet : the env variables of one token type. Each type has its own retired list, so
+// retiring an access kid never touches a refresh key with the same kid.
+type keySet struct {
+	keys    string
+	retired string
+	legacy  string
+}
+
+var keySets = map[string]keySet{
+	"access":  {keys: envAccessKeys, retired: envAccessRetiredKeys, legacy: envAccessSecret},
+	"refresh": {keys: envRefreshKeys, retired: envRefreshRetiredKeys, legacy: envRefreshSecret},
+}
+
+const usage = `usage: jwtkeys <list|add|promote|retire> [flags]
+
+  list                          show keys and their state
+  add     -type T -kid ID       append a new random key (verify only)
+  promote -type T -kid ID       make the key the signing key
+  retire  -type T -kid ID       reject tokens signed with the key
+
+flags:
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	envPath := fs.String("env", ".env", "env file to update")
+	keyType := fs.String("type", "access", "token type: access or refresh")
+	kid := fs.String("kid", "", "key id")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[2:])
+
+	env, err := godotenv.Read(*envPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	set, ok := keySets[*keyType]
+	if !ok {
+		log.Fatalf("invalid -type %q, want access or refresh", *keyType)
+	}
+
+	if cmd == "list" {
+		for _, t := range []string{"access", "refresh"} {
+			s := keySets[t]
+			printKeys(s.keys, splitList(env[s.keys]), splitList(env[s.retired]))
+		}
+		return
+	}
+
+	updates, err := run(cmd, env, set, *kid)
+	if errors.Is(err, errUnknownCommand) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := updateEnvFile(*envPath, updates); err != nil {
+		log.Fatal(err)
+	}
+	printKeys(set.keys, splitList(updates[set.keys]), splitList(updates[set.retired]))
+}
+
+var errUnknownCommand = errors.New("unknown command")
+
+// run applies cmd to the key set in env and returns the variables to write back.
+func run(cmd string, env map[string]string, set keySet, kid string) (map[string]string, error) {
+	keys := splitList(env[set.keys])
+	retired := splitList(env[set.retired])
+
+	var err error
+	switch cmd {
+	case "add":
+		keys, err = addKey(keys, kid, env[set.legacy] != "")
+	case "promote":
+		keys, err = promoteKey(keys, retired, kid)
+	case "retire":
+		retired, err = retireKey(keys, retired, kid)
+	default:
+		return nil, errUnknownCommand
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		set.keys:    strings.Join(keys, ","),
+		set.retired: strings.Join(retired, ","),
+	}, nil
+}
+
+// addKey appends a random key. The first key added next to a single secret is put after
+// the "legacy" entry, so the single secret keeps signing until the new key is promoted.
+func addKey(keys []string, kid string, legacy bool) ([]string, error) {
+	if err := checkKeyID(kid); err != nil {
+		return nil, err
+	}
+	if indexOf(keys, kid) >= 0 {
+		return nil, fmt.Errorf("key %q already exists", kid)
+	}
+	if len(keys) == 0 && legacy {
+		keys = []string{jwttoken.LegacyEntry}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	// Appended: a new key only verifies until it is promoted
+	return append(keys, kid+"="+base64.RawURLEncoding.EncodeToString(secret)), nil
+}
+
+func promoteKey(keys, retired []string, kid string) ([]string, error) {
+	i := indexOf(keys, kid)
+	if i < 0 {
+		return nil, fmt.Errorf("key %q not found", kid)
+	}
+	if slices.Contains(retired, kid) {
+		return nil, fmt.Errorf("key %q is retired", kid)
+	}
+
+	entry := keys[i]
+	return append([]string{entry}, slices.Delete(slices.Clone(keys), i, i+1)...), nil
+}
+
+func retireKey(keys, retired []string, kid string) ([]string, error) {
+	i := indexOf(keys, kid)
+	if i < 0 {
+		return nil, fmt.Errorf("key %q not found", kid)
+	}
+	if i == 0 {
+		return nil, fmt.Errorf("key %q is the signing key, promote another key first", kid)
+	}
+	if slices.Contains(retired, kid) {
+		return retired, nil
+	}
+	return append(retired, kid), nil
+}
+
+func printKeys(name string, keys, retired []string) {
+	fmt.Println(name)
+	for i, entry := range keys {
+		kid := keyID(entry)
+		state := "verify"
+		switch {
+		case slices.Contains(retired, kid):
+			state = "retired"
+		case i == 0:
+			state = "active"
+		}
+		fmt.Printf("  %-16s %s\n", kid, state)
+	}
+}
+
+func checkKeyID(kid string) error {
+	if kid == "" || kid == jwttoken.LegacyEntry || strings.ContainsAny(kid, "=, \t#") {
+		return fmt.Errorf("invalid -kid %q", kid)
+	}
+	return nil
+}
+
+func indexOf(keys []string, kid string) int {
+	return slices.IndexFunc(keys, func(entry string) bool {
+		return keyID(entry) == kid
+	})
+}
+
+// keyID : "kid=secret" -> kid
+func keyID(entry string) string {
+	kid, _, _ := strings.Cut(entry, "=")
+	return kid
+}
+
+func splitList(v string) []string {
+	var list []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// updateEnvFile rewrites the given variables in place, keeping comments and order.
+// Variables not in the file yet are appended.
+func updateEnvFile(path string, updates map[string]string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	done := make(map[string]bool, len(updates))
+	for i, line := range lines {
+		name, _, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "export "))
+		value, found := updates[name]
+		if !ok || !found {
+			continue
+		}
+		lines[i] = name + "=" + value
+		done[name] = true
+	}
+	for _, name := range []string{envAccessKeys, envRefreshKeys, envAccessRetiredKeys, envRefreshRetiredKeys} {
+		if value, found := updates[name]; found && !done[name] && value != "" {
+			lines = append(lines, name+"="+value)
+		}
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), info.Mode().Perm())
+}
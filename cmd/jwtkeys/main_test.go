@@ -0,0 +1,196 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	type testCase struct {
+		name        string
+		cmd         string
+		set         keySet
+		kid         string
+		expected    map[string]string
+		expectedErr bool
+	}
+
+	env := map[string]string{
+		envAccessKeys:         "k2=s2,k1=s1",
+		envRefreshKeys:        "k2=r2,k1=r1",
+		envAccessRetiredKeys:  "k0",
+		envRefreshRetiredKeys: "",
+	}
+
+	testCases := []testCase{
+		{
+			name: "success promote",
+			cmd:  "promote",
+			set:  keySets["access"],
+			kid:  "k1",
+			expected: map[string]string{
+				envAccessKeys:        "k1=s1,k2=s2",
+				envAccessRetiredKeys: "k0",
+			},
+		},
+		{
+			name: "success retire access only",
+			cmd:  "retire",
+			set:  keySets["access"],
+			kid:  "k1",
+			expected: map[string]string{
+				envAccessKeys:        "k2=s2,k1=s1",
+				envAccessRetiredKeys: "k0,k1",
+			},
+		},
+		{
+			name: "success retire refresh only",
+			cmd:  "retire",
+			set:  keySets["refresh"],
+			kid:  "k1",
+			expected: map[string]string{
+				envRefreshKeys:        "k2=r2,k1=r1",
+				envRefreshRetiredKeys: "k1",
+			},
+		},
+		{
+			// k0 is retired and no longer listed
+			name:        "fail retire unlisted key",
+			cmd:         "retire",
+			set:         keySets["access"],
+			kid:         "k0",
+			expectedErr: true,
+		},
+		{
+			name:        "fail retire signing key",
+			cmd:         "retire",
+			set:         keySets["access"],
+			kid:         "k2",
+			expectedErr: true,
+		},
+		{
+			name:        "fail promote unknown key",
+			cmd:         "promote",
+			set:         keySets["refresh"],
+			kid:         "k9",
+			expectedErr: true,
+		},
+		{
+			name:        "fail add existing key",
+			cmd:         "add",
+			set:         keySets["access"],
+			kid:         "k1",
+			expectedErr: true,
+		},
+		{
+			name:        "fail add invalid kid",
+			cmd:         "add",
+			set:         keySets["access"],
+			kid:         "k3=x",
+			expectedErr: true,
+		},
+		{
+			name:        "fail unknown command",
+			cmd:         "rotate",
+			set:         keySets["access"],
+			kid:         "k1",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		updates, err := run(tc.cmd, env, tc.set, tc.kid)
+
+		if tc.expectedErr {
+			assert.Error(t, err, tc.name)
+			continue
+		}
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.expected, updates, tc.name)
+	}
+}
+
+func TestRunAdd(t *testing.T) {
+	updates, err := run("add", map[string]string{envRefreshKeys: "r1=s1"}, keySets["refresh"], "r2")
+	require.NoError(t, err)
+
+	// Appended, so it only verifies until promoted
+	keys := splitList(updates[envRefreshKeys])
+	require.Len(t, keys, 2)
+	assert.Equal(t, "r1=s1", keys[0])
+	assert.True(t, strings.HasPrefix(keys[1], "r2="))
+	assert.Len(t, strings.TrimPrefix(keys[1], "r2="), 43) // base64url of 32 bytes
+	assert.Empty(t, updates[envRefreshRetiredKeys])
+}
+
+func TestRunPromoteRetired(t *testing.T) {
+	env := map[string]string{
+		envAccessKeys:         "k2=s2,k1=s1",
+		envAccessRetiredKeys:  "k1",
+		envRefreshRetiredKeys: "",
+	}
+
+	_, err := run("promote", env, keySets["access"], "k1")
+	assert.Error(t, err)
+
+	// Retired as an access key only: a refresh key with the same kid is not affected
+	env[envRefreshKeys] = "k2=r2,k1=r1"
+	_, err = run("promote", env, keySets["refresh"], "k1")
+	assert.NoError(t, err)
+}
+
+func TestUpdateEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# JWT\nJWT_ACCESS_KEYS=k1=s1\nexport JWT_ACCESS_RETIRED_KEYS=\nAPP_PORT=8080\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	err := updateEnvFile(path, map[string]string{
+		envAccessKeys:         "k2=s2,k1=s1",
+		envAccessRetiredKeys:  "k0",
+		envRefreshRetiredKeys: "r0",
+	})
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# JWT\nJWT_ACCESS_KEYS=k2=s2,k1=s1\nJWT_ACCESS_RETIRED_KEYS=k0\nAPP_PORT=8080\nJWT_REFRESH_RETIRED_KEYS=r0\n", string(b))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestRunMigrateLegacy(t *testing.T) {
+	env := map[string]string{envAccessSecret: "single-secret"}
+
+	// The single secret keeps signing, the new key only verifies
+	updates, err := run("add", env, keySets["access"], "k1")
+	require.NoError(t, err)
+	keys := splitList(updates[envAccessKeys])
+	require.Len(t, keys, 2)
+	assert.Equal(t, "legacy", keys[0])
+	assert.True(t, strings.HasPrefix(keys[1], "k1="))
+
+	env[envAccessKeys] = updates[envAccessKeys]
+	updates, err = run("promote", env, keySets["access"], "k1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{keys[1], "legacy"}, splitList(updates[envAccessKeys]))
+
+	env[envAccessKeys] = updates[envAccessKeys]
+	updates, err = run("retire", env, keySets["access"], "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", updates[envAccessRetiredKeys])
+
+	// Without a single secret the first key signs right away
+	updates, err = run("add", map[string]string{}, keySets["refresh"], "r1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(updates[envRefreshKeys], "r1="))
+
+	_, err = run("add", env, keySets["access"], "legacy")
+	assert.Error(t, err, "reserved kid")
+}
@@ -3,8 +3,10 @@ package auth
 import (
 	"context"
 	"errors"
+	"slices"
 
 	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
 )
 
@@ -26,4 +28,71 @@ func GetUserIDFromContext(ctx context.Context) (string, error) {
 
 func SetContextUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, config.ContextUserIDKey, userID)
-}
\ No newline at end of file
+}
+
+// GetOrgIDFromContext returns the active organization (tenant) of the current token.
+func GetOrgIDFromContext(ctx context.Context) (string, error) {
+	orgID, ok := ctx.Value(config.ContextOrgIDKey).(string)
+	if !ok || orgID == "" {
+		return "", errs.ErrNoActiveOrganization
+	}
+	return orgID, nil
+}
+
+func SetContextOrgID(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, config.ContextOrgIDKey, orgID)
+}
+
+// ServicePrincipal is a machine caller authenticated by its TLS client certificate.
+type ServicePrincipal struct {
+	Name       string   `json:"name"`
+	Subject    string   `json:"subject"`
+	CommonName string   `json:"common_name,omitempty"`
+	DNSNames   []string `json:"dns_names,omitempty"`
+	URIs       []string `json:"uris,omitempty"`
+}
+
+// Matches reports whether the principal's common name or any SAN is in allowed.
+func (p *ServicePrincipal) Matches(allowed []string) bool {
+	for _, a := range allowed {
+		if a == p.Name || a == p.CommonName || slices.Contains(p.DNSNames, a) || slices.Contains(p.URIs, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func GetServicePrincipalFromContext(ctx context.Context) (*ServicePrincipal, error) {
+	principal, ok := ctx.Value(config.ContextServiceKey).(*ServicePrincipal)
+	if !ok {
+		return nil, errors.New("get service principal context failed")
+	}
+	return principal, nil
+}
+
+func SetContextServicePrincipal(ctx context.Context, principal *ServicePrincipal) context.Context {
+	return context.WithValue(ctx, config.ContextServiceKey, principal)
+}
+
+// GetClientIDFromContext returns the API client verified by request signing.
+func GetClientIDFromContext(ctx context.Context) (string, error) {
+	clientID, ok := ctx.Value(config.ContextClientIDKey).(string)
+	if !ok {
+		return "", errors.New("get client id context failed")
+	}
+	return clientID, nil
+}
+
+func SetContextClientID(ctx context.Context, clientID string) context.Context {
+	return context.WithValue(ctx, config.ContextClientIDKey, clientID)
+}
+
+// GetDPoPKeyFromContext returns the thumbprint of the key that signed the request's DPoP proof.
+func GetDPoPKeyFromContext(ctx context.Context) (string, bool) {
+	jkt, ok := ctx.Value(config.ContextDPoPKeyKey).(string)
+	return jkt, ok && jkt != ""
+}
+
+func SetContextDPoPKey(ctx context.Context, jkt string) context.Context {
+	return context.WithValue(ctx, config.ContextDPoPKeyKey, jkt)
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -12,21 +15,39 @@ import (
 type contextKey string
 
 const (
-	// JWT token duration
-	AccessTokenDuration  = time.Minute * 30
-	RefreshTokenDuration = time.Hour * 24 * 7
-
 	// Context keys
 	ContextUserClaimsKey contextKey = "ctx-user-claims"
 	ContextUserIDKey     contextKey = "ctx-user-id"
-
-	ContextTimeout = time.Second * 10
+	ContextOrgIDKey      contextKey = "ctx-org-id"
+	ContextServiceKey    contextKey = "ctx-service-principal"
+	ContextClientIDKey   contextKey = "ctx-client-id"
+	ContextDPoPKeyKey    contextKey = "ctx-dpop-jkt"
+	ContextRequestIDKey  contextKey = "ctx-request-id"
+	ContextTraceIDKey    contextKey = "ctx-trace-id"
+	ContextCSPNonceKey   contextKey = "ctx-csp-nonce"
 )
 
 type EnvConfig struct {
-	APP AppConfig `envPrefix:"APP_"`
-	DB  DBConfig  `envPrefix:"DB_"`
-	JWT JWTConfig `envPrefix:"JWT_"`
+	APP    AppConfig    `envPrefix:"APP_"`
+	HTTP   HTTPConfig   `envPrefix:"HTTP_"`
+	Error  ErrorConfig  `envPrefix:"ERROR_"`
+	DB     DBConfig     `envPrefix:"DB_"`
+	JWT    JWTConfig    `envPrefix:"JWT_"`
+	Mail   MailConfig   `envPrefix:"MAIL_"`
+	Invite InviteConfig `envPrefix:"INVITE_"`
+	TLS    TLSConfig    `envPrefix:"TLS_"`
+	HMAC   HMACConfig   `envPrefix:"HMAC_"`
+	OAuth  OAuthConfig  `envPrefix:"OAUTH_"`
+
+	RateLimit   RateLimitConfig   `envPrefix:"RATE_LIMIT_"`
+	Nonce       NonceConfig       `envPrefix:"NONCE_"`
+	LoadShed    LoadShedConfig    `envPrefix:"LOAD_SHED_"`
+	Log         LogConfig         `envPrefix:"LOG_"`
+	Metrics     MetricsConfig     `envPrefix:"METRICS_"`
+	Tracing     TracingConfig     `envPrefix:"TRACING_"`
+	CORS        CORSConfig        `envPrefix:"CORS_"`
+	Security    SecurityConfig    `envPrefix:"SECURITY_"`
+	Idempotency IdempotencyConfig `envPrefix:"IDEMPOTENCY_"`
 }
 
 type AppConfig struct {
@@ -35,6 +56,202 @@ type AppConfig struct {
 	Prefix string `env:"PREFIX" envDefault:"/api/v1"`
 }
 
+// HTTPConfig : server timeouts, plus the deadline and body size every route gets unless
+// server.go sets its own with mid.Timeout / mid.BodyLimit. AuthRequestTimeout is the
+// deadline of the password (bcrypt) routes, shorter so a login spike fails fast.
+// WriteTimeout must leave room for the longest route deadline, or the connection is cut
+// before the 504 is written.
+type HTTPConfig struct {
+	ReadHeaderTimeout  time.Duration `env:"READ_HEADER_TIMEOUT" envDefault:"5s"`
+	ReadTimeout        time.Duration `env:"READ_TIMEOUT" envDefault:"15s"`
+	WriteTimeout       time.Duration `env:"WRITE_TIMEOUT" envDefault:"30s"`
+	IdleTimeout        time.Duration `env:"IDLE_TIMEOUT" envDefault:"120s"`
+	RequestTimeout     time.Duration `env:"REQUEST_TIMEOUT" envDefault:"10s" validate:"gt=0"`
+	AuthRequestTimeout time.Duration `env:"AUTH_REQUEST_TIMEOUT" envDefault:"5s" validate:"gt=0"`
+	MaxBodySize        int64         `env:"MAX_BODY_SIZE" envDefault:"1048576" validate:"gt=0"`
+	// TrustedProxies : IPs or CIDRs of the reverse proxies in front of the server. Only
+	// their X-Forwarded-* headers are used (client IP, DPoP htu); none are trusted by default.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:"," validate:"dive,cidr|ip"`
+}
+
+func (c *HTTPConfig) validate() error {
+	if longest := max(c.RequestTimeout, c.AuthRequestTimeout); c.WriteTimeout > 0 && c.WriteTimeout <= longest {
+		return fmt.Errorf("HTTP write timeout %s must be longer than request timeout %s", c.WriteTimeout, longest)
+	}
+	return nil
+}
+
+// ErrorConfig : Format is problem (RFC 7807 application/problem+json) or envelope, the
+// {"success": false, "error": ...} body. ProblemTypeBaseURL, if set, prefixes the error
+// code to build the problem type URI (https://example.com/problems/user_not_found).
+type ErrorConfig struct {
+	Format             string `env:"FORMAT" envDefault:"problem" validate:"oneof=problem envelope"`
+	ProblemTypeBaseURL string `env:"PROBLEM_TYPE_BASE_URL" validate:"omitempty,url"`
+}
+
+// LogConfig : Output is stdout, stderr or a file path. SampleRate keeps that share of
+// debug/info records (1 keeps all); warnings and errors are always kept.
+type LogConfig struct {
+	Level      string  `env:"LEVEL" envDefault:"info" validate:"oneof=debug info warn error DEBUG INFO WARN ERROR"`
+	Format     string  `env:"FORMAT" envDefault:"text" validate:"oneof=text json"`
+	Output     string  `env:"OUTPUT" envDefault:"stderr"`
+	SampleRate float64 `env:"SAMPLE_RATE" envDefault:"1" validate:"gt=0,lte=1"`
+	AddSource  bool    `env:"ADD_SOURCE" envDefault:"false"`
+}
+
+// MetricsConfig : Path is served, without auth, on its own (admin) listener at Addr and
+// never on the API router, so it stays off the public network. Loopback by default.
+type MetricsConfig struct {
+	Enabled bool   `env:"ENABLED" envDefault:"true"`
+	Path    string `env:"PATH" envDefault:"/metrics" validate:"startswith=/"`
+	Addr    string `env:"ADDR" envDefault:"127.0.0.1:9090" validate:"hostname_port"`
+}
+
+// TracingConfig : Exporter stdout prints spans as JSON, otlp sends them over OTLP/HTTP
+// to Endpoint (host:port; empty uses the OTEL_EXPORTER_OTLP_* variables). SampleRatio
+// applies to new traces only, an incoming traceparent keeps the caller's decision.
+type TracingConfig struct {
+	Enabled     bool    `env:"ENABLED" envDefault:"false"`
+	Exporter    string  `env:"EXPORTER" envDefault:"stdout" validate:"oneof=stdout otlp"`
+	Endpoint    string  `env:"ENDPOINT"`
+	Insecure    bool    `env:"INSECURE" envDefault:"false"`
+	ServiceName string  `env:"SERVICE_NAME" envDefault:"go-starter-kit"`
+	SampleRatio float64 `env:"SAMPLE_RATIO" envDefault:"1" validate:"gte=0,lte=1"`
+}
+
+// CORSConfig : origins are exact (https://app.example.com), one-level subdomain patterns
+// (https://*.example.com) or "*", which cannot be combined with AllowCredentials.
+// Group* override the policy of one route group, keyed by its path under the API prefix,
+// with "|" between list items: /integrations:https://partner.example.com|https://*.partner.io
+type CORSConfig struct {
+	AllowOrigins     []string      `env:"ALLOW_ORIGINS" envSeparator:"," envDefault:"*"`
+	AllowMethods     []string      `env:"ALLOW_METHODS" envSeparator:"," envDefault:"GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS"`
+	AllowHeaders     []string      `env:"ALLOW_HEADERS" envSeparator:"," envDefault:"Origin,Content-Type,Accept,Authorization,X-Request-ID,Idempotency-Key,traceparent,tracestate"`
+	ExposeHeaders    []string      `env:"EXPOSE_HEADERS" envSeparator:"," envDefault:"Content-Length,X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Retry-After,Idempotent-Replayed,Link"`
+	AllowCredentials bool          `env:"ALLOW_CREDENTIALS" envDefault:"false"`
+	MaxAge           time.Duration `env:"MAX_AGE" envDefault:"12h"`
+
+	GroupAllowOrigins     map[string]string `env:"GROUP_ALLOW_ORIGINS"`
+	GroupAllowMethods     map[string]string `env:"GROUP_ALLOW_METHODS"`
+	GroupAllowHeaders     map[string]string `env:"GROUP_ALLOW_HEADERS"`
+	GroupAllowCredentials map[string]bool   `env:"GROUP_ALLOW_CREDENTIALS"`
+}
+
+// CORSPolicy : the effective policy of a route group
+type CORSPolicy struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// corsOriginPattern : scheme://host[:port], the host may start with "*."
+var corsOriginPattern = regexp.MustCompile(`^[a-z][a-z0-9+.\-]*://(\*\.)?[A-Za-z0-9.\-]+(:[0-9]+)?$`)
+
+// Groups returns the route groups that override the base policy.
+func (c *CORSConfig) Groups() []string {
+	seen := make(map[string]bool)
+	for g := range c.GroupAllowOrigins {
+		seen[g] = true
+	}
+	for g := range c.GroupAllowMethods {
+		seen[g] = true
+	}
+	for g := range c.GroupAllowHeaders {
+		seen[g] = true
+	}
+	for g := range c.GroupAllowCredentials {
+		seen[g] = true
+	}
+
+	groups := make([]string, 0, len(seen))
+	for g := range seen {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// Policy returns the base policy with the overrides of group applied, "" for the base.
+func (c *CORSConfig) Policy(group string) CORSPolicy {
+	p := CORSPolicy{
+		AllowOrigins:     c.AllowOrigins,
+		AllowMethods:     c.AllowMethods,
+		AllowHeaders:     c.AllowHeaders,
+		ExposeHeaders:    c.ExposeHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+	if group == "" {
+		return p
+	}
+	if v, ok := c.GroupAllowOrigins[group]; ok {
+		p.AllowOrigins = strings.Split(v, "|")
+	}
+	if v, ok := c.GroupAllowMethods[group]; ok {
+		p.AllowMethods = strings.Split(v, "|")
+	}
+	if v, ok := c.GroupAllowHeaders[group]; ok {
+		p.AllowHeaders = strings.Split(v, "|")
+	}
+	if v, ok := c.GroupAllowCredentials[group]; ok {
+		p.AllowCredentials = v
+	}
+	return p
+}
+
+// validate rejects origins browsers would never match and "*" with credentials, which
+// browsers refuse and which would let any site make authenticated calls.
+func (c *CORSConfig) validate() error {
+	for _, group := range append([]string{""}, c.Groups()...) {
+		name := "CORS"
+		if group != "" {
+			if !strings.HasPrefix(group, "/") {
+				return fmt.Errorf("CORS group %q: must start with /", group)
+			}
+			name = fmt.Sprintf("CORS group %s", group)
+		}
+
+		p := c.Policy(group)
+		if len(p.AllowOrigins) == 0 {
+			return fmt.Errorf("%s: no allowed origins", name)
+		}
+		for _, origin := range p.AllowOrigins {
+			if origin == "*" {
+				if p.AllowCredentials {
+					return fmt.Errorf("%s: origin * cannot be used with allow credentials", name)
+				}
+				continue
+			}
+			if !corsOriginPattern.MatchString(origin) {
+				return fmt.Errorf("%s: invalid origin %q", name, origin)
+			}
+		}
+	}
+	return nil
+}
+
+// SecurityConfig : hardening headers on every response. CSP applies to the JSON API and
+// CSPHTML to the HTML pages; {nonce} in either is replaced by a per-request nonce.
+// HSTSMaxAge 0 and empty string values leave that header out.
+type SecurityConfig struct {
+	Enabled               bool          `env:"ENABLED" envDefault:"true"`
+	HSTSMaxAge            time.Duration `env:"HSTS_MAX_AGE" envDefault:"8760h"`
+	HSTSIncludeSubdomains bool          `env:"HSTS_INCLUDE_SUBDOMAINS" envDefault:"true"`
+	HSTSPreload           bool          `env:"HSTS_PRELOAD" envDefault:"false"`
+	CSP                   string        `env:"CSP" envDefault:"default-src 'none'; frame-ancestors 'none'; base-uri 'none'"`
+	CSPHTML               string        `env:"CSP_HTML" envDefault:"default-src 'none'; script-src 'nonce-{nonce}'; connect-src 'self'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'"`
+	CSPReportOnly         bool          `env:"CSP_REPORT_ONLY" envDefault:"false"`
+	CSPReportURI          string        `env:"CSP_REPORT_URI"`
+	ContentTypeNosniff    bool          `env:"CONTENT_TYPE_NOSNIFF" envDefault:"true"`
+	ReferrerPolicy        string        `env:"REFERRER_POLICY" envDefault:"no-referrer"`
+	PermissionsPolicy     string        `env:"PERMISSIONS_POLICY" envDefault:"accelerometer=(), camera=(), geolocation=(), microphone=(), payment=(), usb=()"`
+	COOP                  string        `env:"COOP" envDefault:"same-origin" validate:"omitempty,oneof=same-origin same-origin-allow-popups unsafe-none"`
+	COEP                  string        `env:"COEP" envDefault:"require-corp" validate:"omitempty,oneof=require-corp credentialless unsafe-none"`
+}
+
 type DBConfig struct {
 	User     string `env:"USER" validate:"required"`
 	Password string `env:"PASSWORD" validate:"required"`
@@ -45,9 +262,151 @@ type DBConfig struct {
 }
 
 type JWTConfig struct {
-	AppName    string `env:"APP_NAME" envDefault:"Go Starter Kit"`
-	SecretKey  string `env:"SECRET_KEY" validate:"required"`
-	RefreshKey string `env:"REFRESH_KEY" validate:"required"`
+	AppName string `env:"APP_NAME" envDefault:"Go Starter Kit"`
+	// Single-secret setup. With key lists below, these only verify tokens issued without a kid.
+	SecretKey  string `env:"SECRET_KEY" validate:"required_without=AccessKeys"`
+	RefreshKey string `env:"REFRESH_KEY" validate:"required_without=RefreshKeys"`
+
+	// Rotating keys: ordered "kid=secret" lists, the first signs and all verify.
+	// Kids in the retired list of the same token type are rejected, see cmd/jwtkeys.
+	AccessKeys         []string `env:"ACCESS_KEYS" envSeparator:","`
+	RefreshKeys        []string `env:"REFRESH_KEYS" envSeparator:","`
+	AccessRetiredKeys  []string `env:"ACCESS_RETIRED_KEYS" envSeparator:","`
+	RefreshRetiredKeys []string `env:"REFRESH_RETIRED_KEYS" envSeparator:","`
+
+	// Token lifetimes, with optional per-client overrides e.g. "cli:1h,mobile:15m".
+	// RefreshTokenTTL caps every refresh token, sessions may end earlier (see Session).
+	AccessTokenTTL        time.Duration            `env:"ACCESS_TOKEN_TTL" envDefault:"30m"`
+	RefreshTokenTTL       time.Duration            `env:"REFRESH_TOKEN_TTL" envDefault:"2160h"`
+	ClientAccessTokenTTL  map[string]time.Duration `env:"CLIENT_ACCESS_TOKEN_TTL"`
+	ClientRefreshTokenTTL map[string]time.Duration `env:"CLIENT_REFRESH_TOKEN_TTL"`
+
+	Session SessionConfig `envPrefix:"SESSION_"`
+
+	// Audience of tokens issued for this API, defaults to AppName. Issuer is always AppName.
+	Audience string        `env:"AUDIENCE"`
+	Leeway   time.Duration `env:"LEEWAY" envDefault:"30s"`
+
+	// EncryptionKeys : "kid=base64(32 bytes)" list, first is active. Set to issue encrypted (JWE) tokens.
+	EncryptionKeys []string `env:"ENCRYPTION_KEYS" envSeparator:","`
+	EncryptionAlg  string   `env:"ENCRYPTION_ALG" envDefault:"dir" validate:"oneof=dir A256KW"`
+
+	// DPoP (RFC 9449): clients listed here only get sender-constrained tokens
+	DPoPRequiredClients []string      `env:"DPOP_REQUIRED_CLIENTS" envSeparator:","`
+	DPoPProofWindow     time.Duration `env:"DPOP_PROOF_WINDOW" envDefault:"1m"`
+}
+
+// SessionConfig : sliding refresh sessions. Each refresh extends the session by the idle
+// timeout, never past the absolute timeout counted from login. "Remember me" logins use
+// the longer profile.
+//
+// MaxSessions caps active sessions per user (0 = unlimited). LimitPolicy decides what a
+// login over the limit does: evict_oldest revokes the oldest session, reject fails the login.
+type SessionConfig struct {
+	IdleTimeout             time.Duration `env:"IDLE_TIMEOUT" envDefault:"24h"`
+	AbsoluteTimeout         time.Duration `env:"ABSOLUTE_TIMEOUT" envDefault:"168h"`
+	RememberIdleTimeout     time.Duration `env:"REMEMBER_IDLE_TIMEOUT" envDefault:"720h"`
+	RememberAbsoluteTimeout time.Duration `env:"REMEMBER_ABSOLUTE_TIMEOUT" envDefault:"2160h"`
+	MaxSessions             int           `env:"MAX_SESSIONS" envDefault:"0" validate:"gte=0"`
+	LimitPolicy             string        `env:"LIMIT_POLICY" envDefault:"evict_oldest" validate:"oneof=evict_oldest reject"`
+}
+
+// AccessTTL returns the access token lifetime for a client.
+func (c *JWTConfig) AccessTTL(clientID string) time.Duration {
+	if ttl, ok := c.ClientAccessTokenTTL[clientID]; ok && clientID != "" {
+		return ttl
+	}
+	return c.AccessTokenTTL
+}
+
+// RefreshTTL returns the maximum refresh token lifetime for a client.
+func (c *JWTConfig) RefreshTTL(clientID string) time.Duration {
+	if ttl, ok := c.ClientRefreshTokenTTL[clientID]; ok && clientID != "" {
+		return ttl
+	}
+	return c.RefreshTokenTTL
+}
+
+// MailConfig : empty Host logs mails instead of sending them
+type MailConfig struct {
+	Host     string `env:"HOST"`
+	Port     int    `env:"PORT" envDefault:"587"`
+	Username string `env:"USERNAME"`
+	Password string `env:"PASSWORD"`
+	From     string `env:"FROM" envDefault:"no-reply@localhost"`
+}
+
+type InviteConfig struct {
+	SecretKey string        `env:"SECRET_KEY" validate:"required"`
+	TTL       time.Duration `env:"TTL" envDefault:"72h"`
+	AcceptURL string        `env:"ACCEPT_URL" envDefault:"http://localhost:3000/invitations/accept"`
+}
+
+// TLSConfig : ClientAuth is one of none, request, verify_if_given, require
+type TLSConfig struct {
+	Enabled         bool     `env:"ENABLED" envDefault:"false"`
+	CertFile        string   `env:"CERT_FILE" validate:"required_if=Enabled true"`
+	KeyFile         string   `env:"KEY_FILE" validate:"required_if=Enabled true"`
+	ClientCAFile    string   `env:"CLIENT_CA_FILE"`
+	ClientAuth      string   `env:"CLIENT_AUTH" envDefault:"verify_if_given" validate:"oneof=none request verify_if_given require"`
+	AllowedServices []string `env:"ALLOWED_SERVICES" envSeparator:","`
+}
+
+// HMACConfig : request signing for machine clients
+type HMACConfig struct {
+	MaxSkew      time.Duration `env:"MAX_SKEW" envDefault:"5m"`
+	MaxBodyBytes int64         `env:"MAX_BODY_BYTES" envDefault:"1048576"`
+}
+
+type OAuthConfig struct {
+	// DeviceClients : public clients allowed to use the device authorization grant
+	DeviceClients   []string      `env:"DEVICE_CLIENTS" envSeparator:"," envDefault:"cli"`
+	DeviceCodeTTL   time.Duration `env:"DEVICE_CODE_TTL" envDefault:"10m"`
+	PollInterval    time.Duration `env:"POLL_INTERVAL" envDefault:"5s"`
+	VerificationURI string        `env:"VERIFICATION_URI" envDefault:"http://localhost:8080/api/v1/oauth/device"`
+	// DeviceLoginTTL : lifetime of the verification page's login, long enough to approve one code
+	DeviceLoginTTL time.Duration `env:"DEVICE_LOGIN_TTL" envDefault:"5m"`
+
+	// Token exchange (RFC 8693): actors are service principals from the TLS client certificate
+	ExchangeActors    []string      `env:"EXCHANGE_ACTORS" envSeparator:","`
+	ExchangeAudiences []string      `env:"EXCHANGE_AUDIENCES" envSeparator:","`
+	ExchangeTokenTTL  time.Duration `env:"EXCHANGE_TOKEN_TTL" envDefault:"5m"`
+}
+
+// RateLimitConfig : limits themselves are declared per route in server.go. Store is
+// memory (per instance) or postgres (shared by all instances).
+type RateLimitConfig struct {
+	Enabled bool   `env:"ENABLED" envDefault:"true"`
+	Store   string `env:"STORE" envDefault:"memory" validate:"oneof=memory postgres"`
+}
+
+// NonceConfig : where used HMAC nonces and DPoP proof IDs are remembered, memory (per
+// instance, a replay on another instance goes through) or postgres (shared by all instances).
+type NonceConfig struct {
+	Store string `env:"STORE" envDefault:"memory" validate:"oneof=memory postgres"`
+}
+
+// IdempotencyConfig : responses to requests with an Idempotency-Key are kept for TTL
+// in Postgres. A request still unfinished after LockTimeout is considered dead, and a
+// retry with the same payload may run again.
+type IdempotencyConfig struct {
+	Enabled     bool          `env:"ENABLED" envDefault:"true"`
+	TTL         time.Duration `env:"TTL" envDefault:"24h"`
+	LockTimeout time.Duration `env:"LOCK_TIMEOUT" envDefault:"30s"`
+}
+
+// LoadShedConfig : in-flight request caps, one for the whole API and a tighter one for
+// the password (bcrypt) routes. Overflow waits up to QueueTimeout, then gets 503.
+// With Adaptive the caps shrink when requests get slower than TargetLatency.
+type LoadShedConfig struct {
+	Enabled         bool          `env:"ENABLED" envDefault:"true"`
+	MaxInFlight     int           `env:"MAX_IN_FLIGHT" envDefault:"512" validate:"gte=1"`
+	AuthMaxInFlight int           `env:"AUTH_MAX_IN_FLIGHT" envDefault:"32" validate:"gte=1"`
+	MaxQueue        int           `env:"MAX_QUEUE" envDefault:"64" validate:"gte=0"`
+	QueueTimeout    time.Duration `env:"QUEUE_TIMEOUT" envDefault:"100ms"`
+	Adaptive        bool          `env:"ADAPTIVE" envDefault:"false"`
+	MinInFlight     int           `env:"MIN_IN_FLIGHT" envDefault:"4" validate:"gte=1"`
+	TargetLatency   time.Duration `env:"TARGET_LATENCY" envDefault:"500ms"`
 }
 
 func LoadConfig(path string) (*EnvConfig, error) {
@@ -66,6 +425,12 @@ func LoadConfig(path string) (*EnvConfig, error) {
 	if err := validate.Struct(cfg); err != nil {
 		return nil, fmt.Errorf("validate env failed: %w", err)
 	}
+	if err := cfg.HTTP.validate(); err != nil {
+		return nil, fmt.Errorf("validate env failed: %w", err)
+	}
+	if err := cfg.CORS.validate(); err != nil {
+		return nil, fmt.Errorf("validate env failed: %w", err)
+	}
 	return cfg, nil
 }
 
@@ -0,0 +1,167 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadConfig loads an empty .env with the required settings and env on top.
+func loadConfig(t *testing.T, env map[string]string) (*config.EnvConfig, error) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(envPath, nil, 0o600))
+
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_PASSWORD", "postgres")
+	t.Setenv("DB_NAME", "starter")
+	t.Setenv("JWT_SECRET_KEY", "access-secret")
+	t.Setenv("JWT_REFRESH_KEY", "refresh-secret")
+	t.Setenv("INVITE_SECRET_KEY", "invite-secret")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	return config.LoadConfig(envPath)
+}
+
+func TestLoadConfigCORS(t *testing.T) {
+	type testCase struct {
+		name        string
+		env         map[string]string
+		expectedErr string
+	}
+
+	testCases := []testCase{
+		{
+			name: "success defaults",
+		},
+		{
+			name: "success credentials with explicit origins",
+			env: map[string]string{
+				"CORS_ALLOW_ORIGINS":     "https://app.example.com,https://*.example.com,http://localhost:3000",
+				"CORS_ALLOW_CREDENTIALS": "true",
+			},
+		},
+		{
+			name: "success group overrides",
+			env: map[string]string{
+				"CORS_ALLOW_ORIGINS":           "https://app.example.com",
+				"CORS_ALLOW_CREDENTIALS":       "true",
+				"CORS_GROUP_ALLOW_ORIGINS":     "/integrations:https://partner.io|https://*.partner.io,/oauth:*",
+				"CORS_GROUP_ALLOW_CREDENTIALS": "/oauth:false",
+			},
+		},
+		{
+			name: "fail credentials with any origin",
+			env: map[string]string{
+				"CORS_ALLOW_ORIGINS":     "*",
+				"CORS_ALLOW_CREDENTIALS": "true",
+			},
+			expectedErr: "origin * cannot be used with allow credentials",
+		},
+		{
+			name: "fail group inherits credentials with any origin",
+			env: map[string]string{
+				"CORS_ALLOW_ORIGINS":       "https://app.example.com",
+				"CORS_ALLOW_CREDENTIALS":   "true",
+				"CORS_GROUP_ALLOW_ORIGINS": "/oauth:*",
+			},
+			expectedErr: "CORS group /oauth: origin * cannot be used with allow credentials",
+		},
+		{
+			name: "fail invalid origin",
+			env: map[string]string{
+				"CORS_ALLOW_ORIGINS": "app.example.com",
+			},
+			expectedErr: `invalid origin "app.example.com"`,
+		},
+		{
+			name: "fail origin with path",
+			env: map[string]string{
+				"CORS_ALLOW_ORIGINS": "https://app.example.com/",
+			},
+			expectedErr: `invalid origin "https://app.example.com/"`,
+		},
+		{
+			name: "fail group without leading slash",
+			env: map[string]string{
+				"CORS_GROUP_ALLOW_ORIGINS": "oauth:https://app.example.com",
+			},
+			expectedErr: `CORS group "oauth": must start with /`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := loadConfig(t, tc.env)
+
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, cfg.CORS.Policy("").AllowOrigins)
+		})
+	}
+}
+
+func TestLoadConfigHTTP(t *testing.T) {
+	type testCase struct {
+		name        string
+		env         map[string]string
+		expectedErr string
+	}
+
+	testCases := []testCase{
+		{
+			name: "success defaults",
+		},
+		{
+			name: "success longer request timeout",
+			env: map[string]string{
+				"HTTP_REQUEST_TIMEOUT": "45s",
+				"HTTP_WRITE_TIMEOUT":   "60s",
+			},
+		},
+		{
+			name: "fail write timeout shorter than request timeout",
+			env: map[string]string{
+				"HTTP_REQUEST_TIMEOUT": "30s",
+				"HTTP_WRITE_TIMEOUT":   "10s",
+			},
+			expectedErr: "HTTP write timeout 10s must be longer than request timeout 30s",
+		},
+		{
+			name: "fail write timeout shorter than auth request timeout",
+			env: map[string]string{
+				"HTTP_AUTH_REQUEST_TIMEOUT": "45s",
+			},
+			expectedErr: "HTTP write timeout 30s must be longer than request timeout 45s",
+		},
+		{
+			name: "fail zero body size",
+			env: map[string]string{
+				"HTTP_MAX_BODY_SIZE": "0",
+			},
+			expectedErr: "MaxBodySize",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := loadConfig(t, tc.env)
+
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Less(t, cfg.HTTP.RequestTimeout, cfg.HTTP.WriteTimeout)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package errs
+
+import (
+	"errors"
+
+	"github.com/codepnw/go-starter-kit/pkg/utils/validate"
+)
+
+// AppError : an error the API answers with. Code is stable for clients to match on,
+// Message is safe to show, Err is the internal cause and only ever logged.
+type AppError struct {
+	Code    string
+	Status  int
+	Message string
+	Err     error
+}
+
+func New(code string, status int, message string) *AppError {
+	return &AppError{
+		Code:    code,
+		Status:  status,
+		Message: message,
+	}
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is : copies made by Wrap still match their sentinel with errors.Is.
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e carrying cause, for the logs.
+func (e *AppError) Wrap(cause error) *AppError {
+	wrapped := *e
+	wrapped.Err = cause
+	return &wrapped
+}
+
+// WithMessage returns a copy of e showing message instead, e.g. which field is invalid.
+func (e *AppError) WithMessage(message string) *AppError {
+	wrapped := *e
+	wrapped.Message = message
+	return &wrapped
+}
+
+// InvalidInput : a request the handler could not bind. Invalid fields are listed by the
+// response (see validate.Fields); any other binding error (malformed JSON) is shown as is.
+func InvalidInput(err error) *AppError {
+	if validate.HasFields(err) {
+		return ErrInvalidInput.Wrap(err)
+	}
+	return ErrInvalidInput.WithMessage(err.Error()).Wrap(err)
+}
+
+// From returns the AppError in err's chain, or nil.
+func From(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return nil
+}
@@ -1,12 +1,63 @@
 package errs
 
-import "errors"
+import "net/http"
 
 var (
-	ErrUserNotFound           = errors.New("user not found")
-	ErrEmailAlreadyExists     = errors.New("email already exists")
-	ErrInvalidEmailOrPassword = errors.New("invalid email or password")
-	ErrTokenNotFound          = errors.New("token not found")
-	ErrTokenRevoked           = errors.New("token revoked")
-	ErrTokenExpires           = errors.New("token expires")
+	ErrUserNotFound           = New("user_not_found", http.StatusNotFound, "user not found")
+	ErrEmailAlreadyExists     = New("email_already_exists", http.StatusBadRequest, "email already exists")
+	ErrInvalidEmailOrPassword = New("invalid_credentials", http.StatusBadRequest, "invalid email or password")
+	ErrTokenNotFound          = New("token_not_found", http.StatusNotFound, "token not found")
+	ErrTokenRevoked           = New("token_revoked", http.StatusBadRequest, "token revoked")
+	ErrTokenExpires           = New("token_expired", http.StatusBadRequest, "token expires")
+	ErrInvalidToken           = New("invalid_token", http.StatusUnauthorized, "invalid token")
+	ErrSessionExpired         = New("session_expired", http.StatusUnauthorized, "session expired")
+	ErrSessionIdle            = New("session_idle", http.StatusUnauthorized, "session idle timeout")
+	ErrDPoPRequired           = New("dpop_required", http.StatusBadRequest, "dpop proof required for this client")
+	ErrClientIDRequired       = New("client_id_required", http.StatusBadRequest, "client_id is required")
+	ErrSessionLimitReached    = New("session_limit_reached", http.StatusConflict, "maximum number of active sessions reached")
+
+	ErrOrganizationNotFound  = New("organization_not_found", http.StatusNotFound, "organization not found")
+	ErrNotOrganizationMember = New("not_organization_member", http.StatusForbidden, "not a member of this organization")
+	ErrNoActiveOrganization  = New("no_active_organization", http.StatusForbidden, "no active organization")
+	ErrOrganizationMismatch  = New("organization_mismatch", http.StatusForbidden, "organization does not match token")
+	ErrInsufficientRole      = New("insufficient_role", http.StatusForbidden, "insufficient organization role")
+	ErrAlreadyMember         = New("already_member", http.StatusConflict, "user is already a member")
+
+	ErrInvitationNotFound      = New("invitation_not_found", http.StatusNotFound, "invitation not found")
+	ErrInvitationAlreadyExists = New("invitation_already_exists", http.StatusConflict, "pending invitation already exists")
+	ErrInvitationNotPending    = New("invitation_not_pending", http.StatusConflict, "invitation is no longer pending")
+	ErrInvitationExpired       = New("invitation_expired", http.StatusGone, "invitation expired")
+	ErrInvalidInvitationToken  = New("invalid_invitation_token", http.StatusBadRequest, "invalid invitation token")
+	ErrInvalidInvitationRole   = New("invalid_invitation_role", http.StatusBadRequest, "invalid invitation role")
+	ErrPasswordRequired        = New("password_required", http.StatusBadRequest, "password is required")
+
+	ErrRateLimited      = New("rate_limited", http.StatusTooManyRequests, "too many requests")
+	ErrServerOverloaded = New("server_overloaded", http.StatusServiceUnavailable, "server overloaded, retry later")
+	ErrRequestTimeout   = New("request_timeout", http.StatusGatewayTimeout, "request timed out")
+	ErrRequestCanceled  = New("request_canceled", http.StatusServiceUnavailable, "request canceled")
+	ErrRequestTooLarge  = New("request_too_large", http.StatusRequestEntityTooLarge, "request body too large")
+	ErrInvalidInput     = New("invalid_input", http.StatusBadRequest, "invalid input")
+	ErrInternal         = New("internal_error", http.StatusInternalServerError, "internal server error")
+
+	ErrInvalidIdempotencyKey = New("invalid_idempotency_key", http.StatusBadRequest, "invalid idempotency key")
+	ErrIdempotencyKeyReused  = New("idempotency_key_reused", http.StatusUnprocessableEntity, "idempotency key already used with a different request")
+	ErrIdempotencyInProgress = New("idempotency_in_progress", http.StatusConflict, "a request with this idempotency key is in progress")
+
+	ErrInsufficientPermission = New("insufficient_permission", http.StatusForbidden, "insufficient permission")
+	ErrAPIClientNotFound      = New("api_client_not_found", http.StatusNotFound, "api client not found")
+
+	// OAuth 2.0 (RFC 6749 / RFC 8628 error codes)
+	ErrInvalidClient        = New("invalid_client", http.StatusUnauthorized, "invalid_client")
+	ErrInvalidGrant         = New("invalid_grant", http.StatusBadRequest, "invalid_grant")
+	ErrUnsupportedGrantType = New("unsupported_grant_type", http.StatusBadRequest, "unsupported_grant_type")
+	ErrAuthorizationPending = New("authorization_pending", http.StatusBadRequest, "authorization_pending")
+	ErrSlowDown             = New("slow_down", http.StatusBadRequest, "slow_down")
+	ErrExpiredToken         = New("expired_token", http.StatusBadRequest, "expired_token")
+	ErrAccessDenied         = New("access_denied", http.StatusBadRequest, "access_denied")
+	ErrInvalidRequest       = New("invalid_request", http.StatusBadRequest, "invalid_request")
+	ErrInvalidScope         = New("invalid_scope", http.StatusBadRequest, "invalid_scope")
+	ErrInvalidTarget        = New("invalid_target", http.StatusBadRequest, "invalid_target")
+	ErrInvalidUserCode      = New("invalid_user_code", http.StatusBadRequest, "invalid or expired user code")
+	// RFC 9449 section 5
+	ErrInvalidDPoPProof = New("invalid_dpop_proof", http.StatusBadRequest, "invalid_dpop_proof")
 )
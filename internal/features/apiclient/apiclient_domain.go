@@ -0,0 +1,41 @@
+package apiclient
+
+import (
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+)
+
+// APIClient is a machine client authenticating with HMAC signed requests.
+type APIClient struct {
+	ID        string    `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Secret    string    `db:"secret" json:"-"`
+	Revoked   bool      `db:"revoked" json:"revoked"`
+	CreatedBy string    `db:"created_by" json:"created_by"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ListSpec : sorting and filtering of GET /admin/clients
+var ListSpec = pagination.Spec{
+	Sorts: map[string]pagination.Field{
+		"id":         {Column: "id", Type: pagination.UUID},
+		"name":       {Column: "name"},
+		"created_at": {Column: "created_at", Type: pagination.Time},
+	},
+	Filters: map[string]pagination.Field{
+		"revoked":    {Column: "revoked", Type: pagination.Bool},
+		"created_by": {Column: "created_by", Type: pagination.UUID},
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+func (c *APIClient) SortValues() map[string]any {
+	return map[string]any{
+		"id":         c.ID,
+		"name":       c.Name,
+		"created_at": c.CreatedAt,
+	}
+}
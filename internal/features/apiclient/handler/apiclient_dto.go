@@ -0,0 +1,9 @@
+package apiclienthandler
+
+type CreateClientReq struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type ClientURI struct {
+	ClientID string `uri:"client_id" validate:"required,uuid"`
+}
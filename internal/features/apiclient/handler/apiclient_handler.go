@@ -0,0 +1,92 @@
+package apiclienthandler
+
+import (
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/apiclient"
+	apiclientservice "github.com/codepnw/go-starter-kit/internal/features/apiclient/service"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type apiClientHandler struct {
+	service apiclientservice.APIClientService
+}
+
+func NewAPIClientHandler(service apiclientservice.APIClientService) *apiClientHandler {
+	return &apiClientHandler{service: service}
+}
+
+func (h *apiClientHandler) CreateClient(c *gin.Context) {
+	req := new(CreateClientReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.CreateClient(c.Request.Context(), req.Name)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *apiClientHandler) ListClients(c *gin.Context) {
+	q, err := pagination.Parse(c.Request.URL.Query(), apiclient.ListSpec)
+	if err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	page, err := h.service.ListClients(c.Request.Context(), q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponsePage(c, page)
+}
+
+func (h *apiClientHandler) RotateSecret(c *gin.Context) {
+	uri := new(ClientURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.RotateSecret(c.Request.Context(), uri.ClientID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *apiClientHandler) RevokeClient(c *gin.Context) {
+	uri := new(ClientURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	if err := h.service.RevokeClient(c.Request.Context(), uri.ClientID); err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
+
+// WhoAmI echoes the verified client, useful to test request signing.
+func (h *apiClientHandler) WhoAmI(c *gin.Context) {
+	clientID, err := auth.GetClientIDFromContext(c.Request.Context())
+	if err != nil {
+		response.ResponseError(c, http.StatusUnauthorized, err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, gin.H{"client_id": clientID})
+}
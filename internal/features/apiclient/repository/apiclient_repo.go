@@ -0,0 +1,145 @@
+package apiclientrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/apiclient"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+)
+
+//go:generate mockgen -source=apiclient_repo.go -destination=apiclient_repo_mock.go -package=apiclientrepository
+type APIClientRepository interface {
+	InsertClient(ctx context.Context, client *apiclient.APIClient) error
+	FindActiveClientByID(ctx context.Context, clientID string) (*apiclient.APIClient, error)
+	ListClients(ctx context.Context, q *pagination.Query) ([]*apiclient.APIClient, error)
+	CountClients(ctx context.Context, q *pagination.Query) (int, error)
+	UpdateClientSecret(ctx context.Context, clientID, secret string) error
+	RevokeClient(ctx context.Context, clientID string) error
+}
+
+type apiClientRepository struct {
+	db *sql.DB
+}
+
+func NewAPIClientRepository(db *sql.DB) APIClientRepository {
+	return &apiClientRepository{db: db}
+}
+
+func (r *apiClientRepository) InsertClient(ctx context.Context, client *apiclient.APIClient) error {
+	query := `
+		INSERT INTO api_clients (name, secret, created_by)
+		VALUES ($1, $2, $3) RETURNING id, revoked, created_at, updated_at
+	`
+	if err := r.db.QueryRowContext(ctx, query, client.Name, client.Secret, client.CreatedBy).Scan(
+		&client.ID,
+		&client.Revoked,
+		&client.CreatedAt,
+		&client.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *apiClientRepository) FindActiveClientByID(ctx context.Context, clientID string) (*apiclient.APIClient, error) {
+	var client apiclient.APIClient
+	query := `
+		SELECT id, name, secret, revoked, COALESCE(created_by::text, ''), created_at, updated_at
+		FROM api_clients WHERE id = $1 AND revoked = FALSE LIMIT 1
+	`
+	if err := r.db.QueryRowContext(ctx, query, clientID).Scan(
+		&client.ID,
+		&client.Name,
+		&client.Secret,
+		&client.Revoked,
+		&client.CreatedBy,
+		&client.CreatedAt,
+		&client.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrAPIClientNotFound
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *apiClientRepository) ListClients(ctx context.Context, q *pagination.Query) ([]*apiclient.APIClient, error) {
+	c := q.Clause(0)
+	query := `
+		SELECT id, name, revoked, COALESCE(created_by::text, ''), created_at, updated_at
+		FROM api_clients WHERE ` + c.Where + `
+		ORDER BY ` + c.OrderBy + ` ` + c.Limit
+	rows, err := r.db.QueryContext(ctx, query, c.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := make([]*apiclient.APIClient, 0)
+	for rows.Next() {
+		var client apiclient.APIClient
+		if err := rows.Scan(
+			&client.ID,
+			&client.Name,
+			&client.Revoked,
+			&client.CreatedBy,
+			&client.CreatedAt,
+			&client.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		clients = append(clients, &client)
+	}
+	return clients, rows.Err()
+}
+
+func (r *apiClientRepository) CountClients(ctx context.Context, q *pagination.Query) (int, error) {
+	c := q.Clause(0)
+	query := `SELECT COUNT(*) FROM api_clients WHERE ` + c.Filter
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query, c.FilterArgs...).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (r *apiClientRepository) UpdateClientSecret(ctx context.Context, clientID, secret string) error {
+	query := `
+		UPDATE api_clients SET secret = $1, updated_at = NOW()
+		WHERE id = $2 AND revoked = FALSE
+	`
+	res, err := r.db.ExecContext(ctx, query, secret, clientID)
+	if err != nil {
+		return err
+	}
+	return checkClientUpdated(res)
+}
+
+func (r *apiClientRepository) RevokeClient(ctx context.Context, clientID string) error {
+	query := `
+		UPDATE api_clients SET revoked = TRUE, updated_at = NOW()
+		WHERE id = $1 AND revoked = FALSE
+	`
+	res, err := r.db.ExecContext(ctx, query, clientID)
+	if err != nil {
+		return err
+	}
+	return checkClientUpdated(res)
+}
+
+func checkClientUpdated(res sql.Result) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrAPIClientNotFound
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: apiclient_repo.go
+
+// Package apiclientrepository is a generated GoMock package.
+package apiclientrepository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	apiclient "github.com/codepnw/go-starter-kit/internal/features/apiclient"
+	pagination "github.com/codepnw/go-starter-kit/pkg/pagination"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAPIClientRepository is a mock of APIClientRepository interface.
+type MockAPIClientRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIClientRepositoryMockRecorder
+}
+
+// MockAPIClientRepositoryMockRecorder is the mock recorder for MockAPIClientRepository.
+type MockAPIClientRepositoryMockRecorder struct {
+	mock *MockAPIClientRepository
+}
+
+// NewMockAPIClientRepository creates a new mock instance.
+func NewMockAPIClientRepository(ctrl *gomock.Controller) *MockAPIClientRepository {
+	mock := &MockAPIClientRepository{ctrl: ctrl}
+	mock.recorder = &MockAPIClientRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIClientRepository) EXPECT() *MockAPIClientRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CountClients mocks base method.
+func (m *MockAPIClientRepository) CountClients(ctx context.Context, q *pagination.Query) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountClients", ctx, q)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountClients indicates an expected call of CountClients.
+func (mr *MockAPIClientRepositoryMockRecorder) CountClients(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountClients", reflect.TypeOf((*MockAPIClientRepository)(nil).CountClients), ctx, q)
+}
+
+// FindActiveClientByID mocks base method.
+func (m *MockAPIClientRepository) FindActiveClientByID(ctx context.Context, clientID string) (*apiclient.APIClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindActiveClientByID", ctx, clientID)
+	ret0, _ := ret[0].(*apiclient.APIClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindActiveClientByID indicates an expected call of FindActiveClientByID.
+func (mr *MockAPIClientRepositoryMockRecorder) FindActiveClientByID(ctx, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindActiveClientByID", reflect.TypeOf((*MockAPIClientRepository)(nil).FindActiveClientByID), ctx, clientID)
+}
+
+// InsertClient mocks base method.
+func (m *MockAPIClientRepository) InsertClient(ctx context.Context, client *apiclient.APIClient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertClient", ctx, client)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertClient indicates an expected call of InsertClient.
+func (mr *MockAPIClientRepositoryMockRecorder) InsertClient(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertClient", reflect.TypeOf((*MockAPIClientRepository)(nil).InsertClient), ctx, client)
+}
+
+// ListClients mocks base method.
+func (m *MockAPIClientRepository) ListClients(ctx context.Context, q *pagination.Query) ([]*apiclient.APIClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClients", ctx, q)
+	ret0, _ := ret[0].([]*apiclient.APIClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListClients indicates an expected call of ListClients.
+func (mr *MockAPIClientRepositoryMockRecorder) ListClients(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClients", reflect.TypeOf((*MockAPIClientRepository)(nil).ListClients), ctx, q)
+}
+
+// RevokeClient mocks base method.
+func (m *MockAPIClientRepository) RevokeClient(ctx context.Context, clientID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeClient", ctx, clientID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeClient indicates an expected call of RevokeClient.
+func (mr *MockAPIClientRepositoryMockRecorder) RevokeClient(ctx, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeClient", reflect.TypeOf((*MockAPIClientRepository)(nil).RevokeClient), ctx, clientID)
+}
+
+// UpdateClientSecret mocks base method.
+func (m *MockAPIClientRepository) UpdateClientSecret(ctx context.Context, clientID, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateClientSecret", ctx, clientID, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateClientSecret indicates an expected call of UpdateClientSecret.
+func (mr *MockAPIClientRepositoryMockRecorder) UpdateClientSecret(ctx, clientID, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateClientSecret", reflect.TypeOf((*MockAPIClientRepository)(nil).UpdateClientSecret), ctx, clientID, secret)
+}
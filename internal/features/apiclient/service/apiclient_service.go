@@ -0,0 +1,121 @@
+package apiclientservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/features/apiclient"
+	apiclientrepository "github.com/codepnw/go-starter-kit/internal/features/apiclient/repository"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+)
+
+type APIClientService interface {
+	CreateClient(ctx context.Context, name string) (*ClientCredentialsResponse, error)
+	ListClients(ctx context.Context, q *pagination.Query) (*pagination.Page[*apiclient.APIClient], error)
+	RotateSecret(ctx context.Context, clientID string) (*ClientCredentialsResponse, error)
+	RevokeClient(ctx context.Context, clientID string) error
+	GetClientSecret(ctx context.Context, clientID string) (string, error)
+}
+
+type apiClientService struct {
+	repo apiclientrepository.APIClientRepository
+}
+
+func NewAPIClientService(repo apiclientrepository.APIClientRepository) APIClientService {
+	return &apiClientService{repo: repo}
+}
+
+// ClientCredentialsResponse : the secret is only returned once, on create and rotate
+type ClientCredentialsResponse struct {
+	Client *apiclient.APIClient `json:"client"`
+	Secret string               `json:"secret"`
+}
+
+func (s *apiClientService) CreateClient(ctx context.Context, name string) (*ClientCredentialsResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &apiclient.APIClient{
+		Name:      name,
+		Secret:    secret,
+		CreatedBy: userID,
+	}
+	if err := s.repo.InsertClient(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return &ClientCredentialsResponse{
+		Client: client,
+		Secret: secret,
+	}, nil
+}
+
+func (s *apiClientService) ListClients(ctx context.Context, q *pagination.Query) (*pagination.Page[*apiclient.APIClient], error) {
+	items, err := s.repo.ListClients(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := pagination.NewPage(q, items)
+	if q.Total {
+		total, err := s.repo.CountClients(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		page.Meta.Total = &total
+	}
+	return page, nil
+}
+
+func (s *apiClientService) RotateSecret(ctx context.Context, clientID string) (*ClientCredentialsResponse, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateClientSecret(ctx, clientID, secret); err != nil {
+		return nil, err
+	}
+
+	client, err := s.repo.FindActiveClientByID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientCredentialsResponse{
+		Client: client,
+		Secret: secret,
+	}, nil
+}
+
+func (s *apiClientService) RevokeClient(ctx context.Context, clientID string) error {
+	return s.repo.RevokeClient(ctx, clientID)
+}
+
+// GetClientSecret returns the signing secret of an active (not revoked) client.
+func (s *apiClientService) GetClientSecret(ctx context.Context, clientID string) (string, error) {
+	client, err := s.repo.FindActiveClientByID(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	return client.Secret, nil
+}
+
+// ------------------ Private Method -------------------
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,328 @@
+package apiclientservice_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/apiclient"
+	apiclientrepository "github.com/codepnw/go-starter-kit/internal/features/apiclient/repository"
+	apiclientservice "github.com/codepnw/go-starter-kit/internal/features/apiclient/service"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var ErrDB = errors.New("DB Error")
+
+func TestCreateClient(t *testing.T) {
+	type testCase struct {
+		name        string
+		clientName  string
+		mockFn      func(mockRepo *apiclientrepository.MockAPIClientRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:       "success",
+			clientName: "billing-webhooks",
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository) {
+				mockRepo.EXPECT().InsertClient(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, client *apiclient.APIClient) error {
+						client.ID = "mock-client-1"
+						return nil
+					},
+				).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:       "fail insert client",
+			clientName: "billing-webhooks",
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository) {
+				mockRepo.EXPECT().InsertClient(gomock.Any(), gomock.Any()).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockRepo, service := setup(t)
+
+		tc.mockFn(mockRepo)
+
+		ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+
+		resp, err := service.CreateClient(ctx, tc.clientName)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, "mock-client-1", resp.Client.ID)
+			assert.Equal(t, "mock-uuid-1", resp.Client.CreatedBy)
+			assert.NotEmpty(t, resp.Secret)
+			assert.Equal(t, resp.Secret, resp.Client.Secret)
+		}
+	}
+}
+
+func TestRotateSecret(t *testing.T) {
+	type testCase struct {
+		name        string
+		clientID    string
+		mockFn      func(mockRepo *apiclientrepository.MockAPIClientRepository, clientID string)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:     "success",
+			clientID: "mock-client-1",
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository, clientID string) {
+				var newSecret string
+				mockRepo.EXPECT().UpdateClientSecret(gomock.Any(), clientID, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, clientID, secret string) error {
+						newSecret = secret
+						return nil
+					},
+				).Times(1)
+				mockRepo.EXPECT().FindActiveClientByID(gomock.Any(), clientID).DoAndReturn(
+					func(ctx context.Context, clientID string) (*apiclient.APIClient, error) {
+						return &apiclient.APIClient{ID: clientID, Secret: newSecret}, nil
+					},
+				).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "fail client revoked",
+			clientID: "mock-client-1",
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository, clientID string) {
+				mockRepo.EXPECT().UpdateClientSecret(gomock.Any(), clientID, gomock.Any()).Return(errs.ErrAPIClientNotFound).Times(1)
+			},
+			expectedErr: errs.ErrAPIClientNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockRepo, service := setup(t)
+
+		tc.mockFn(mockRepo, tc.clientID)
+
+		resp, err := service.RotateSecret(context.Background(), tc.clientID)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.NotEmpty(t, resp.Secret)
+			assert.Equal(t, resp.Secret, resp.Client.Secret)
+		}
+	}
+}
+
+func TestGetClientSecret(t *testing.T) {
+	type testCase struct {
+		name        string
+		clientID    string
+		mockFn      func(mockRepo *apiclientrepository.MockAPIClientRepository, clientID string)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:     "success",
+			clientID: "mock-client-1",
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository, clientID string) {
+				mockRepo.EXPECT().FindActiveClientByID(gomock.Any(), clientID).Return(&apiclient.APIClient{ID: clientID, Secret: "mock-secret"}, nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "fail revoked or unknown client",
+			clientID: "mock-client-2",
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository, clientID string) {
+				mockRepo.EXPECT().FindActiveClientByID(gomock.Any(), clientID).Return(nil, errs.ErrAPIClientNotFound).Times(1)
+			},
+			expectedErr: errs.ErrAPIClientNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockRepo, service := setup(t)
+
+		tc.mockFn(mockRepo, tc.clientID)
+
+		secret, err := service.GetClientSecret(context.Background(), tc.clientID)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, "mock-secret", secret)
+		}
+	}
+}
+
+func TestListClients(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	clients := []*apiclient.APIClient{
+		{ID: "7c1a0a9e-2f0b-4bde-9a57-3b1c2d4e5f60", Name: "billing", CreatedAt: now},
+		{ID: "5d2b1c8f-3a1c-4ce0-8b68-4c2d3e5f6071", Name: "reports", CreatedAt: now.Add(-time.Minute)},
+		{ID: "1e3c2d7a-4b2d-4df1-9c79-5d3e4f607182", Name: "search", CreatedAt: now.Add(-2 * time.Minute)},
+	}
+
+	type testCase struct {
+		name            string
+		query           url.Values
+		mockFn          func(mockRepo *apiclientrepository.MockAPIClientRepository)
+		expectedErr     error
+		expectedItems   int
+		expectedHasMore bool
+		expectedTotal   *int
+	}
+
+	total := 7
+	testCases := []testCase{
+		{
+			name:  "success first page",
+			query: url.Values{"limit": {"2"}},
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository) {
+				mockRepo.EXPECT().ListClients(gomock.Any(), gomock.Any()).Return(clients, nil).Times(1)
+			},
+			expectedItems:   2,
+			expectedHasMore: true,
+		},
+		{
+			name:  "success last page",
+			query: url.Values{"limit": {"3"}},
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository) {
+				mockRepo.EXPECT().ListClients(gomock.Any(), gomock.Any()).Return(clients, nil).Times(1)
+			},
+			expectedItems: 3,
+		},
+		{
+			name:  "success with total",
+			query: url.Values{"limit": {"2"}, "page": {"1"}, "total": {"true"}},
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository) {
+				mockRepo.EXPECT().ListClients(gomock.Any(), gomock.Any()).Return(clients, nil).Times(1)
+				mockRepo.EXPECT().CountClients(gomock.Any(), gomock.Any()).Return(total, nil).Times(1)
+			},
+			expectedItems:   2,
+			expectedHasMore: true,
+			expectedTotal:   &total,
+		},
+		{
+			name:  "fail list clients",
+			query: url.Values{},
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository) {
+				mockRepo.EXPECT().ListClients(gomock.Any(), gomock.Any()).Return(nil, ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name:  "fail count clients",
+			query: url.Values{"total": {"true"}},
+			mockFn: func(mockRepo *apiclientrepository.MockAPIClientRepository) {
+				mockRepo.EXPECT().ListClients(gomock.Any(), gomock.Any()).Return(clients, nil).Times(1)
+				mockRepo.EXPECT().CountClients(gomock.Any(), gomock.Any()).Return(0, ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockRepo, service := setup(t)
+
+		tc.mockFn(mockRepo)
+
+		q, err := pagination.Parse(tc.query, apiclient.ListSpec)
+		assert.NoError(t, err, tc.name)
+
+		page, err := service.ListClients(context.Background(), q)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.Len(t, page.Items, tc.expectedItems, tc.name)
+			assert.Equal(t, tc.expectedHasMore, page.Meta.HasMore, tc.name)
+			assert.Equal(t, tc.expectedTotal, page.Meta.Total, tc.name)
+			// Keyset pages continue with a cursor, offset pages with ?page
+			assert.Equal(t, tc.expectedHasMore && q.Page == 0, page.Meta.NextCursor != "", tc.name)
+		}
+	}
+}
+
+func TestListClientsNextCursor(t *testing.T) {
+	mockRepo, service := setup(t)
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	clients := []*apiclient.APIClient{
+		{ID: "7c1a0a9e-2f0b-4bde-9a57-3b1c2d4e5f60", CreatedAt: now},
+		{ID: "5d2b1c8f-3a1c-4ce0-8b68-4c2d3e5f6071", CreatedAt: now},
+	}
+	mockRepo.EXPECT().ListClients(gomock.Any(), gomock.Any()).Return(clients, nil).Times(1)
+
+	first, err := pagination.Parse(url.Values{"limit": {"1"}, "filter[revoked]": {"false"}}, apiclient.ListSpec)
+	assert.NoError(t, err)
+
+	page, err := service.ListClients(context.Background(), first)
+	assert.NoError(t, err)
+	assert.Equal(t, `</admin/clients?filter%5Brevoked%5D=false&limit=1>; rel="first", `+
+		`</admin/clients?cursor=`+page.Meta.NextCursor+`&filter%5Brevoked%5D=false&limit=1>; rel="next"`,
+		page.Meta.Links(&url.URL{Path: "/admin/clients", RawQuery: "filter%5Brevoked%5D=false&limit=1"}))
+
+	// The next page starts after the last row, ties on created_at broken by id
+	next, err := pagination.Parse(url.Values{"limit": {"1"}, "filter[revoked]": {"false"}, "cursor": {page.Meta.NextCursor}}, apiclient.ListSpec)
+	assert.NoError(t, err)
+
+	c := next.Clause(0)
+	assert.Equal(t, "revoked = $1 AND ((created_at < $2) OR (created_at = $3 AND id < $4))", c.Where)
+	assert.Equal(t, "created_at DESC, id DESC", c.OrderBy)
+	assert.Equal(t, "LIMIT $5", c.Limit)
+	assert.Equal(t, []any{false, now, now, clients[0].ID, 2}, c.Args)
+	assert.Equal(t, []any{false}, c.FilterArgs)
+
+	// A cursor only fits the sort it was made for
+	_, err = pagination.Parse(url.Values{"sort": {"name"}, "cursor": {page.Meta.NextCursor}}, apiclient.ListSpec)
+	assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+}
+
+func TestListClientsInvalidQuery(t *testing.T) {
+	type testCase struct {
+		name  string
+		query url.Values
+	}
+
+	testCases := []testCase{
+		{name: "fail limit too large", query: url.Values{"limit": {"1000"}}},
+		{name: "fail cursor and page", query: url.Values{"cursor": {"abc"}, "page": {"2"}}},
+		{name: "fail unknown sort", query: url.Values{"sort": {"secret"}}},
+		{name: "fail unknown filter", query: url.Values{"filter[secret]": {"x"}}},
+		{name: "fail filter type", query: url.Values{"filter[created_by]": {"1; DROP TABLE api_clients"}}},
+		{name: "fail malformed cursor", query: url.Values{"cursor": {"not-a-cursor"}}},
+	}
+
+	for _, tc := range testCases {
+		_, err := pagination.Parse(tc.query, apiclient.ListSpec)
+		assert.Error(t, err, tc.name)
+	}
+}
+
+func setup(t *testing.T) (*apiclientrepository.MockAPIClientRepository, apiclientservice.APIClientService) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := apiclientrepository.NewMockAPIClientRepository(ctrl)
+
+	service := apiclientservice.NewAPIClientService(mockRepo)
+
+	return mockRepo, service
+}
@@ -0,0 +1,31 @@
+package oauthhandler
+
+// OAuth endpoints accept application/x-www-form-urlencoded (RFC 6749) or JSON.
+
+type DeviceCodeReq struct {
+	ClientID string `form:"client_id" json:"client_id" validate:"required"`
+	Scope    string `form:"scope" json:"scope"`
+}
+
+type TokenReq struct {
+	GrantType  string `form:"grant_type" json:"grant_type" validate:"required"`
+	ClientID   string `form:"client_id" json:"client_id"`
+	DeviceCode string `form:"device_code" json:"device_code"`
+
+	// Token exchange (RFC 8693)
+	SubjectToken       string `form:"subject_token" json:"subject_token"`
+	SubjectTokenType   string `form:"subject_token_type" json:"subject_token_type"`
+	RequestedTokenType string `form:"requested_token_type" json:"requested_token_type"`
+	Audience           string `form:"audience" json:"audience"`
+	Scope              string `form:"scope" json:"scope"`
+}
+
+type DeviceLoginReq struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type VerifyDeviceReq struct {
+	UserCode string `json:"user_code" validate:"required"`
+	Action   string `json:"action" validate:"required,oneof=approve deny"`
+}
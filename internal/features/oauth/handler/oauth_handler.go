@@ -0,0 +1,227 @@
+package oauthhandler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/oauth"
+	oauthservice "github.com/codepnw/go-starter-kit/internal/features/oauth/service"
+	"github.com/codepnw/go-starter-kit/pkg/csp"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthErrors : errors rendered in the RFC 6749 error format, which OAuth clients expect
+// instead of problem+json
+var oauthErrors = []*errs.AppError{
+	errs.ErrInvalidClient,
+	errs.ErrInvalidGrant,
+	errs.ErrUnsupportedGrantType,
+	errs.ErrAuthorizationPending,
+	errs.ErrSlowDown,
+	errs.ErrExpiredToken,
+	errs.ErrAccessDenied,
+	errs.ErrInvalidRequest,
+	errs.ErrInvalidScope,
+	errs.ErrInvalidTarget,
+	errs.ErrInvalidDPoPProof,
+}
+
+type oauthHandler struct {
+	service oauthservice.OAuthService
+	prefix  string
+}
+
+func NewOAuthHandler(service oauthservice.OAuthService, prefix string) *oauthHandler {
+	return &oauthHandler{
+		service: service,
+		prefix:  prefix,
+	}
+}
+
+func (h *oauthHandler) RequestDeviceCode(c *gin.Context) {
+	req := new(DeviceCodeReq)
+
+	if err := c.ShouldBind(req); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	resp, err := h.service.RequestDeviceCode(c.Request.Context(), req.ClientID, req.Scope)
+	if err != nil {
+		handleOAuthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *oauthHandler) Token(c *gin.Context) {
+	req := new(TokenReq)
+
+	if err := c.ShouldBind(req); err != nil {
+		oauthError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	switch req.GrantType {
+	case oauth.GrantTypeDeviceCode:
+		if req.DeviceCode == "" || req.ClientID == "" {
+			oauthError(c, http.StatusBadRequest, "invalid_request", "device_code and client_id are required")
+			return
+		}
+
+		resp, err := h.service.ExchangeDeviceCode(c.Request.Context(), req.DeviceCode, req.ClientID)
+		if err != nil {
+			handleOAuthError(c, err)
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, resp)
+	case oauth.GrantTypeTokenExchange:
+		if req.SubjectToken == "" || req.SubjectTokenType != oauth.TokenTypeAccessToken {
+			oauthError(c, http.StatusBadRequest, "invalid_request", "subject_token must be an access_token")
+			return
+		}
+		if req.RequestedTokenType != "" && req.RequestedTokenType != oauth.TokenTypeAccessToken {
+			oauthError(c, http.StatusBadRequest, "invalid_request", "only access_token can be requested")
+			return
+		}
+
+		resp, err := h.service.ExchangeToken(c.Request.Context(), req.SubjectToken, req.Audience, req.Scope)
+		if err != nil {
+			handleOAuthError(c, err)
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, resp)
+	default:
+		handleOAuthError(c, errs.ErrUnsupportedGrantType)
+	}
+}
+
+// DeviceLogin signs the user in on the verification page, for approving a user code only.
+func (h *oauthHandler) DeviceLogin(c *gin.Context) {
+	req := new(DeviceLoginReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.DeviceLogin(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+// VerifyDevice approves or denies a user code for the logged-in user.
+func (h *oauthHandler) VerifyDevice(c *gin.Context) {
+	req := new(VerifyDeviceReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	if err := h.service.VerifyDeviceCode(c.Request.Context(), req.UserCode, req.Action == "approve"); err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, gin.H{"status": req.Action + "d"})
+}
+
+// DevicePage serves the verification_uri page: log in, then approve the user code.
+func (h *oauthHandler) DevicePage(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	devicePage.Execute(c.Writer, gin.H{
+		"Prefix":   h.prefix,
+		"UserCode": c.Query("user_code"),
+		"Nonce":    csp.NonceFromContext(c.Request.Context()),
+	})
+}
+
+// ------------------ Private Method -------------------
+
+func handleOAuthError(c *gin.Context, err error) {
+	for _, e := range oauthErrors {
+		if errors.Is(err, e) {
+			oauthError(c, e.Status, e.Code, "")
+			return
+		}
+	}
+	slog.ErrorContext(c.Request.Context(), "oauth request error", slog.String("error", err.Error()))
+	oauthError(c, http.StatusInternalServerError, "server_error", "")
+}
+
+func oauthError(c *gin.Context, status int, code, description string) {
+	body := gin.H{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, body)
+}
+
+var devicePage = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Connect a device</title>
+</head>
+<body>
+<h1>Connect a device</h1>
+<form id="device-form">
+  <p><label>Code <input name="user_code" value="{{.UserCode}}" required autocomplete="off"></label></p>
+  <p><label>Email <input name="email" type="email" required></label></p>
+  <p><label>Password <input name="password" type="password" required></label></p>
+  <p>
+    <button type="submit" name="action" value="approve">Approve</button>
+    <button type="submit" name="action" value="deny">Deny</button>
+  </p>
+</form>
+<p id="result" role="status"></p>
+<script nonce="{{.Nonce}}">
+const prefix = {{.Prefix}};
+const form = document.getElementById("device-form");
+const result = document.getElementById("result");
+// Errors come as the envelope or problem+json, depending on ERROR_FORMAT
+const errorText = (body) => body.error || body.detail || body.title;
+
+form.addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const data = new FormData(form);
+  const post = (path, body, token) => fetch(prefix + path, {
+    method: "POST",
+    headers: Object.assign({"Content-Type": "application/json"}, token ? {"Authorization": "Bearer " + token} : {}),
+    body: JSON.stringify(body),
+  }).then((r) => r.json());
+
+  // A token for this page only: no session is created
+  const login = await post("/oauth/device/login", {email: data.get("email"), password: data.get("password")});
+  if (!login.success) {
+    result.textContent = errorText(login);
+    return;
+  }
+
+  const verify = await post("/oauth/device/verify", {
+    user_code: data.get("user_code"),
+    action: event.submitter.value,
+  }, login.data.access_token);
+  result.textContent = verify.success ? "Done, you can return to your device." : errorText(verify);
+});
+</script>
+</body>
+</html>
+`))
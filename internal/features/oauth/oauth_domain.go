@@ -0,0 +1,41 @@
+package oauth
+
+import "time"
+
+// Grant types
+const (
+	GrantTypeDeviceCode    = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// Token types (RFC 8693 section 3)
+const (
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// DeviceVerifyAudience : the audience of the credential the device page gets at login. It
+// is only accepted by the verify route, so it cannot be used as a session.
+const DeviceVerifyAudience = "device-verification"
+
+// Device code statuses
+const (
+	DeviceCodePending  = "pending"
+	DeviceCodeApproved = "approved"
+	DeviceCodeDenied   = "denied"
+	DeviceCodeConsumed = "consumed"
+)
+
+// DeviceCode is an RFC 8628 device authorization request.
+type DeviceCode struct {
+	ID             string     `db:"id" json:"id"`
+	DeviceCodeHash string     `db:"device_code_hash" json:"-"`
+	UserCode       string     `db:"user_code" json:"user_code"`
+	ClientID       string     `db:"client_id" json:"client_id"`
+	Scope          string     `db:"scope" json:"scope"`
+	Status         string     `db:"status" json:"status"`
+	UserID         *string    `db:"user_id" json:"user_id,omitempty"`
+	PollInterval   int        `db:"poll_interval" json:"poll_interval"`
+	LastPolledAt   *time.Time `db:"last_polled_at" json:"last_polled_at,omitempty"`
+	ExpiresAt      time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
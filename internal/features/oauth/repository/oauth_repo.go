@@ -0,0 +1,134 @@
+package oauthrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/oauth"
+)
+
+//go:generate mockgen -source=oauth_repo.go -destination=oauth_repo_mock.go -package=oauthrepository
+type OAuthRepository interface {
+	InsertDeviceCode(ctx context.Context, dc *oauth.DeviceCode) error
+	FindDeviceCodeByHash(ctx context.Context, deviceCodeHash string) (*oauth.DeviceCode, error)
+	FindPendingDeviceCodeByUserCode(ctx context.Context, userCode string) (*oauth.DeviceCode, error)
+	UpdateDeviceCodePoll(ctx context.Context, id string, polledAt time.Time, interval int) error
+	DecideDeviceCode(ctx context.Context, id, status, userID string) error
+
+	// Transaction
+	ConsumeDeviceCodeTx(ctx context.Context, tx *sql.Tx, id string) error
+}
+
+type oauthRepository struct {
+	db *sql.DB
+}
+
+func NewOAuthRepository(db *sql.DB) OAuthRepository {
+	return &oauthRepository{db: db}
+}
+
+func (r *oauthRepository) InsertDeviceCode(ctx context.Context, dc *oauth.DeviceCode) error {
+	query := `
+		INSERT INTO device_codes (device_code_hash, user_code, client_id, scope, status, poll_interval, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at
+	`
+	if err := r.db.QueryRowContext(
+		ctx,
+		query,
+		dc.DeviceCodeHash,
+		dc.UserCode,
+		dc.ClientID,
+		dc.Scope,
+		dc.Status,
+		dc.PollInterval,
+		dc.ExpiresAt,
+	).Scan(
+		&dc.ID,
+		&dc.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *oauthRepository) FindDeviceCodeByHash(ctx context.Context, deviceCodeHash string) (*oauth.DeviceCode, error) {
+	query := `
+		SELECT id, device_code_hash, user_code, client_id, scope, status, user_id, poll_interval, last_polled_at, expires_at, created_at
+		FROM device_codes WHERE device_code_hash = $1 LIMIT 1
+	`
+	return r.scanDeviceCode(r.db.QueryRowContext(ctx, query, deviceCodeHash), errs.ErrInvalidGrant)
+}
+
+func (r *oauthRepository) FindPendingDeviceCodeByUserCode(ctx context.Context, userCode string) (*oauth.DeviceCode, error) {
+	query := `
+		SELECT id, device_code_hash, user_code, client_id, scope, status, user_id, poll_interval, last_polled_at, expires_at, created_at
+		FROM device_codes WHERE user_code = $1 AND status = 'pending' LIMIT 1
+	`
+	return r.scanDeviceCode(r.db.QueryRowContext(ctx, query, userCode), errs.ErrInvalidUserCode)
+}
+
+func (r *oauthRepository) UpdateDeviceCodePoll(ctx context.Context, id string, polledAt time.Time, interval int) error {
+	query := `UPDATE device_codes SET last_polled_at = $1, poll_interval = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, polledAt, interval, id)
+	return err
+}
+
+func (r *oauthRepository) DecideDeviceCode(ctx context.Context, id, status, userID string) error {
+	query := `
+		UPDATE device_codes SET status = $1, user_id = $2
+		WHERE id = $3 AND status = 'pending'
+	`
+	res, err := r.db.ExecContext(ctx, query, status, userID, id)
+	if err != nil {
+		return err
+	}
+	return checkDeviceCodeUpdated(res, errs.ErrInvalidUserCode)
+}
+
+func (r *oauthRepository) ConsumeDeviceCodeTx(ctx context.Context, tx *sql.Tx, id string) error {
+	query := `UPDATE device_codes SET status = 'consumed' WHERE id = $1 AND status = 'approved'`
+	res, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	// Concurrent polls: only one can redeem the approval
+	return checkDeviceCodeUpdated(res, errs.ErrInvalidGrant)
+}
+
+func (r *oauthRepository) scanDeviceCode(row *sql.Row, notFound error) (*oauth.DeviceCode, error) {
+	var dc oauth.DeviceCode
+	if err := row.Scan(
+		&dc.ID,
+		&dc.DeviceCodeHash,
+		&dc.UserCode,
+		&dc.ClientID,
+		&dc.Scope,
+		&dc.Status,
+		&dc.UserID,
+		&dc.PollInterval,
+		&dc.LastPolledAt,
+		&dc.ExpiresAt,
+		&dc.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	return &dc, nil
+}
+
+func checkDeviceCodeUpdated(res sql.Result, notUpdated error) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return notUpdated
+	}
+	return nil
+}
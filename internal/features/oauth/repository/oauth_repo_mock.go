@@ -0,0 +1,124 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: oauth_repo.go
+
+// Package oauthrepository is a generated GoMock package.
+package oauthrepository
+
+import (
+	context "context"
+	sql "database/sql"
+	reflect "reflect"
+	time "time"
+
+	oauth "github.com/codepnw/go-starter-kit/internal/features/oauth"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOAuthRepository is a mock of OAuthRepository interface.
+type MockOAuthRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthRepositoryMockRecorder
+}
+
+// MockOAuthRepositoryMockRecorder is the mock recorder for MockOAuthRepository.
+type MockOAuthRepositoryMockRecorder struct {
+	mock *MockOAuthRepository
+}
+
+// NewMockOAuthRepository creates a new mock instance.
+func NewMockOAuthRepository(ctrl *gomock.Controller) *MockOAuthRepository {
+	mock := &MockOAuthRepository{ctrl: ctrl}
+	mock.recorder = &MockOAuthRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthRepository) EXPECT() *MockOAuthRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ConsumeDeviceCodeTx mocks base method.
+func (m *MockOAuthRepository) ConsumeDeviceCodeTx(ctx context.Context, tx *sql.Tx, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeDeviceCodeTx", ctx, tx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeDeviceCodeTx indicates an expected call of ConsumeDeviceCodeTx.
+func (mr *MockOAuthRepositoryMockRecorder) ConsumeDeviceCodeTx(ctx, tx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeDeviceCodeTx", reflect.TypeOf((*MockOAuthRepository)(nil).ConsumeDeviceCodeTx), ctx, tx, id)
+}
+
+// DecideDeviceCode mocks base method.
+func (m *MockOAuthRepository) DecideDeviceCode(ctx context.Context, id, status, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecideDeviceCode", ctx, id, status, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecideDeviceCode indicates an expected call of DecideDeviceCode.
+func (mr *MockOAuthRepositoryMockRecorder) DecideDeviceCode(ctx, id, status, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecideDeviceCode", reflect.TypeOf((*MockOAuthRepository)(nil).DecideDeviceCode), ctx, id, status, userID)
+}
+
+// FindDeviceCodeByHash mocks base method.
+func (m *MockOAuthRepository) FindDeviceCodeByHash(ctx context.Context, deviceCodeHash string) (*oauth.DeviceCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeviceCodeByHash", ctx, deviceCodeHash)
+	ret0, _ := ret[0].(*oauth.DeviceCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeviceCodeByHash indicates an expected call of FindDeviceCodeByHash.
+func (mr *MockOAuthRepositoryMockRecorder) FindDeviceCodeByHash(ctx, deviceCodeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeviceCodeByHash", reflect.TypeOf((*MockOAuthRepository)(nil).FindDeviceCodeByHash), ctx, deviceCodeHash)
+}
+
+// FindPendingDeviceCodeByUserCode mocks base method.
+func (m *MockOAuthRepository) FindPendingDeviceCodeByUserCode(ctx context.Context, userCode string) (*oauth.DeviceCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPendingDeviceCodeByUserCode", ctx, userCode)
+	ret0, _ := ret[0].(*oauth.DeviceCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPendingDeviceCodeByUserCode indicates an expected call of FindPendingDeviceCodeByUserCode.
+func (mr *MockOAuthRepositoryMockRecorder) FindPendingDeviceCodeByUserCode(ctx, userCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPendingDeviceCodeByUserCode", reflect.TypeOf((*MockOAuthRepository)(nil).FindPendingDeviceCodeByUserCode), ctx, userCode)
+}
+
+// InsertDeviceCode mocks base method.
+func (m *MockOAuthRepository) InsertDeviceCode(ctx context.Context, dc *oauth.DeviceCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDeviceCode", ctx, dc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertDeviceCode indicates an expected call of InsertDeviceCode.
+func (mr *MockOAuthRepositoryMockRecorder) InsertDeviceCode(ctx, dc interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDeviceCode", reflect.TypeOf((*MockOAuthRepository)(nil).InsertDeviceCode), ctx, dc)
+}
+
+// UpdateDeviceCodePoll mocks base method.
+func (m *MockOAuthRepository) UpdateDeviceCodePoll(ctx context.Context, id string, polledAt time.Time, interval int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeviceCodePoll", ctx, id, polledAt, interval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDeviceCodePoll indicates an expected call of UpdateDeviceCodePoll.
+func (mr *MockOAuthRepositoryMockRecorder) UpdateDeviceCodePoll(ctx, id, polledAt, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeviceCodePoll", reflect.TypeOf((*MockOAuthRepository)(nil).UpdateDeviceCodePoll), ctx, id, polledAt, interval)
+}
@@ -0,0 +1,357 @@
+package oauthservice
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"math/big"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/oauth"
+	oauthrepository "github.com/codepnw/go-starter-kit/internal/features/oauth/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/utils/signer"
+)
+
+// userCodeAlphabet : RFC 8628 section 6.1, no vowels or ambiguous characters
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+const slowDownStep = 5 // seconds
+
+type OAuthService interface {
+	RequestDeviceCode(ctx context.Context, clientID, scope string) (*DeviceCodeResponse, error)
+	DeviceLogin(ctx context.Context, email, password string) (*DeviceLoginResponse, error)
+	VerifyDeviceCode(ctx context.Context, userCode string, approve bool) error
+	ExchangeDeviceCode(ctx context.Context, deviceCode, clientID string) (*userservice.UserTokenResponse, error)
+	ExchangeToken(ctx context.Context, subjectToken, audience, scope string) (*TokenExchangeResponse, error)
+}
+
+type oauthService struct {
+	cfg     *config.OAuthConfig
+	tx      database.TxManager
+	token   jwttoken.JWTToken
+	repo    oauthrepository.OAuthRepository
+	userSrv userservice.UserService
+}
+
+func NewOAuthService(cfg *config.OAuthConfig, tx database.TxManager, token jwttoken.JWTToken, repo oauthrepository.OAuthRepository, userSrv userservice.UserService) OAuthService {
+	return &oauthService{
+		cfg:     cfg,
+		tx:      tx,
+		token:   token,
+		repo:    repo,
+		userSrv: userSrv,
+	}
+}
+
+// DeviceCodeResponse : RFC 8628 section 3.2
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+func (s *oauthService) RequestDeviceCode(ctx context.Context, clientID, scope string) (*DeviceCodeResponse, error) {
+	if !slices.Contains(s.cfg.DeviceClients, clientID) {
+		return nil, errs.ErrInvalidClient
+	}
+
+	deviceCode, err := generateDeviceCode()
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	dc := &oauth.DeviceCode{
+		DeviceCodeHash: signer.Hash(deviceCode),
+		UserCode:       userCode,
+		ClientID:       clientID,
+		Scope:          scope,
+		Status:         oauth.DeviceCodePending,
+		PollInterval:   int(s.cfg.PollInterval.Seconds()),
+		ExpiresAt:      time.Now().Add(s.cfg.DeviceCodeTTL),
+	}
+	if err := s.repo.InsertDeviceCode(ctx, dc); err != nil {
+		return nil, err
+	}
+
+	displayCode := formatUserCode(userCode)
+	return &DeviceCodeResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                displayCode,
+		VerificationURI:         s.cfg.VerificationURI,
+		VerificationURIComplete: s.cfg.VerificationURI + "?user_code=" + url.QueryEscape(displayCode),
+		ExpiresIn:               int(s.cfg.DeviceCodeTTL.Seconds()),
+		Interval:                dc.PollInterval,
+	}, nil
+}
+
+// DeviceLoginResponse : the verification page's credential, an access token for the
+// verify route only. There is no refresh token and no session behind it.
+type DeviceLoginResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// DeviceLogin checks the user's credentials on the verification page and returns a
+// short-lived token restricted to approving or denying a user code.
+func (s *oauthService) DeviceLogin(ctx context.Context, email, password string) (*DeviceLoginResponse, error) {
+	u, err := s.userSrv.Authenticate(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.token.GenerateAccessToken(u,
+		jwttoken.WithAudience(oauth.DeviceVerifyAudience),
+		jwttoken.WithTTL(s.cfg.DeviceLoginTTL),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceLoginResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.cfg.DeviceLoginTTL.Seconds()),
+	}, nil
+}
+
+// VerifyDeviceCode approves or denies a pending device code for the logged-in user.
+func (s *oauthService) VerifyDeviceCode(ctx context.Context, userCode string, approve bool) error {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	dc, err := s.repo.FindPendingDeviceCodeByUserCode(ctx, normalizeUserCode(userCode))
+	if err != nil {
+		return err
+	}
+	if time.Now().After(dc.ExpiresAt) {
+		return errs.ErrInvalidUserCode
+	}
+
+	status := oauth.DeviceCodeDenied
+	if approve {
+		status = oauth.DeviceCodeApproved
+	}
+	return s.repo.DecideDeviceCode(ctx, dc.ID, status, userID)
+}
+
+// ExchangeDeviceCode handles one poll of the token endpoint (RFC 8628 section 3.4).
+func (s *oauthService) ExchangeDeviceCode(ctx context.Context, deviceCode, clientID string) (*userservice.UserTokenResponse, error) {
+	dc, err := s.repo.FindDeviceCodeByHash(ctx, signer.Hash(deviceCode))
+	if err != nil {
+		return nil, err
+	}
+	if dc.ClientID != clientID {
+		return nil, errs.ErrInvalidGrant
+	}
+
+	now := time.Now()
+	if now.After(dc.ExpiresAt) {
+		return nil, errs.ErrExpiredToken
+	}
+
+	// Polling faster than the interval: back off by 5 seconds
+	interval := dc.PollInterval
+	tooFast := dc.LastPolledAt != nil && now.Sub(*dc.LastPolledAt) < time.Duration(dc.PollInterval)*time.Second
+	if tooFast {
+		interval += slowDownStep
+	}
+	if err := s.repo.UpdateDeviceCodePoll(ctx, dc.ID, now, interval); err != nil {
+		return nil, err
+	}
+	if tooFast {
+		return nil, errs.ErrSlowDown
+	}
+
+	switch dc.Status {
+	case oauth.DeviceCodePending:
+		return nil, errs.ErrAuthorizationPending
+	case oauth.DeviceCodeDenied:
+		return nil, errs.ErrAccessDenied
+	case oauth.DeviceCodeApproved:
+		// continue
+	default:
+		return nil, errs.ErrInvalidGrant
+	}
+
+	// The tokens carry the scope the device asked for
+	var opts []jwttoken.TokenOption
+	if dc.Scope != "" {
+		opts = append(opts, jwttoken.WithScope(dc.Scope))
+	}
+
+	var response *userservice.UserTokenResponse
+	// DB Transaction: the approval is redeemed only if the session is saved
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.ConsumeDeviceCodeTx(ctx, tx, dc.ID); err != nil {
+			return err
+		}
+
+		resp, err := s.userSrv.IssueTokensTx(ctx, tx, *dc.UserID, dc.ClientID, opts...)
+		response = resp
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// TokenExchangeResponse : RFC 8693 section 2.2.1
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	Scope           string `json:"scope"`
+}
+
+// ExchangeToken trades a user's access token for a short-lived token restricted to one
+// audience and a subset of scopes, naming the calling service in the "act" claim.
+func (s *oauthService) ExchangeToken(ctx context.Context, subjectToken, audience, scope string) (*TokenExchangeResponse, error) {
+	actor, err := auth.GetServicePrincipalFromContext(ctx)
+	if err != nil {
+		return nil, errs.ErrInvalidClient
+	}
+
+	if !slices.Contains(s.cfg.ExchangeAudiences, audience) {
+		return nil, errs.ErrInvalidTarget
+	}
+
+	claims, err := s.token.VerifyAccessToken(subjectToken)
+	if err != nil {
+		return nil, errs.ErrInvalidGrant
+	}
+
+	// A DPoP-bound subject token is only exchanged with a proof from its key, and the
+	// issued token stays bound to that key
+	jkt, err := confirmation(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err = downscope(claims.Scope, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Role and org role are dropped: the scope is all the downstream service gets
+	subject := &user.User{ID: claims.UserID, Email: claims.Email}
+	opts := []jwttoken.TokenOption{
+		jwttoken.WithAudience(audience),
+		jwttoken.WithScope(scope),
+		jwttoken.WithActor(actor.Name),
+		jwttoken.WithTTL(s.cfg.ExchangeTokenTTL),
+	}
+	if claims.OrgID != "" {
+		opts = append(opts, jwttoken.WithOrganization(claims.OrgID, ""))
+	}
+	if jkt != "" {
+		opts = append(opts, jwttoken.WithConfirmation(jkt))
+	}
+
+	accessToken, err := s.token.GenerateAccessToken(subject, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenExchangeResponse{
+		AccessToken:     accessToken,
+		IssuedTokenType: oauth.TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int(s.cfg.ExchangeTokenTTL.Seconds()),
+		Scope:           scope,
+	}, nil
+}
+
+// ------------------ Private Method -------------------
+
+// confirmation : the key the subject token is bound to, which must also have signed the
+// request's DPoP proof. Empty for unbound tokens.
+func confirmation(ctx context.Context, claims *jwttoken.UserClaims) (string, error) {
+	if claims.Cnf == nil || claims.Cnf.JKT == "" {
+		return "", nil
+	}
+
+	jkt, ok := auth.GetDPoPKeyFromContext(ctx)
+	if !ok || jkt != claims.Cnf.JKT {
+		return "", errs.ErrInvalidDPoPProof
+	}
+	return jkt, nil
+}
+
+// downscope : the requested scopes must be a non-empty subset of the subject token's.
+// An unscoped subject token is a full-power user token and may be narrowed to anything.
+func downscope(granted, requested string) (string, error) {
+	requestedScopes := strings.Fields(requested)
+	if len(requestedScopes) == 0 {
+		return "", errs.ErrInvalidScope
+	}
+
+	if granted != "" {
+		grantedScopes := strings.Fields(granted)
+		for _, sc := range requestedScopes {
+			if !slices.Contains(grantedScopes, sc) {
+				return "", errs.ErrInvalidScope
+			}
+		}
+	}
+	return strings.Join(requestedScopes, " "), nil
+}
+
+func generateDeviceCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func generateUserCode() (string, error) {
+	code := make([]byte, 8)
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// formatUserCode : BCDFGHJK -> BCDF-GHJK
+func formatUserCode(code string) string {
+	return code[:4] + "-" + code[4:]
+}
+
+// normalizeUserCode : users may type lower case, dashes or spaces
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
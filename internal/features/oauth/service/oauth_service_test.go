@@ -0,0 +1,572 @@
+package oauthservice_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/oauth"
+	oauthrepository "github.com/codepnw/go-starter-kit/internal/features/oauth/repository"
+	oauthservice "github.com/codepnw/go-starter-kit/internal/features/oauth/service"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/utils/signer"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var ErrDB = errors.New("DB Error")
+
+func TestRequestDeviceCode(t *testing.T) {
+	type testCase struct {
+		name        string
+		clientID    string
+		mockFn      func(mockRepo *oauthrepository.MockOAuthRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:     "success",
+			clientID: "cli",
+			mockFn: func(mockRepo *oauthrepository.MockOAuthRepository) {
+				mockRepo.EXPECT().InsertDeviceCode(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, dc *oauth.DeviceCode) error {
+						dc.ID = "mock-device-1"
+						return nil
+					},
+				).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "fail unknown client",
+			clientID:    "unknown",
+			mockFn:      func(mockRepo *oauthrepository.MockOAuthRepository) {},
+			expectedErr: errs.ErrInvalidClient,
+		},
+		{
+			name:     "fail insert device code",
+			clientID: "cli",
+			mockFn: func(mockRepo *oauthrepository.MockOAuthRepository) {
+				mockRepo.EXPECT().InsertDeviceCode(gomock.Any(), gomock.Any()).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, _, mockRepo, _, service := setup(t)
+
+		tc.mockFn(mockRepo)
+
+		resp, err := service.RequestDeviceCode(context.Background(), tc.clientID, "")
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.NotEmpty(t, resp.DeviceCode)
+			assert.Len(t, resp.UserCode, 9)
+			assert.Equal(t, 600, resp.ExpiresIn)
+			assert.Equal(t, 5, resp.Interval)
+			assert.True(t, strings.HasPrefix(resp.VerificationURIComplete, resp.VerificationURI+"?user_code="))
+		}
+	}
+}
+
+func TestDeviceLogin(t *testing.T) {
+	type testCase struct {
+		name        string
+		mockFn      func(mockToken *jwttoken.MockJWTToken, mockUserSrv *userservice.MockUserService)
+		expectedErr error
+	}
+
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockUserSrv *userservice.MockUserService) {
+				mockUserSrv.EXPECT().Authenticate(gomock.Any(), "mock@mail.com", "password").Return(mockUser, nil).Times(1)
+				mockToken.EXPECT().GenerateAccessToken(mockUser, gomock.Any()).DoAndReturn(
+					func(u *user.User, opts ...jwttoken.TokenOption) (string, error) {
+						// Only the verify route accepts this audience
+						claims := &jwttoken.UserClaims{RegisteredClaims: &jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now())}}
+						for _, opt := range opts {
+							opt(claims)
+						}
+						assert.Equal(t, jwt.ClaimStrings{oauth.DeviceVerifyAudience}, claims.Audience)
+						assert.WithinDuration(t, time.Now().Add(5*time.Minute), claims.ExpiresAt.Time, time.Second)
+						return "mock-device-token", nil
+					},
+				).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "fail invalid credentials",
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockUserSrv *userservice.MockUserService) {
+				mockUserSrv.EXPECT().Authenticate(gomock.Any(), "mock@mail.com", "password").Return(nil, errs.ErrInvalidEmailOrPassword).Times(1)
+			},
+			expectedErr: errs.ErrInvalidEmailOrPassword,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockToken, _, _, mockUserSrv, service := setup(t)
+
+		tc.mockFn(mockToken, mockUserSrv)
+
+		resp, err := service.DeviceLogin(context.Background(), "mock@mail.com", "password")
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, "mock-device-token", resp.AccessToken)
+			assert.Equal(t, 300, resp.ExpiresIn)
+		}
+	}
+}
+
+func TestVerifyDeviceCode(t *testing.T) {
+	type testCase struct {
+		name        string
+		userCode    string
+		approve     bool
+		mockFn      func(mockRepo *oauthrepository.MockOAuthRepository)
+		expectedErr error
+	}
+
+	pending := func() *oauth.DeviceCode {
+		return &oauth.DeviceCode{
+			ID:        "mock-device-1",
+			UserCode:  "BCDFGHJK",
+			Status:    oauth.DeviceCodePending,
+			ExpiresAt: time.Now().Add(time.Minute),
+		}
+	}
+
+	testCases := []testCase{
+		{
+			name:     "success approve",
+			userCode: "bcdf-ghjk",
+			approve:  true,
+			mockFn: func(mockRepo *oauthrepository.MockOAuthRepository) {
+				mockRepo.EXPECT().FindPendingDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(pending(), nil).Times(1)
+				mockRepo.EXPECT().DecideDeviceCode(gomock.Any(), "mock-device-1", oauth.DeviceCodeApproved, "mock-uuid-1").Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "success deny",
+			userCode: "BCDF-GHJK",
+			approve:  false,
+			mockFn: func(mockRepo *oauthrepository.MockOAuthRepository) {
+				mockRepo.EXPECT().FindPendingDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(pending(), nil).Times(1)
+				mockRepo.EXPECT().DecideDeviceCode(gomock.Any(), "mock-device-1", oauth.DeviceCodeDenied, "mock-uuid-1").Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "fail user code not found",
+			userCode: "BCDF-GHJK",
+			approve:  true,
+			mockFn: func(mockRepo *oauthrepository.MockOAuthRepository) {
+				mockRepo.EXPECT().FindPendingDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(nil, errs.ErrInvalidUserCode).Times(1)
+			},
+			expectedErr: errs.ErrInvalidUserCode,
+		},
+		{
+			name:     "fail user code expired",
+			userCode: "BCDF-GHJK",
+			approve:  true,
+			mockFn: func(mockRepo *oauthrepository.MockOAuthRepository) {
+				dc := pending()
+				dc.ExpiresAt = time.Now().Add(-time.Minute)
+				mockRepo.EXPECT().FindPendingDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(dc, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidUserCode,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, _, mockRepo, _, service := setup(t)
+
+		tc.mockFn(mockRepo)
+
+		ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+
+		err := service.VerifyDeviceCode(ctx, tc.userCode, tc.approve)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestExchangeDeviceCode(t *testing.T) {
+	type testCase struct {
+		name        string
+		clientID    string
+		mockFn      func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService)
+		expectedErr error
+	}
+
+	const deviceCode = "mock-device-code"
+	userID := "mock-uuid-1"
+
+	deviceCodeWith := func(status string, lastPolled *time.Time) *oauth.DeviceCode {
+		dc := &oauth.DeviceCode{
+			ID:           "mock-device-1",
+			ClientID:     "cli",
+			Scope:        "profile:read",
+			Status:       status,
+			PollInterval: 5,
+			LastPolledAt: lastPolled,
+			ExpiresAt:    time.Now().Add(time.Minute),
+		}
+		if status == oauth.DeviceCodeApproved {
+			dc.UserID = &userID
+		}
+		return dc
+	}
+	longAgo := time.Now().Add(-time.Minute)
+	justNow := time.Now()
+
+	testCases := []testCase{
+		{
+			name:     "success approved",
+			clientID: "cli",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService) {
+				mockRepo.EXPECT().FindDeviceCodeByHash(gomock.Any(), signer.Hash(deviceCode)).Return(deviceCodeWith(oauth.DeviceCodeApproved, &longAgo), nil).Times(1)
+				mockRepo.EXPECT().UpdateDeviceCodePoll(gomock.Any(), "mock-device-1", gomock.Any(), 5).Return(nil).Times(1)
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				mockRepo.EXPECT().ConsumeDeviceCodeTx(gomock.Any(), nil, "mock-device-1").Return(nil).Times(1)
+				mockUserSrv.EXPECT().IssueTokensTx(gomock.Any(), nil, userID, "cli", gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, userID, clientID string, opts ...jwttoken.TokenOption) (*userservice.UserTokenResponse, error) {
+						// The requested scope is carried into the tokens
+						claims := &jwttoken.UserClaims{}
+						for _, opt := range opts {
+							opt(claims)
+						}
+						assert.Equal(t, "profile:read", claims.Scope)
+						return &userservice.UserTokenResponse{AccessToken: "mock-access-token"}, nil
+					},
+				).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "fail issue tokens",
+			clientID: "cli",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService) {
+				mockRepo.EXPECT().FindDeviceCodeByHash(gomock.Any(), gomock.Any()).Return(deviceCodeWith(oauth.DeviceCodeApproved, nil), nil).Times(1)
+				mockRepo.EXPECT().UpdateDeviceCodePoll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				// Consumed in the same transaction, so the failed issue rolls it back
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				mockRepo.EXPECT().ConsumeDeviceCodeTx(gomock.Any(), nil, "mock-device-1").Return(nil).Times(1)
+				mockUserSrv.EXPECT().IssueTokensTx(gomock.Any(), nil, userID, "cli", gomock.Any()).Return(nil, ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name:     "fail authorization pending",
+			clientID: "cli",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService) {
+				mockRepo.EXPECT().FindDeviceCodeByHash(gomock.Any(), gomock.Any()).Return(deviceCodeWith(oauth.DeviceCodePending, nil), nil).Times(1)
+				mockRepo.EXPECT().UpdateDeviceCodePoll(gomock.Any(), "mock-device-1", gomock.Any(), 5).Return(nil).Times(1)
+			},
+			expectedErr: errs.ErrAuthorizationPending,
+		},
+		{
+			name:     "fail slow down",
+			clientID: "cli",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService) {
+				mockRepo.EXPECT().FindDeviceCodeByHash(gomock.Any(), gomock.Any()).Return(deviceCodeWith(oauth.DeviceCodePending, &justNow), nil).Times(1)
+				mockRepo.EXPECT().UpdateDeviceCodePoll(gomock.Any(), "mock-device-1", gomock.Any(), 10).Return(nil).Times(1)
+			},
+			expectedErr: errs.ErrSlowDown,
+		},
+		{
+			name:     "fail access denied",
+			clientID: "cli",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService) {
+				mockRepo.EXPECT().FindDeviceCodeByHash(gomock.Any(), gomock.Any()).Return(deviceCodeWith(oauth.DeviceCodeDenied, nil), nil).Times(1)
+				mockRepo.EXPECT().UpdateDeviceCodePoll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: errs.ErrAccessDenied,
+		},
+		{
+			name:     "fail expired",
+			clientID: "cli",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService) {
+				dc := deviceCodeWith(oauth.DeviceCodePending, nil)
+				dc.ExpiresAt = time.Now().Add(-time.Second)
+				mockRepo.EXPECT().FindDeviceCodeByHash(gomock.Any(), gomock.Any()).Return(dc, nil).Times(1)
+			},
+			expectedErr: errs.ErrExpiredToken,
+		},
+		{
+			name:     "fail client mismatch",
+			clientID: "other",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService) {
+				mockRepo.EXPECT().FindDeviceCodeByHash(gomock.Any(), gomock.Any()).Return(deviceCodeWith(oauth.DeviceCodeApproved, nil), nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidGrant,
+		},
+		{
+			name:     "fail already consumed",
+			clientID: "cli",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *oauthrepository.MockOAuthRepository, mockUserSrv *userservice.MockUserService) {
+				mockRepo.EXPECT().FindDeviceCodeByHash(gomock.Any(), gomock.Any()).Return(deviceCodeWith(oauth.DeviceCodeApproved, nil), nil).Times(1)
+				mockRepo.EXPECT().UpdateDeviceCodePoll(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				mockRepo.EXPECT().ConsumeDeviceCodeTx(gomock.Any(), nil, "mock-device-1").Return(errs.ErrInvalidGrant).Times(1)
+			},
+			expectedErr: errs.ErrInvalidGrant,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, mockTx, mockRepo, mockUserSrv, service := setup(t)
+
+		tc.mockFn(mockTx, mockRepo, mockUserSrv)
+
+		resp, err := service.ExchangeDeviceCode(context.Background(), deviceCode, tc.clientID)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, "mock-access-token", resp.AccessToken)
+		}
+	}
+}
+
+func TestExchangeToken(t *testing.T) {
+	type testCase struct {
+		name          string
+		withActor     bool
+		dpopKey       string
+		audience      string
+		scope         string
+		mockFn        func(mockToken *jwttoken.MockJWTToken)
+		expectedScope string
+		expectedErr   error
+	}
+
+	subjectClaims := func(scope string) *jwttoken.UserClaims {
+		return &jwttoken.UserClaims{UserID: "mock-uuid-1", Email: "mock@mail.com", Role: "admin", OrgID: "mock-org-1", Scope: scope}
+	}
+	boundClaims := func() *jwttoken.UserClaims {
+		claims := subjectClaims("")
+		claims.Cnf = &jwttoken.Confirmation{JKT: "mock-jkt"}
+		return claims
+	}
+
+	testCases := []testCase{
+		{
+			name:      "success",
+			withActor: true,
+			audience:  "billing",
+			scope:     "invoices:read",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken("mock-subject-token").Return(subjectClaims(""), nil).Times(1)
+				mockToken.EXPECT().GenerateAccessToken(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(u *user.User, opts ...jwttoken.TokenOption) (string, error) {
+						claims := &jwttoken.UserClaims{RegisteredClaims: &jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now())}}
+						for _, opt := range opts {
+							opt(claims)
+						}
+						assert.Equal(t, "mock-uuid-1", u.ID)
+						assert.Empty(t, u.Role)
+						assert.Equal(t, jwt.ClaimStrings{"billing"}, claims.Audience)
+						assert.Equal(t, "spiffe://example.org/gateway", claims.Act.Sub)
+						assert.Equal(t, "mock-org-1", claims.OrgID)
+						assert.Empty(t, claims.OrgRole)
+						assert.Nil(t, claims.Cnf)
+						return "mock-exchanged-token", nil
+					},
+				).Times(1)
+			},
+			expectedScope: "invoices:read",
+			expectedErr:   nil,
+		},
+		{
+			name:      "success narrower scope",
+			withActor: true,
+			audience:  "billing",
+			scope:     "invoices:read",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken(gomock.Any()).Return(subjectClaims("invoices:read invoices:write"), nil).Times(1)
+				mockToken.EXPECT().GenerateAccessToken(gomock.Any(), gomock.Any()).Return("mock-exchanged-token", nil).Times(1)
+			},
+			expectedScope: "invoices:read",
+			expectedErr:   nil,
+		},
+		{
+			name:        "fail no actor",
+			withActor:   false,
+			audience:    "billing",
+			scope:       "invoices:read",
+			mockFn:      func(mockToken *jwttoken.MockJWTToken) {},
+			expectedErr: errs.ErrInvalidClient,
+		},
+		{
+			name:        "fail audience not allowed",
+			withActor:   true,
+			audience:    "payroll",
+			scope:       "invoices:read",
+			mockFn:      func(mockToken *jwttoken.MockJWTToken) {},
+			expectedErr: errs.ErrInvalidTarget,
+		},
+		{
+			name:      "fail invalid subject token",
+			withActor: true,
+			audience:  "billing",
+			scope:     "invoices:read",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken(gomock.Any()).Return(nil, jwttoken.ErrAudienceRestricted).Times(1)
+			},
+			expectedErr: errs.ErrInvalidGrant,
+		},
+		{
+			name:      "fail scope broader than subject",
+			withActor: true,
+			audience:  "billing",
+			scope:     "invoices:read invoices:write",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken(gomock.Any()).Return(subjectClaims("invoices:read"), nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidScope,
+		},
+		{
+			name:      "success dpop-bound subject",
+			withActor: true,
+			dpopKey:   "mock-jkt",
+			audience:  "billing",
+			scope:     "invoices:read",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken(gomock.Any()).Return(boundClaims(), nil).Times(1)
+				mockToken.EXPECT().GenerateAccessToken(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(u *user.User, opts ...jwttoken.TokenOption) (string, error) {
+						claims := &jwttoken.UserClaims{RegisteredClaims: &jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now())}}
+						for _, opt := range opts {
+							opt(claims)
+						}
+						assert.Equal(t, &jwttoken.Confirmation{JKT: "mock-jkt"}, claims.Cnf)
+						return "mock-exchanged-token", nil
+					},
+				).Times(1)
+			},
+			expectedScope: "invoices:read",
+			expectedErr:   nil,
+		},
+		{
+			name:      "fail dpop-bound subject without proof",
+			withActor: true,
+			audience:  "billing",
+			scope:     "invoices:read",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken(gomock.Any()).Return(boundClaims(), nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidDPoPProof,
+		},
+		{
+			name:      "fail dpop-bound subject with other key",
+			withActor: true,
+			dpopKey:   "other-jkt",
+			audience:  "billing",
+			scope:     "invoices:read",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken(gomock.Any()).Return(boundClaims(), nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidDPoPProof,
+		},
+		{
+			name:      "fail empty scope",
+			withActor: true,
+			audience:  "billing",
+			scope:     "",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken(gomock.Any()).Return(subjectClaims(""), nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidScope,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockToken, _, _, _, service := setup(t)
+
+		tc.mockFn(mockToken)
+
+		ctx := context.Background()
+		if tc.withActor {
+			ctx = auth.SetContextServicePrincipal(ctx, &auth.ServicePrincipal{Name: "spiffe://example.org/gateway"})
+		}
+		if tc.dpopKey != "" {
+			ctx = auth.SetContextDPoPKey(ctx, tc.dpopKey)
+		}
+
+		resp, err := service.ExchangeToken(ctx, "mock-subject-token", tc.audience, tc.scope)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, "mock-exchanged-token", resp.AccessToken)
+			assert.Equal(t, oauth.TokenTypeAccessToken, resp.IssuedTokenType)
+			assert.Equal(t, 300, resp.ExpiresIn)
+			assert.Equal(t, tc.expectedScope, resp.Scope)
+		}
+	}
+}
+
+func setup(t *testing.T) (*jwttoken.MockJWTToken, *database.MockTxManager, *oauthrepository.MockOAuthRepository, *userservice.MockUserService, oauthservice.OAuthService) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockToken := jwttoken.NewMockJWTToken(ctrl)
+	mockTx := database.NewMockTxManager(ctrl)
+	mockRepo := oauthrepository.NewMockOAuthRepository(ctrl)
+	mockUserSrv := userservice.NewMockUserService(ctrl)
+
+	cfg := &config.OAuthConfig{
+		DeviceClients:   []string{"cli"},
+		DeviceCodeTTL:   10 * time.Minute,
+		PollInterval:    5 * time.Second,
+		VerificationURI: "http://localhost:8080/api/v1/oauth/device",
+		DeviceLoginTTL:  5 * time.Minute,
+
+		ExchangeAudiences: []string{"billing"},
+		ExchangeTokenTTL:  5 * time.Minute,
+	}
+	service := oauthservice.NewOAuthService(cfg, mockTx, mockToken, mockRepo, mockUserSrv)
+
+	return mockToken, mockTx, mockRepo, mockUserSrv, service
+}
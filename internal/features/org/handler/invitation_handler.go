@@ -0,0 +1,109 @@
+package orghandler
+
+import (
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	orgservice "github.com/codepnw/go-starter-kit/internal/features/org/service"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type invitationHandler struct {
+	service orgservice.InvitationService
+}
+
+func NewInvitationHandler(service orgservice.InvitationService) *invitationHandler {
+	return &invitationHandler{service: service}
+}
+
+func (h *invitationHandler) CreateInvitation(c *gin.Context) {
+	uri := new(OrgURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	req := new(CreateInvitationReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.CreateInvitation(c.Request.Context(), uri.OrgID, req.Email, req.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *invitationHandler) ListInvitations(c *gin.Context) {
+	uri := new(OrgURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	q, err := pagination.Parse(c.Request.URL.Query(), org.InvitationListSpec)
+	if err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	page, err := h.service.ListInvitations(c.Request.Context(), uri.OrgID, q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponsePage(c, page)
+}
+
+func (h *invitationHandler) ResendInvitation(c *gin.Context) {
+	uri := new(InvitationURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.ResendInvitation(c.Request.Context(), uri.OrgID, uri.InvitationID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *invitationHandler) RevokeInvitation(c *gin.Context) {
+	uri := new(InvitationURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	if err := h.service.RevokeInvitation(c.Request.Context(), uri.OrgID, uri.InvitationID); err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
+
+func (h *invitationHandler) AcceptInvitation(c *gin.Context) {
+	req := new(AcceptInvitationReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.AcceptInvitation(c.Request.Context(), req.Token, req.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
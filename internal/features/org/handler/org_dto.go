@@ -0,0 +1,25 @@
+package orghandler
+
+type CreateOrganizationReq struct {
+	Name string `json:"name" validate:"required,max=255,singleline"`
+}
+
+type CreateInvitationReq struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required"`
+}
+
+type AcceptInvitationReq struct {
+	Token string `json:"token" validate:"required"`
+	// Password : required when the invited email has no account yet
+	Password string `json:"password" validate:"omitempty,password"`
+}
+
+type OrgURI struct {
+	OrgID string `uri:"org_id" validate:"required,uuid"`
+}
+
+type InvitationURI struct {
+	OrgID        string `uri:"org_id" validate:"required,uuid"`
+	InvitationID string `uri:"invitation_id" validate:"required,uuid"`
+}
@@ -0,0 +1,97 @@
+package orghandler
+
+import (
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	orgservice "github.com/codepnw/go-starter-kit/internal/features/org/service"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type orgHandler struct {
+	service orgservice.OrgService
+}
+
+func NewOrgHandler(service orgservice.OrgService) *orgHandler {
+	return &orgHandler{service: service}
+}
+
+func (h *orgHandler) CreateOrganization(c *gin.Context) {
+	req := new(CreateOrganizationReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.CreateOrganization(c.Request.Context(), req.Name)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *orgHandler) ListMyOrganizations(c *gin.Context) {
+	resp, err := h.service.ListMyOrganizations(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *orgHandler) GetOrganization(c *gin.Context) {
+	uri := new(OrgURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.GetOrganization(c.Request.Context(), uri.OrgID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *orgHandler) ListMembers(c *gin.Context) {
+	uri := new(OrgURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	q, err := pagination.Parse(c.Request.URL.Query(), org.MemberListSpec)
+	if err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	page, err := h.service.ListMembers(c.Request.Context(), uri.OrgID, q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponsePage(c, page)
+}
+
+func (h *orgHandler) SwitchOrganization(c *gin.Context) {
+	uri := new(OrgURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.SwitchOrganization(c.Request.Context(), uri.OrgID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
@@ -0,0 +1,111 @@
+package org
+
+import (
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+)
+
+// Membership roles
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+type Organization struct {
+	ID        string    `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	CreatedBy string    `db:"created_by" json:"created_by"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type Membership struct {
+	ID        string    `db:"id" json:"id"`
+	OrgID     string    `db:"org_id" json:"org_id"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	Role      string    `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+
+	// Joined fields
+	OrgName string `db:"org_name" json:"org_name,omitempty"`
+	Email   string `db:"email" json:"email,omitempty"`
+}
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+type Invitation struct {
+	ID         string     `db:"id" json:"id"`
+	OrgID      string     `db:"org_id" json:"org_id"`
+	Email      string     `db:"email" json:"email"`
+	Role       string     `db:"role" json:"role"`
+	InvitedBy  string     `db:"invited_by" json:"invited_by"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	Status     string     `db:"status" json:"status"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	AcceptedAt *time.Time `db:"accepted_at" json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// MemberListSpec : sorting and filtering of GET /orgs/:org_id/members
+var MemberListSpec = pagination.Spec{
+	Sorts: map[string]pagination.Field{
+		"id":         {Column: "m.id", Type: pagination.UUID},
+		"email":      {Column: "u.email"},
+		"created_at": {Column: "m.created_at", Type: pagination.Time},
+	},
+	Filters: map[string]pagination.Field{
+		"role": {Column: "m.role"},
+	},
+	DefaultSort: "created_at",
+	Key:         "id",
+}
+
+func (m *Membership) SortValues() map[string]any {
+	return map[string]any{
+		"id":         m.ID,
+		"email":      m.Email,
+		"created_at": m.CreatedAt,
+	}
+}
+
+// InvitationListSpec : sorting and filtering of GET /orgs/:org_id/invitations
+var InvitationListSpec = pagination.Spec{
+	Sorts: map[string]pagination.Field{
+		"id":         {Column: "id", Type: pagination.UUID},
+		"email":      {Column: "email"},
+		"created_at": {Column: "created_at", Type: pagination.Time},
+		"expires_at": {Column: "expires_at", Type: pagination.Time},
+	},
+	Filters: map[string]pagination.Field{
+		"status": {Column: "status"},
+		"role":   {Column: "role"},
+		"email":  {Column: "email"},
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+func (inv *Invitation) SortValues() map[string]any {
+	return map[string]any{
+		"id":         inv.ID,
+		"email":      inv.Email,
+		"created_at": inv.CreatedAt,
+		"expires_at": inv.ExpiresAt,
+	}
+}
+
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleAdmin, RoleMember:
+		return true
+	}
+	return false
+}
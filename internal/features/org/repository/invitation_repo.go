@@ -0,0 +1,207 @@
+package orgrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+)
+
+//go:generate mockgen -source=invitation_repo.go -destination=invitation_repo_mock.go -package=orgrepository
+type InvitationRepository interface {
+	CheckPendingInvitationExists(ctx context.Context, orgID, email string) (bool, error)
+	FindInvitationByID(ctx context.Context, invitationID string) (*org.Invitation, error)
+	ListInvitationsByOrgID(ctx context.Context, orgID string, q *pagination.Query) ([]*org.Invitation, error)
+	CountInvitationsByOrgID(ctx context.Context, orgID string, q *pagination.Query) (int, error)
+	UpdateInvitationToken(ctx context.Context, invitationID, tokenHash string, expiresAt time.Time) error
+	RevokeInvitation(ctx context.Context, invitationID string) error
+
+	// Transaction
+	InsertInvitationTx(ctx context.Context, tx *sql.Tx, inv *org.Invitation) error
+	UpdateInvitationTokenTx(ctx context.Context, tx *sql.Tx, invitationID, tokenHash string, expiresAt time.Time) error
+	AcceptInvitationTx(ctx context.Context, tx *sql.Tx, invitationID string) error
+}
+
+type invitationRepository struct {
+	db *sql.DB
+}
+
+func NewInvitationRepository(db *sql.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) CheckPendingInvitationExists(ctx context.Context, orgID, email string) (bool, error) {
+	var dummy bool
+	query := `
+		SELECT 1 FROM invitations
+		WHERE org_id = $1 AND email = $2 AND status = 'pending' LIMIT 1
+	`
+	if err := r.db.QueryRowContext(ctx, query, orgID, email).Scan(&dummy); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *invitationRepository) InsertInvitationTx(ctx context.Context, tx *sql.Tx, inv *org.Invitation) error {
+	query := `
+		INSERT INTO invitations (org_id, email, role, invited_by, token_hash, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at
+	`
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		inv.OrgID,
+		inv.Email,
+		inv.Role,
+		inv.InvitedBy,
+		inv.TokenHash,
+		inv.Status,
+		inv.ExpiresAt,
+	).Scan(
+		&inv.ID,
+		&inv.CreatedAt,
+		&inv.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *invitationRepository) FindInvitationByID(ctx context.Context, invitationID string) (*org.Invitation, error) {
+	var inv org.Invitation
+	query := `
+		SELECT id, org_id, email, role, invited_by, token_hash, status, expires_at, accepted_at, created_at, updated_at
+		FROM invitations WHERE id = $1 LIMIT 1
+	`
+	if err := r.db.QueryRowContext(ctx, query, invitationID).Scan(
+		&inv.ID,
+		&inv.OrgID,
+		&inv.Email,
+		&inv.Role,
+		&inv.InvitedBy,
+		&inv.TokenHash,
+		&inv.Status,
+		&inv.ExpiresAt,
+		&inv.AcceptedAt,
+		&inv.CreatedAt,
+		&inv.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *invitationRepository) ListInvitationsByOrgID(ctx context.Context, orgID string, q *pagination.Query) ([]*org.Invitation, error) {
+	c := q.Clause(1)
+	query := `
+		SELECT id, org_id, email, role, invited_by, status, expires_at, accepted_at, created_at, updated_at
+		FROM invitations WHERE org_id = $1 AND ` + c.Where + `
+		ORDER BY ` + c.OrderBy + ` ` + c.Limit
+	rows, err := r.db.QueryContext(ctx, query, append([]any{orgID}, c.Args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := make([]*org.Invitation, 0)
+	for rows.Next() {
+		var inv org.Invitation
+		if err := rows.Scan(
+			&inv.ID,
+			&inv.OrgID,
+			&inv.Email,
+			&inv.Role,
+			&inv.InvitedBy,
+			&inv.Status,
+			&inv.ExpiresAt,
+			&inv.AcceptedAt,
+			&inv.CreatedAt,
+			&inv.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, &inv)
+	}
+	return invitations, rows.Err()
+}
+
+func (r *invitationRepository) CountInvitationsByOrgID(ctx context.Context, orgID string, q *pagination.Query) (int, error) {
+	c := q.Clause(1)
+	query := `SELECT COUNT(*) FROM invitations WHERE org_id = $1 AND ` + c.Filter
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query, append([]any{orgID}, c.FilterArgs...)...).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (r *invitationRepository) UpdateInvitationToken(ctx context.Context, invitationID, tokenHash string, expiresAt time.Time) error {
+	return updateInvitationToken(ctx, r.db, invitationID, tokenHash, expiresAt)
+}
+
+func (r *invitationRepository) UpdateInvitationTokenTx(ctx context.Context, tx *sql.Tx, invitationID, tokenHash string, expiresAt time.Time) error {
+	return updateInvitationToken(ctx, tx, invitationID, tokenHash, expiresAt)
+}
+
+// updateInvitationToken : db is the *sql.DB or the *sql.Tx
+func updateInvitationToken(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, invitationID, tokenHash string, expiresAt time.Time) error {
+	query := `
+		UPDATE invitations SET token_hash = $1, expires_at = $2, updated_at = NOW()
+		WHERE id = $3 AND status = 'pending'
+	`
+	res, err := db.ExecContext(ctx, query, tokenHash, expiresAt, invitationID)
+	if err != nil {
+		return err
+	}
+	return checkPendingUpdated(res)
+}
+
+func (r *invitationRepository) RevokeInvitation(ctx context.Context, invitationID string) error {
+	query := `
+		UPDATE invitations SET status = 'revoked', updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`
+	res, err := r.db.ExecContext(ctx, query, invitationID)
+	if err != nil {
+		return err
+	}
+	return checkPendingUpdated(res)
+}
+
+func (r *invitationRepository) AcceptInvitationTx(ctx context.Context, tx *sql.Tx, invitationID string) error {
+	query := `
+		UPDATE invitations SET status = 'accepted', accepted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`
+	res, err := tx.ExecContext(ctx, query, invitationID)
+	if err != nil {
+		return err
+	}
+	return checkPendingUpdated(res)
+}
+
+// checkPendingUpdated : status guarded updates affect no rows once the invitation left "pending"
+func checkPendingUpdated(res sql.Result) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrInvitationNotPending
+	}
+	return nil
+}
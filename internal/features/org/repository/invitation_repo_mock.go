@@ -0,0 +1,169 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: invitation_repo.go
+
+// Package orgrepository is a generated GoMock package.
+package orgrepository
+
+import (
+	context "context"
+	sql "database/sql"
+	reflect "reflect"
+	time "time"
+
+	org "github.com/codepnw/go-starter-kit/internal/features/org"
+	pagination "github.com/codepnw/go-starter-kit/pkg/pagination"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockInvitationRepository is a mock of InvitationRepository interface.
+type MockInvitationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvitationRepositoryMockRecorder
+}
+
+// MockInvitationRepositoryMockRecorder is the mock recorder for MockInvitationRepository.
+type MockInvitationRepositoryMockRecorder struct {
+	mock *MockInvitationRepository
+}
+
+// NewMockInvitationRepository creates a new mock instance.
+func NewMockInvitationRepository(ctrl *gomock.Controller) *MockInvitationRepository {
+	mock := &MockInvitationRepository{ctrl: ctrl}
+	mock.recorder = &MockInvitationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInvitationRepository) EXPECT() *MockInvitationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AcceptInvitationTx mocks base method.
+func (m *MockInvitationRepository) AcceptInvitationTx(ctx context.Context, tx *sql.Tx, invitationID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcceptInvitationTx", ctx, tx, invitationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AcceptInvitationTx indicates an expected call of AcceptInvitationTx.
+func (mr *MockInvitationRepositoryMockRecorder) AcceptInvitationTx(ctx, tx, invitationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptInvitationTx", reflect.TypeOf((*MockInvitationRepository)(nil).AcceptInvitationTx), ctx, tx, invitationID)
+}
+
+// CheckPendingInvitationExists mocks base method.
+func (m *MockInvitationRepository) CheckPendingInvitationExists(ctx context.Context, orgID, email string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPendingInvitationExists", ctx, orgID, email)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckPendingInvitationExists indicates an expected call of CheckPendingInvitationExists.
+func (mr *MockInvitationRepositoryMockRecorder) CheckPendingInvitationExists(ctx, orgID, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPendingInvitationExists", reflect.TypeOf((*MockInvitationRepository)(nil).CheckPendingInvitationExists), ctx, orgID, email)
+}
+
+// CountInvitationsByOrgID mocks base method.
+func (m *MockInvitationRepository) CountInvitationsByOrgID(ctx context.Context, orgID string, q *pagination.Query) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountInvitationsByOrgID", ctx, orgID, q)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountInvitationsByOrgID indicates an expected call of CountInvitationsByOrgID.
+func (mr *MockInvitationRepositoryMockRecorder) CountInvitationsByOrgID(ctx, orgID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountInvitationsByOrgID", reflect.TypeOf((*MockInvitationRepository)(nil).CountInvitationsByOrgID), ctx, orgID, q)
+}
+
+// FindInvitationByID mocks base method.
+func (m *MockInvitationRepository) FindInvitationByID(ctx context.Context, invitationID string) (*org.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindInvitationByID", ctx, invitationID)
+	ret0, _ := ret[0].(*org.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindInvitationByID indicates an expected call of FindInvitationByID.
+func (mr *MockInvitationRepositoryMockRecorder) FindInvitationByID(ctx, invitationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindInvitationByID", reflect.TypeOf((*MockInvitationRepository)(nil).FindInvitationByID), ctx, invitationID)
+}
+
+// InsertInvitationTx mocks base method.
+func (m *MockInvitationRepository) InsertInvitationTx(ctx context.Context, tx *sql.Tx, inv *org.Invitation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertInvitationTx", ctx, tx, inv)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertInvitationTx indicates an expected call of InsertInvitationTx.
+func (mr *MockInvitationRepositoryMockRecorder) InsertInvitationTx(ctx, tx, inv interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertInvitationTx", reflect.TypeOf((*MockInvitationRepository)(nil).InsertInvitationTx), ctx, tx, inv)
+}
+
+// ListInvitationsByOrgID mocks base method.
+func (m *MockInvitationRepository) ListInvitationsByOrgID(ctx context.Context, orgID string, q *pagination.Query) ([]*org.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListInvitationsByOrgID", ctx, orgID, q)
+	ret0, _ := ret[0].([]*org.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListInvitationsByOrgID indicates an expected call of ListInvitationsByOrgID.
+func (mr *MockInvitationRepositoryMockRecorder) ListInvitationsByOrgID(ctx, orgID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInvitationsByOrgID", reflect.TypeOf((*MockInvitationRepository)(nil).ListInvitationsByOrgID), ctx, orgID, q)
+}
+
+// RevokeInvitation mocks base method.
+func (m *MockInvitationRepository) RevokeInvitation(ctx context.Context, invitationID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeInvitation", ctx, invitationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeInvitation indicates an expected call of RevokeInvitation.
+func (mr *MockInvitationRepositoryMockRecorder) RevokeInvitation(ctx, invitationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeInvitation", reflect.TypeOf((*MockInvitationRepository)(nil).RevokeInvitation), ctx, invitationID)
+}
+
+// UpdateInvitationToken mocks base method.
+func (m *MockInvitationRepository) UpdateInvitationToken(ctx context.Context, invitationID, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateInvitationToken", ctx, invitationID, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateInvitationToken indicates an expected call of UpdateInvitationToken.
+func (mr *MockInvitationRepositoryMockRecorder) UpdateInvitationToken(ctx, invitationID, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInvitationToken", reflect.TypeOf((*MockInvitationRepository)(nil).UpdateInvitationToken), ctx, invitationID, tokenHash, expiresAt)
+}
+
+// UpdateInvitationTokenTx mocks base method.
+func (m *MockInvitationRepository) UpdateInvitationTokenTx(ctx context.Context, tx *sql.Tx, invitationID, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateInvitationTokenTx", ctx, tx, invitationID, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateInvitationTokenTx indicates an expected call of UpdateInvitationTokenTx.
+func (mr *MockInvitationRepositoryMockRecorder) UpdateInvitationTokenTx(ctx, tx, invitationID, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInvitationTokenTx", reflect.TypeOf((*MockInvitationRepository)(nil).UpdateInvitationTokenTx), ctx, tx, invitationID, tokenHash, expiresAt)
+}
@@ -0,0 +1,182 @@
+package orgrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+)
+
+//go:generate mockgen -source=org_repo.go -destination=org_repo_mock.go -package=orgrepository
+type OrgRepository interface {
+	FindOrganizationByID(ctx context.Context, orgID string) (*org.Organization, error)
+	FindMembership(ctx context.Context, orgID, userID string) (*org.Membership, error)
+	ListMembershipsByUserID(ctx context.Context, userID string) ([]*org.Membership, error)
+	ListMembersByOrgID(ctx context.Context, orgID string, q *pagination.Query) ([]*org.Membership, error)
+	CountMembersByOrgID(ctx context.Context, orgID string, q *pagination.Query) (int, error)
+
+	// Transaction
+	InsertOrganizationTx(ctx context.Context, tx *sql.Tx, o *org.Organization) error
+	InsertMembershipTx(ctx context.Context, tx *sql.Tx, m *org.Membership) error
+}
+
+type orgRepository struct {
+	db *sql.DB
+}
+
+func NewOrgRepository(db *sql.DB) OrgRepository {
+	return &orgRepository{db: db}
+}
+
+func (r *orgRepository) InsertOrganizationTx(ctx context.Context, tx *sql.Tx, o *org.Organization) error {
+	query := `
+		INSERT INTO organizations (name, created_by)
+		VALUES ($1, $2) RETURNING id, created_at, updated_at
+	`
+	if err := tx.QueryRowContext(ctx, query, o.Name, o.CreatedBy).Scan(
+		&o.ID,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *orgRepository) InsertMembershipTx(ctx context.Context, tx *sql.Tx, m *org.Membership) error {
+	query := `
+		INSERT INTO memberships (org_id, user_id, role)
+		VALUES ($1, $2, $3) RETURNING id, created_at
+	`
+	if err := tx.QueryRowContext(ctx, query, m.OrgID, m.UserID, m.Role).Scan(
+		&m.ID,
+		&m.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *orgRepository) FindOrganizationByID(ctx context.Context, orgID string) (*org.Organization, error) {
+	var o org.Organization
+	query := `
+		SELECT id, name, created_by, created_at, updated_at
+		FROM organizations WHERE id = $1 LIMIT 1
+	`
+	if err := r.db.QueryRowContext(ctx, query, orgID).Scan(
+		&o.ID,
+		&o.Name,
+		&o.CreatedBy,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *orgRepository) FindMembership(ctx context.Context, orgID, userID string) (*org.Membership, error) {
+	var m org.Membership
+	query := `
+		SELECT id, org_id, user_id, role, created_at
+		FROM memberships WHERE org_id = $1 AND user_id = $2 LIMIT 1
+	`
+	if err := r.db.QueryRowContext(ctx, query, orgID, userID).Scan(
+		&m.ID,
+		&m.OrgID,
+		&m.UserID,
+		&m.Role,
+		&m.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotOrganizationMember
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *orgRepository) ListMembershipsByUserID(ctx context.Context, userID string) ([]*org.Membership, error) {
+	query := `
+		SELECT m.id, m.org_id, m.user_id, m.role, m.created_at, o.name
+		FROM memberships m
+		JOIN organizations o ON o.id = m.org_id
+		WHERE m.user_id = $1
+		ORDER BY m.created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := make([]*org.Membership, 0)
+	for rows.Next() {
+		var m org.Membership
+		if err := rows.Scan(
+			&m.ID,
+			&m.OrgID,
+			&m.UserID,
+			&m.Role,
+			&m.CreatedAt,
+			&m.OrgName,
+		); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, &m)
+	}
+	return memberships, rows.Err()
+}
+
+func (r *orgRepository) ListMembersByOrgID(ctx context.Context, orgID string, q *pagination.Query) ([]*org.Membership, error) {
+	c := q.Clause(1)
+	query := `
+		SELECT m.id, m.org_id, m.user_id, m.role, m.created_at, u.email
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND ` + c.Where + `
+		ORDER BY ` + c.OrderBy + ` ` + c.Limit
+	rows, err := r.db.QueryContext(ctx, query, append([]any{orgID}, c.Args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]*org.Membership, 0)
+	for rows.Next() {
+		var m org.Membership
+		if err := rows.Scan(
+			&m.ID,
+			&m.OrgID,
+			&m.UserID,
+			&m.Role,
+			&m.CreatedAt,
+			&m.Email,
+		); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+	return members, rows.Err()
+}
+
+func (r *orgRepository) CountMembersByOrgID(ctx context.Context, orgID string, q *pagination.Query) (int, error) {
+	c := q.Clause(1)
+	query := `
+		SELECT COUNT(*)
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND ` + c.Filter
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, query, append([]any{orgID}, c.FilterArgs...)...).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
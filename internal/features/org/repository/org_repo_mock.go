@@ -0,0 +1,141 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: org_repo.go
+
+// Package orgrepository is a generated GoMock package.
+package orgrepository
+
+import (
+	context "context"
+	sql "database/sql"
+	reflect "reflect"
+
+	org "github.com/codepnw/go-starter-kit/internal/features/org"
+	pagination "github.com/codepnw/go-starter-kit/pkg/pagination"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOrgRepository is a mock of OrgRepository interface.
+type MockOrgRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrgRepositoryMockRecorder
+}
+
+// MockOrgRepositoryMockRecorder is the mock recorder for MockOrgRepository.
+type MockOrgRepositoryMockRecorder struct {
+	mock *MockOrgRepository
+}
+
+// NewMockOrgRepository creates a new mock instance.
+func NewMockOrgRepository(ctrl *gomock.Controller) *MockOrgRepository {
+	mock := &MockOrgRepository{ctrl: ctrl}
+	mock.recorder = &MockOrgRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrgRepository) EXPECT() *MockOrgRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CountMembersByOrgID mocks base method.
+func (m *MockOrgRepository) CountMembersByOrgID(ctx context.Context, orgID string, q *pagination.Query) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountMembersByOrgID", ctx, orgID, q)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountMembersByOrgID indicates an expected call of CountMembersByOrgID.
+func (mr *MockOrgRepositoryMockRecorder) CountMembersByOrgID(ctx, orgID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountMembersByOrgID", reflect.TypeOf((*MockOrgRepository)(nil).CountMembersByOrgID), ctx, orgID, q)
+}
+
+// FindMembership mocks base method.
+func (m *MockOrgRepository) FindMembership(ctx context.Context, orgID, userID string) (*org.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindMembership", ctx, orgID, userID)
+	ret0, _ := ret[0].(*org.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindMembership indicates an expected call of FindMembership.
+func (mr *MockOrgRepositoryMockRecorder) FindMembership(ctx, orgID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindMembership", reflect.TypeOf((*MockOrgRepository)(nil).FindMembership), ctx, orgID, userID)
+}
+
+// FindOrganizationByID mocks base method.
+func (m *MockOrgRepository) FindOrganizationByID(ctx context.Context, orgID string) (*org.Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrganizationByID", ctx, orgID)
+	ret0, _ := ret[0].(*org.Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrganizationByID indicates an expected call of FindOrganizationByID.
+func (mr *MockOrgRepositoryMockRecorder) FindOrganizationByID(ctx, orgID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrganizationByID", reflect.TypeOf((*MockOrgRepository)(nil).FindOrganizationByID), ctx, orgID)
+}
+
+// InsertMembershipTx mocks base method.
+func (m_2 *MockOrgRepository) InsertMembershipTx(ctx context.Context, tx *sql.Tx, m *org.Membership) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "InsertMembershipTx", ctx, tx, m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertMembershipTx indicates an expected call of InsertMembershipTx.
+func (mr *MockOrgRepositoryMockRecorder) InsertMembershipTx(ctx, tx, m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertMembershipTx", reflect.TypeOf((*MockOrgRepository)(nil).InsertMembershipTx), ctx, tx, m)
+}
+
+// InsertOrganizationTx mocks base method.
+func (m *MockOrgRepository) InsertOrganizationTx(ctx context.Context, tx *sql.Tx, o *org.Organization) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertOrganizationTx", ctx, tx, o)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertOrganizationTx indicates an expected call of InsertOrganizationTx.
+func (mr *MockOrgRepositoryMockRecorder) InsertOrganizationTx(ctx, tx, o interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertOrganizationTx", reflect.TypeOf((*MockOrgRepository)(nil).InsertOrganizationTx), ctx, tx, o)
+}
+
+// ListMembersByOrgID mocks base method.
+func (m *MockOrgRepository) ListMembersByOrgID(ctx context.Context, orgID string, q *pagination.Query) ([]*org.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMembersByOrgID", ctx, orgID, q)
+	ret0, _ := ret[0].([]*org.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMembersByOrgID indicates an expected call of ListMembersByOrgID.
+func (mr *MockOrgRepositoryMockRecorder) ListMembersByOrgID(ctx, orgID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMembersByOrgID", reflect.TypeOf((*MockOrgRepository)(nil).ListMembersByOrgID), ctx, orgID, q)
+}
+
+// ListMembershipsByUserID mocks base method.
+func (m *MockOrgRepository) ListMembershipsByUserID(ctx context.Context, userID string) ([]*org.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMembershipsByUserID", ctx, userID)
+	ret0, _ := ret[0].([]*org.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMembershipsByUserID indicates an expected call of ListMembershipsByUserID.
+func (mr *MockOrgRepositoryMockRecorder) ListMembershipsByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMembershipsByUserID", reflect.TypeOf((*MockOrgRepository)(nil).ListMembershipsByUserID), ctx, userID)
+}
@@ -0,0 +1,287 @@
+package orgservice
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	orgrepository "github.com/codepnw/go-starter-kit/internal/features/org/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/mailer"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+	"github.com/codepnw/go-starter-kit/pkg/utils/signer"
+)
+
+type InvitationService interface {
+	CreateInvitation(ctx context.Context, orgID, email, role string) (*org.Invitation, error)
+	ListInvitations(ctx context.Context, orgID string, q *pagination.Query) (*pagination.Page[*org.Invitation], error)
+	ResendInvitation(ctx context.Context, orgID, invitationID string) (*org.Invitation, error)
+	RevokeInvitation(ctx context.Context, orgID, invitationID string) error
+	AcceptInvitation(ctx context.Context, token, password string) (*AcceptInvitationResponse, error)
+}
+
+type invitationService struct {
+	cfg      *config.InviteConfig
+	tx       database.TxManager
+	repo     orgrepository.InvitationRepository
+	orgRepo  orgrepository.OrgRepository
+	userRepo userrepository.UserRepository
+	userSrv  userservice.UserService
+	mailer   mailer.Mailer
+}
+
+func NewInvitationService(
+	cfg *config.InviteConfig,
+	tx database.TxManager,
+	repo orgrepository.InvitationRepository,
+	orgRepo orgrepository.OrgRepository,
+	userRepo userrepository.UserRepository,
+	userSrv userservice.UserService,
+	mailer mailer.Mailer,
+) InvitationService {
+	return &invitationService{
+		cfg:      cfg,
+		tx:       tx,
+		repo:     repo,
+		orgRepo:  orgRepo,
+		userRepo: userRepo,
+		userSrv:  userSrv,
+		mailer:   mailer,
+	}
+}
+
+type AcceptInvitationResponse struct {
+	Membership *org.Membership `json:"membership"`
+	// Tokens : only set when the invitation created a new account
+	Tokens *userservice.UserTokenResponse `json:"tokens,omitempty"`
+}
+
+func (s *invitationService) CreateInvitation(ctx context.Context, orgID, email, role string) (*org.Invitation, error) {
+	// Owners are only created with the organization
+	if !org.IsValidRole(role) || role == org.RoleOwner {
+		return nil, errs.ErrInvalidInvitationRole
+	}
+
+	inviterID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check Pending Invitation
+	exists, err := s.repo.CheckPendingInvitationExists(ctx, orgID, email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errs.ErrInvitationAlreadyExists
+	}
+
+	o, err := s.orgRepo.FindOrganizationByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &org.Invitation{
+		OrgID:     orgID,
+		Email:     email,
+		Role:      role,
+		InvitedBy: inviterID,
+		Status:    org.InvitationPending,
+		ExpiresAt: time.Now().Add(s.cfg.TTL),
+	}
+	// Insert with a placeholder hash, the signed token needs the invitation ID. Both
+	// writes commit together, so no invitation is left without a valid link.
+	var token string
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.InsertInvitationTx(ctx, tx, inv); err != nil {
+			return err
+		}
+		token = s.issueToken(inv)
+		return s.repo.UpdateInvitationTokenTx(ctx, tx, inv.ID, inv.TokenHash, inv.ExpiresAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.send(ctx, o, inv, token); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (s *invitationService) ListInvitations(ctx context.Context, orgID string, q *pagination.Query) (*pagination.Page[*org.Invitation], error) {
+	items, err := s.repo.ListInvitationsByOrgID(ctx, orgID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := pagination.NewPage(q, items)
+	if q.Total {
+		total, err := s.repo.CountInvitationsByOrgID(ctx, orgID, q)
+		if err != nil {
+			return nil, err
+		}
+		page.Meta.Total = &total
+	}
+	return page, nil
+}
+
+func (s *invitationService) ResendInvitation(ctx context.Context, orgID, invitationID string) (*org.Invitation, error) {
+	inv, err := s.findOrgInvitation(ctx, orgID, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Status != org.InvitationPending {
+		return nil, errs.ErrInvitationNotPending
+	}
+
+	o, err := s.orgRepo.FindOrganizationByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	// New link, previous links stop working
+	inv.ExpiresAt = time.Now().Add(s.cfg.TTL)
+	token := s.issueToken(inv)
+	if err := s.repo.UpdateInvitationToken(ctx, inv.ID, inv.TokenHash, inv.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.send(ctx, o, inv, token); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (s *invitationService) RevokeInvitation(ctx context.Context, orgID, invitationID string) error {
+	inv, err := s.findOrgInvitation(ctx, orgID, invitationID)
+	if err != nil {
+		return err
+	}
+	return s.repo.RevokeInvitation(ctx, inv.ID)
+}
+
+func (s *invitationService) AcceptInvitation(ctx context.Context, token, password string) (*AcceptInvitationResponse, error) {
+	// Verify Signed Token
+	invitationID, err := signer.Verify([]byte(s.cfg.SecretKey), token)
+	if err != nil {
+		if errors.Is(err, signer.ErrExpired) {
+			return nil, errs.ErrInvitationExpired
+		}
+		return nil, errs.ErrInvalidInvitationToken
+	}
+
+	inv, err := s.repo.FindInvitationByID(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	// Only the latest link is valid
+	if subtle.ConstantTimeCompare([]byte(signer.Hash(token)), []byte(inv.TokenHash)) != 1 {
+		return nil, errs.ErrInvalidInvitationToken
+	}
+	if inv.Status != org.InvitationPending {
+		return nil, errs.ErrInvitationNotPending
+	}
+	if time.Now().After(inv.ExpiresAt) {
+		return nil, errs.ErrInvitationExpired
+	}
+
+	// Link Existing User or Register
+	var newUser *user.User
+	membership := &org.Membership{
+		OrgID: inv.OrgID,
+		Role:  inv.Role,
+	}
+	foundUser, err := s.userRepo.FindUserByEmail(ctx, inv.Email)
+	switch {
+	case err == nil:
+		membership.UserID = foundUser.ID
+
+		if _, err := s.orgRepo.FindMembership(ctx, inv.OrgID, foundUser.ID); err == nil {
+			return nil, errs.ErrAlreadyMember
+		} else if !errors.Is(err, errs.ErrNotOrganizationMember) {
+			return nil, err
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		if password == "" {
+			return nil, errs.ErrPasswordRequired
+		}
+		newUser = &user.User{Email: inv.Email, Password: password}
+	default:
+		return nil, err
+	}
+
+	resp := &AcceptInvitationResponse{Membership: membership}
+	// DB Transaction: the new account, the membership and the accepted invitation commit
+	// together, so a failure leaves the invitation pending and retryable
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if newUser != nil {
+			tokens, err := s.userSrv.RegisterTx(ctx, tx, newUser)
+			if err != nil {
+				return err
+			}
+			membership.UserID = newUser.ID
+			resp.Tokens = tokens
+		}
+
+		if err := s.repo.AcceptInvitationTx(ctx, tx, inv.ID); err != nil {
+			return err
+		}
+		return s.orgRepo.InsertMembershipTx(ctx, tx, membership)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ------------------ Private Method -------------------
+
+func (s *invitationService) findOrgInvitation(ctx context.Context, orgID, invitationID string) (*org.Invitation, error) {
+	inv, err := s.repo.FindInvitationByID(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	// Hide invitations of other tenants
+	if inv.OrgID != orgID {
+		return nil, errs.ErrInvitationNotFound
+	}
+	return inv, nil
+}
+
+// issueToken signs a new link for inv and sets its hash, the caller saves it
+func (s *invitationService) issueToken(inv *org.Invitation) string {
+	token := signer.Sign([]byte(s.cfg.SecretKey), inv.ID, inv.ExpiresAt)
+	inv.TokenHash = signer.Hash(token)
+	return token
+}
+
+func (s *invitationService) send(ctx context.Context, o *org.Organization, inv *org.Invitation, token string) error {
+	msg := &mailer.Message{
+		To:      []string{inv.Email},
+		Subject: fmt.Sprintf("You're invited to join %s", o.Name),
+		Body: fmt.Sprintf(
+			"You have been invited to join %s as %s.\n\nAccept the invitation: %s?token=%s\n\nThis link expires at %s.",
+			o.Name,
+			inv.Role,
+			s.cfg.AcceptURL,
+			url.QueryEscape(token),
+			inv.ExpiresAt.Format(time.RFC1123),
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send invitation failed: %w", err)
+	}
+	return nil
+}
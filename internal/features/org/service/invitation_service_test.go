@@ -0,0 +1,375 @@
+package orgservice_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	orgrepository "github.com/codepnw/go-starter-kit/internal/features/org/repository"
+	orgservice "github.com/codepnw/go-starter-kit/internal/features/org/service"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/mailer"
+	"github.com/codepnw/go-starter-kit/pkg/utils/signer"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var inviteCfg = &config.InviteConfig{
+	SecretKey: "mock-invite-secret",
+	TTL:       time.Hour,
+	AcceptURL: "http://localhost/invitations/accept",
+}
+
+type invitationMocks struct {
+	tx       *database.MockTxManager
+	repo     *orgrepository.MockInvitationRepository
+	orgRepo  *orgrepository.MockOrgRepository
+	userRepo *userrepository.MockUserRepository
+	userSrv  *userservice.MockUserService
+	mailer   *mailer.MockMailer
+}
+
+func TestCreateInvitation(t *testing.T) {
+	type testCase struct {
+		name        string
+		email       string
+		role        string
+		mockFn      func(m *invitationMocks)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:  "success",
+			email: "invitee@mail.com",
+			role:  org.RoleMember,
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().CheckPendingInvitationExists(gomock.Any(), "mock-org-1", "invitee@mail.com").Return(false, nil).Times(1)
+				m.orgRepo.EXPECT().FindOrganizationByID(gomock.Any(), "mock-org-1").Return(&org.Organization{ID: "mock-org-1", Name: "Acme"}, nil).Times(1)
+				m.tx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				m.repo.EXPECT().InsertInvitationTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, inv *org.Invitation) error {
+						inv.ID = "mock-invitation-1"
+						return nil
+					},
+				).Times(1)
+				m.repo.EXPECT().UpdateInvitationTokenTx(gomock.Any(), nil, "mock-invitation-1", gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				m.mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "fail owner role",
+			email:       "invitee@mail.com",
+			role:        org.RoleOwner,
+			mockFn:      func(m *invitationMocks) {},
+			expectedErr: errs.ErrInvalidInvitationRole,
+		},
+		{
+			name:  "fail pending invitation exists",
+			email: "invitee@mail.com",
+			role:  org.RoleAdmin,
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().CheckPendingInvitationExists(gomock.Any(), "mock-org-1", "invitee@mail.com").Return(true, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvitationAlreadyExists,
+		},
+		{
+			name:  "fail send mail",
+			email: "invitee@mail.com",
+			role:  org.RoleMember,
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().CheckPendingInvitationExists(gomock.Any(), "mock-org-1", "invitee@mail.com").Return(false, nil).Times(1)
+				m.orgRepo.EXPECT().FindOrganizationByID(gomock.Any(), "mock-org-1").Return(&org.Organization{ID: "mock-org-1", Name: "Acme"}, nil).Times(1)
+				m.tx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				m.repo.EXPECT().InsertInvitationTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+				m.repo.EXPECT().UpdateInvitationTokenTx(gomock.Any(), nil, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				m.mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name:  "fail save token",
+			email: "invitee@mail.com",
+			role:  org.RoleMember,
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().CheckPendingInvitationExists(gomock.Any(), "mock-org-1", "invitee@mail.com").Return(false, nil).Times(1)
+				m.orgRepo.EXPECT().FindOrganizationByID(gomock.Any(), "mock-org-1").Return(&org.Organization{ID: "mock-org-1", Name: "Acme"}, nil).Times(1)
+				m.tx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				m.repo.EXPECT().InsertInvitationTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+				m.repo.EXPECT().UpdateInvitationTokenTx(gomock.Any(), nil, gomock.Any(), gomock.Any(), gomock.Any()).Return(ErrDB).Times(1)
+				// No mail for an invitation that was rolled back
+				m.mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		m, service := setupInvitation(t)
+
+		tc.mockFn(m)
+
+		ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+
+		resp, err := service.CreateInvitation(ctx, "mock-org-1", tc.email, tc.role)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, org.InvitationPending, resp.Status)
+			assert.NotEmpty(t, resp.TokenHash)
+		}
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	validToken := signer.Sign([]byte(inviteCfg.SecretKey), "mock-invitation-1", expiresAt)
+	staleToken := signer.Sign([]byte(inviteCfg.SecretKey), "mock-invitation-1", expiresAt.Add(-time.Minute))
+
+	pendingInvitation := func() *org.Invitation {
+		return &org.Invitation{
+			ID:        "mock-invitation-1",
+			OrgID:     "mock-org-1",
+			Email:     "invitee@mail.com",
+			Role:      org.RoleMember,
+			TokenHash: signer.Hash(validToken),
+			Status:    org.InvitationPending,
+			ExpiresAt: expiresAt,
+		}
+	}
+
+	type testCase struct {
+		name         string
+		token        string
+		password     string
+		mockFn       func(m *invitationMocks)
+		expectTokens bool
+		expectedErr  error
+	}
+
+	testCases := []testCase{
+		{
+			name:  "success existing user",
+			token: validToken,
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(pendingInvitation(), nil).Times(1)
+				m.userRepo.EXPECT().FindUserByEmail(gomock.Any(), "invitee@mail.com").Return(&user.User{ID: "mock-uuid-2"}, nil).Times(1)
+				m.orgRepo.EXPECT().FindMembership(gomock.Any(), "mock-org-1", "mock-uuid-2").Return(nil, errs.ErrNotOrganizationMember).Times(1)
+
+				m.tx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				m.repo.EXPECT().AcceptInvitationTx(gomock.Any(), nil, "mock-invitation-1").Return(nil).Times(1)
+				m.orgRepo.EXPECT().InsertMembershipTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "success register new user",
+			token:    validToken,
+			password: "test_password",
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(pendingInvitation(), nil).Times(1)
+				m.userRepo.EXPECT().FindUserByEmail(gomock.Any(), "invitee@mail.com").Return(nil, sql.ErrNoRows).Times(1)
+				m.tx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				m.userSrv.EXPECT().RegisterTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, u *user.User) (*userservice.UserTokenResponse, error) {
+						u.ID = "mock-uuid-3"
+						return &userservice.UserTokenResponse{AccessToken: "mock-access-token", RefreshToken: "mock-refresh-token"}, nil
+					},
+				).Times(1)
+				m.repo.EXPECT().AcceptInvitationTx(gomock.Any(), nil, "mock-invitation-1").Return(nil).Times(1)
+				m.orgRepo.EXPECT().InsertMembershipTx(gomock.Any(), nil, &org.Membership{
+					OrgID:  "mock-org-1",
+					UserID: "mock-uuid-3",
+					Role:   org.RoleMember,
+				}).Return(nil).Times(1)
+			},
+			expectTokens: true,
+			expectedErr:  nil,
+		},
+		{
+			name:     "fail new user membership insert",
+			token:    validToken,
+			password: "test_password",
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(pendingInvitation(), nil).Times(1)
+				m.userRepo.EXPECT().FindUserByEmail(gomock.Any(), "invitee@mail.com").Return(nil, sql.ErrNoRows).Times(1)
+				// The account is created in the same transaction, so it is rolled back too
+				m.tx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				m.userSrv.EXPECT().RegisterTx(gomock.Any(), nil, gomock.Any()).Return(&userservice.UserTokenResponse{}, nil).Times(1)
+				m.repo.EXPECT().AcceptInvitationTx(gomock.Any(), nil, "mock-invitation-1").Return(nil).Times(1)
+				m.orgRepo.EXPECT().InsertMembershipTx(gomock.Any(), nil, gomock.Any()).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name:  "fail new user without password",
+			token: validToken,
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(pendingInvitation(), nil).Times(1)
+				m.userRepo.EXPECT().FindUserByEmail(gomock.Any(), "invitee@mail.com").Return(nil, sql.ErrNoRows).Times(1)
+			},
+			expectedErr: errs.ErrPasswordRequired,
+		},
+		{
+			name:  "fail already member",
+			token: validToken,
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(pendingInvitation(), nil).Times(1)
+				m.userRepo.EXPECT().FindUserByEmail(gomock.Any(), "invitee@mail.com").Return(&user.User{ID: "mock-uuid-2"}, nil).Times(1)
+				m.orgRepo.EXPECT().FindMembership(gomock.Any(), "mock-org-1", "mock-uuid-2").Return(&org.Membership{}, nil).Times(1)
+			},
+			expectedErr: errs.ErrAlreadyMember,
+		},
+		{
+			name:        "fail tampered token",
+			token:       validToken + "x",
+			mockFn:      func(m *invitationMocks) {},
+			expectedErr: errs.ErrInvalidInvitationToken,
+		},
+		{
+			name:  "fail token replaced by resend",
+			token: staleToken,
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(pendingInvitation(), nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidInvitationToken,
+		},
+		{
+			name:  "fail revoked",
+			token: validToken,
+			mockFn: func(m *invitationMocks) {
+				inv := pendingInvitation()
+				inv.Status = org.InvitationRevoked
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(inv, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvitationNotPending,
+		},
+		{
+			name:        "fail expired link",
+			token:       signer.Sign([]byte(inviteCfg.SecretKey), "mock-invitation-1", time.Now().Add(-time.Minute)),
+			mockFn:      func(m *invitationMocks) {},
+			expectedErr: errs.ErrInvitationExpired,
+		},
+	}
+
+	for _, tc := range testCases {
+		m, service := setupInvitation(t)
+
+		tc.mockFn(m)
+
+		resp, err := service.AcceptInvitation(context.Background(), tc.token, tc.password)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.NotNil(t, resp.Membership)
+			assert.Equal(t, tc.expectTokens, resp.Tokens != nil, tc.name)
+		}
+	}
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	type testCase struct {
+		name        string
+		orgID       string
+		mockFn      func(m *invitationMocks)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:  "success",
+			orgID: "mock-org-1",
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(&org.Invitation{ID: "mock-invitation-1", OrgID: "mock-org-1"}, nil).Times(1)
+				m.repo.EXPECT().RevokeInvitation(gomock.Any(), "mock-invitation-1").Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:  "fail other organization",
+			orgID: "mock-org-2",
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(&org.Invitation{ID: "mock-invitation-1", OrgID: "mock-org-1"}, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvitationNotFound,
+		},
+		{
+			name:  "fail not pending",
+			orgID: "mock-org-1",
+			mockFn: func(m *invitationMocks) {
+				m.repo.EXPECT().FindInvitationByID(gomock.Any(), "mock-invitation-1").Return(&org.Invitation{ID: "mock-invitation-1", OrgID: "mock-org-1"}, nil).Times(1)
+				m.repo.EXPECT().RevokeInvitation(gomock.Any(), "mock-invitation-1").Return(errs.ErrInvitationNotPending).Times(1)
+			},
+			expectedErr: errs.ErrInvitationNotPending,
+		},
+	}
+
+	for _, tc := range testCases {
+		m, service := setupInvitation(t)
+
+		tc.mockFn(m)
+
+		err := service.RevokeInvitation(context.Background(), tc.orgID, "mock-invitation-1")
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
+
+func setupInvitation(t *testing.T) (*invitationMocks, orgservice.InvitationService) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := &invitationMocks{
+		tx:       database.NewMockTxManager(ctrl),
+		repo:     orgrepository.NewMockInvitationRepository(ctrl),
+		orgRepo:  orgrepository.NewMockOrgRepository(ctrl),
+		userRepo: userrepository.NewMockUserRepository(ctrl),
+		userSrv:  userservice.NewMockUserService(ctrl),
+		mailer:   mailer.NewMockMailer(ctrl),
+	}
+
+	service := orgservice.NewInvitationService(inviteCfg, m.tx, m.repo, m.orgRepo, m.userRepo, m.userSrv, m.mailer)
+
+	return m, service
+}
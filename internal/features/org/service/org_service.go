@@ -0,0 +1,119 @@
+package orgservice
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	orgrepository "github.com/codepnw/go-starter-kit/internal/features/org/repository"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+)
+
+type OrgService interface {
+	CreateOrganization(ctx context.Context, name string) (*org.Organization, error)
+	ListMyOrganizations(ctx context.Context) ([]*org.Membership, error)
+	GetOrganization(ctx context.Context, orgID string) (*org.Organization, error)
+	ListMembers(ctx context.Context, orgID string, q *pagination.Query) (*pagination.Page[*org.Membership], error)
+	SwitchOrganization(ctx context.Context, orgID string) (*userservice.UserTokenResponse, error)
+}
+
+type orgService struct {
+	tx      database.TxManager
+	repo    orgrepository.OrgRepository
+	userSrv userservice.UserService
+}
+
+func NewOrgService(tx database.TxManager, repo orgrepository.OrgRepository, userSrv userservice.UserService) OrgService {
+	return &orgService{
+		tx:      tx,
+		repo:    repo,
+		userSrv: userSrv,
+	}
+}
+
+func (s *orgService) CreateOrganization(ctx context.Context, name string) (*org.Organization, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &org.Organization{
+		Name:      name,
+		CreatedBy: userID,
+	}
+	// DB Transaction
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		// Insert Organization
+		if err := s.repo.InsertOrganizationTx(ctx, tx, o); err != nil {
+			return err
+		}
+
+		// Creator Becomes Owner
+		owner := &org.Membership{
+			OrgID:  o.ID,
+			UserID: userID,
+			Role:   org.RoleOwner,
+		}
+		return s.repo.InsertMembershipTx(ctx, tx, owner)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func (s *orgService) ListMyOrganizations(ctx context.Context) ([]*org.Membership, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembershipsByUserID(ctx, userID)
+}
+
+func (s *orgService) GetOrganization(ctx context.Context, orgID string) (*org.Organization, error) {
+	return s.repo.FindOrganizationByID(ctx, orgID)
+}
+
+func (s *orgService) ListMembers(ctx context.Context, orgID string, q *pagination.Query) (*pagination.Page[*org.Membership], error) {
+	items, err := s.repo.ListMembersByOrgID(ctx, orgID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := pagination.NewPage(q, items)
+	if q.Total {
+		total, err := s.repo.CountMembersByOrgID(ctx, orgID, q)
+		if err != nil {
+			return nil, err
+		}
+		page.Meta.Total = &total
+	}
+	return page, nil
+}
+
+func (s *orgService) SwitchOrganization(ctx context.Context, orgID string) (*userservice.UserTokenResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check Membership
+	membership, err := s.repo.FindMembership(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the client of the current token
+	var clientID string
+	if claims, err := auth.GetUserFromContext(ctx); err == nil {
+		clientID = claims.ClientID
+	}
+
+	// New Tokens Scoped To Organization
+	return s.userSrv.IssueTokens(ctx, userID, clientID, jwttoken.WithOrganization(membership.OrgID, membership.Role))
+}
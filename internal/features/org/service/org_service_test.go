@@ -0,0 +1,179 @@
+package orgservice_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	orgrepository "github.com/codepnw/go-starter-kit/internal/features/org/repository"
+	orgservice "github.com/codepnw/go-starter-kit/internal/features/org/service"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var ErrDB = errors.New("DB Error")
+
+func TestCreateOrganization(t *testing.T) {
+	type testCase struct {
+		name        string
+		orgName     string
+		mockFn      func(mockTx *database.MockTxManager, mockRepo *orgrepository.MockOrgRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:    "success",
+			orgName: "Acme",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *orgrepository.MockOrgRepository) {
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockRepo.EXPECT().InsertOrganizationTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, o *org.Organization) error {
+						o.ID = "mock-org-1"
+						return nil
+					},
+				).Times(1)
+
+				mockRepo.EXPECT().InsertMembershipTx(gomock.Any(), nil, &org.Membership{
+					OrgID:  "mock-org-1",
+					UserID: "mock-uuid-1",
+					Role:   org.RoleOwner,
+				}).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:    "fail insert organization",
+			orgName: "Acme",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *orgrepository.MockOrgRepository) {
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockRepo.EXPECT().InsertOrganizationTx(gomock.Any(), nil, gomock.Any()).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name:    "fail insert membership",
+			orgName: "Acme",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *orgrepository.MockOrgRepository) {
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockRepo.EXPECT().InsertOrganizationTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+				mockRepo.EXPECT().InsertMembershipTx(gomock.Any(), nil, gomock.Any()).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockTx, mockRepo, _, service := setup(t)
+
+		tc.mockFn(mockTx, mockRepo)
+
+		ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+
+		resp, err := service.CreateOrganization(ctx, tc.orgName)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, "mock-org-1", resp.ID)
+			assert.Equal(t, "mock-uuid-1", resp.CreatedBy)
+		}
+	}
+}
+
+func TestSwitchOrganization(t *testing.T) {
+	type testCase struct {
+		name        string
+		orgID       string
+		mockFn      func(mockRepo *orgrepository.MockOrgRepository, mockUser *userservice.MockUserService, orgID string)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:  "success",
+			orgID: "mock-org-1",
+			mockFn: func(mockRepo *orgrepository.MockOrgRepository, mockUser *userservice.MockUserService, orgID string) {
+				membership := &org.Membership{OrgID: orgID, UserID: "mock-uuid-1", Role: org.RoleAdmin}
+				mockRepo.EXPECT().FindMembership(gomock.Any(), orgID, "mock-uuid-1").Return(membership, nil).Times(1)
+
+				mockUser.EXPECT().IssueTokens(gomock.Any(), "mock-uuid-1", "", gomock.Any()).Return(&userservice.UserTokenResponse{
+					AccessToken:  "mock-access-token",
+					RefreshToken: "mock-refresh-token",
+				}, nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:  "fail not a member",
+			orgID: "mock-org-2",
+			mockFn: func(mockRepo *orgrepository.MockOrgRepository, mockUser *userservice.MockUserService, orgID string) {
+				mockRepo.EXPECT().FindMembership(gomock.Any(), orgID, "mock-uuid-1").Return(nil, errs.ErrNotOrganizationMember).Times(1)
+			},
+			expectedErr: errs.ErrNotOrganizationMember,
+		},
+		{
+			name:  "fail issue tokens",
+			orgID: "mock-org-1",
+			mockFn: func(mockRepo *orgrepository.MockOrgRepository, mockUser *userservice.MockUserService, orgID string) {
+				membership := &org.Membership{OrgID: orgID, UserID: "mock-uuid-1", Role: org.RoleMember}
+				mockRepo.EXPECT().FindMembership(gomock.Any(), orgID, "mock-uuid-1").Return(membership, nil).Times(1)
+
+				mockUser.EXPECT().IssueTokens(gomock.Any(), "mock-uuid-1", "", gomock.Any()).Return(nil, ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, mockRepo, mockUser, service := setup(t)
+
+		tc.mockFn(mockRepo, mockUser, tc.orgID)
+
+		ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+
+		resp, err := service.SwitchOrganization(ctx, tc.orgID)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.NotEmpty(t, resp)
+		}
+	}
+}
+
+func setup(t *testing.T) (*database.MockTxManager, *orgrepository.MockOrgRepository, *userservice.MockUserService, orgservice.OrgService) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTx := database.NewMockTxManager(ctrl)
+	mockRepo := orgrepository.NewMockOrgRepository(ctrl)
+	mockUser := userservice.NewMockUserService(ctrl)
+
+	service := orgservice.NewOrgService(mockTx, mockRepo, mockUser)
+
+	return mockTx, mockRepo, mockUser, service
+}
@@ -1,15 +1,21 @@
 package userhandler
 
 type RegisterReq struct {
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,password"`
 }
 
 type LoginReq struct {
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required"`
+	ClientID   string `json:"client_id"`
+	RememberMe bool   `json:"remember_me"`
 }
 
 type RefreshTokenReq struct {
-	RefreshToken string `json:"token" binding:"required"`
+	RefreshToken string `json:"token" validate:"required"`
+}
+
+type UserURI struct {
+	UserID string `uri:"user_id" validate:"required,uuid"`
 }
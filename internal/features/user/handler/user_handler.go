@@ -22,7 +22,7 @@ func (h *userHandler) Register(c *gin.Context) {
 	req := new(RegisterReq)
 
 	if err := c.ShouldBindJSON(req); err != nil {
-		response.ResponseError(c, http.StatusBadRequest, err)
+		c.Error(errs.InvalidInput(err))
 		return
 	}
 
@@ -32,12 +32,7 @@ func (h *userHandler) Register(c *gin.Context) {
 	}
 	resp, err := h.service.Register(c.Request.Context(), input)
 	if err != nil {
-		switch err {
-		case errs.ErrEmailAlreadyExists:
-			response.ResponseError(c, http.StatusBadRequest, err)
-		default:
-			response.ResponseError(c, http.StatusInternalServerError, err)
-		}
+		c.Error(err)
 		return
 	}
 
@@ -48,18 +43,13 @@ func (h *userHandler) Login(c *gin.Context) {
 	req := new(LoginReq)
 
 	if err := c.ShouldBindJSON(req); err != nil {
-		response.ResponseError(c, http.StatusBadRequest, err)
+		c.Error(errs.InvalidInput(err))
 		return
 	}
 
-	resp, err := h.service.Login(c.Request.Context(), req.Email, req.Password)
+	resp, err := h.service.Login(c.Request.Context(), req.Email, req.Password, req.ClientID, req.RememberMe)
 	if err != nil {
-		switch err {
-		case errs.ErrInvalidEmailOrPassword:
-			response.ResponseError(c, http.StatusBadRequest, err)
-		default:
-			response.ResponseError(c, http.StatusInternalServerError, err)
-		}
+		c.Error(err)
 		return
 	}
 
@@ -70,22 +60,13 @@ func (h *userHandler) RefreshToken(c *gin.Context) {
 	req := new(RefreshTokenReq)
 
 	if err := c.ShouldBindJSON(req); err != nil {
-		response.ResponseError(c, http.StatusBadRequest, err)
+		c.Error(errs.InvalidInput(err))
 		return
 	}
 
 	resp, err := h.service.RefreshToken(c.Request.Context(), req.RefreshToken)
 	if err != nil {
-		switch err {
-		case errs.ErrTokenNotFound:
-			response.ResponseError(c, http.StatusNotFound, err)
-		case errs.ErrTokenRevoked:
-			response.ResponseError(c, http.StatusBadRequest, err)
-		case errs.ErrTokenExpires:
-			response.ResponseError(c, http.StatusBadRequest, err)
-		default:
-			response.ResponseError(c, http.StatusInternalServerError, err)
-		}
+		c.Error(err)
 		return
 	}
 
@@ -96,17 +77,12 @@ func (h *userHandler) Logout(c *gin.Context) {
 	req := new(RefreshTokenReq)
 
 	if err := c.ShouldBindJSON(req); err != nil {
-		response.ResponseError(c, http.StatusBadRequest, err)
+		c.Error(errs.InvalidInput(err))
 		return
 	}
 
 	if err := h.service.Logout(c.Request.Context(), req.RefreshToken); err != nil {
-		switch err {
-		case errs.ErrTokenNotFound:
-			response.ResponseError(c, http.StatusNotFound, err)
-		default:
-			response.ResponseError(c, http.StatusInternalServerError, err)
-		}
+		c.Error(err)
 		return
 	}
 
@@ -116,12 +92,22 @@ func (h *userHandler) Logout(c *gin.Context) {
 func (h *userHandler) GetProfile(c *gin.Context) {
 	resp, err := h.service.GetProfile(c.Request.Context())
 	if err != nil {
-		switch err {
-		case errs.ErrUserNotFound:
-			response.ResponseError(c, http.StatusNotFound, err)
-		default:
-			response.ResponseError(c, http.StatusInternalServerError, err)
-		}
+		c.Error(err)
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *userHandler) GetUserByID(c *gin.Context) {
+	uri := new(UserURI)
+	if err := c.ShouldBindUri(uri); err != nil {
+		c.Error(errs.InvalidInput(err))
+		return
+	}
+
+	resp, err := h.service.GetUserByID(c.Request.Context(), uri.UserID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 	response.ResponseSuccess(c, http.StatusOK, resp)
@@ -15,12 +15,15 @@ type UserRepository interface {
 	CheckEmailExists(ctx context.Context, email string) (bool, error)
 	FindUserByEmail(ctx context.Context, email string) (*user.User, error)
 	FindUserByID(ctx context.Context, userID string) (*user.User, error)
-	ValidateRefreshToken(ctx context.Context, token string) error
+	ValidateRefreshToken(ctx context.Context, token string) (*user.RefreshToken, error)
 
 	// Transaction
 	InsertUserTx(ctx context.Context, tx *sql.Tx, u *user.User) error
 	InsertRefreshTokenTx(ctx context.Context, tx *sql.Tx, token *user.RefreshToken) error
 	RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, token string) error
+	LockUserTx(ctx context.Context, tx *sql.Tx, userID string) error
+	ListActiveSessionIDsTx(ctx context.Context, tx *sql.Tx, userID string) ([]string, error)
+	RevokeSessionTx(ctx context.Context, tx *sql.Tx, sessionID string) error
 }
 
 type userRepository struct {
@@ -34,10 +37,11 @@ func NewUserRepository(db *sql.DB) UserRepository {
 func (r *userRepository) InsertUserTx(ctx context.Context, tx *sql.Tx, u *user.User) error {
 	query := `
 		INSERT INTO users (email, password)
-		VALUES ($1, $2) RETURNING id, created_at, updated_at
+		VALUES ($1, $2) RETURNING id, role, created_at, updated_at
 	`
 	if err := tx.QueryRowContext(ctx, query, u.Email, u.Password).Scan(
 		&u.ID,
+		&u.Role,
 		&u.CreatedAt,
 		&u.UpdatedAt,
 	); err != nil {
@@ -62,13 +66,14 @@ func (r *userRepository) CheckEmailExists(ctx context.Context, email string) (bo
 func (r *userRepository) FindUserByEmail(ctx context.Context, email string) (*user.User, error) {
 	var u user.User
 	query := `
-		SELECT id, email, password
+		SELECT id, email, password, role
 		FROM users WHERE email = $1 LIMIT 1
 	`
 	if err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&u.ID,
 		&u.Email,
 		&u.Password,
+		&u.Role,
 	); err != nil {
 		return nil, err
 	}
@@ -78,12 +83,13 @@ func (r *userRepository) FindUserByEmail(ctx context.Context, email string) (*us
 func (r *userRepository) FindUserByID(ctx context.Context, userID string) (*user.User, error) {
 	var u user.User
 	query := `
-		SELECT id, email, created_at, updated_at
+		SELECT id, email, role, created_at, updated_at
 		FROM users WHERE id = $1 LIMIT 1
 	`
 	if err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&u.ID,
 		&u.Email,
+		&u.Role,
 		&u.CreatedAt,
 		&u.UpdatedAt,
 	); err != nil {
@@ -96,54 +102,78 @@ func (r *userRepository) FindUserByID(ctx context.Context, userID string) (*user
 }
 
 func (r *userRepository) InsertRefreshTokenTx(ctx context.Context, tx *sql.Tx, token *user.RefreshToken) error {
+	// Empty SessionID starts a new session
 	query := `
-		INSERT INTO refresh_tokens (user_id, token, expires_at, revoked)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_tokens (user_id, token, expires_at, revoked, session_id, session_expires_at, remember_me, client_id)
+		VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, '')::uuid, gen_random_uuid()), $6, $7, $8)
+		RETURNING id, session_id, created_at
 	`
-	_, err := tx.ExecContext(
+	if err := tx.QueryRowContext(
 		ctx,
 		query,
 		token.UserID,
 		token.Token,
 		token.ExpiresAt,
 		token.Revoked,
-	)
-	if err != nil {
+		token.SessionID,
+		token.SessionExpiresAt,
+		token.RememberMe,
+		token.ClientID,
+	).Scan(
+		&token.ID,
+		&token.SessionID,
+		&token.CreatedAt,
+	); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *userRepository) ValidateRefreshToken(ctx context.Context, token string) error {
-	var revoked bool
-	var expiresAt time.Time
+// ValidateRefreshToken returns the token's session, rejecting revoked tokens,
+// sessions past their absolute expiry and sessions idle past the token's deadline.
+func (r *userRepository) ValidateRefreshToken(ctx context.Context, token string) (*user.RefreshToken, error) {
+	var rt user.RefreshToken
 
 	query := `
-		SELECT revoked, expires_at
+		SELECT id, user_id, token, expires_at, revoked, session_id, session_expires_at, remember_me, client_id, created_at
 		FROM refresh_tokens WHERE token = $1 LIMIT 1
 	`
 	if err := r.db.QueryRowContext(ctx, query, token).Scan(
-		&revoked,
-		&expiresAt,
+		&rt.ID,
+		&rt.UserID,
+		&rt.Token,
+		&rt.ExpiresAt,
+		&rt.Revoked,
+		&rt.SessionID,
+		&rt.SessionExpiresAt,
+		&rt.RememberMe,
+		&rt.ClientID,
+		&rt.CreatedAt,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return errs.ErrTokenNotFound
+			return nil, errs.ErrTokenNotFound
 		}
-		return err
+		return nil, err
 	}
 
-	if revoked {
-		return errs.ErrTokenRevoked
+	if rt.Revoked {
+		return nil, errs.ErrTokenRevoked
 	}
 
-	if time.Now().After(expiresAt) {
-		return errs.ErrTokenExpires
+	now := time.Now()
+	if now.After(rt.SessionExpiresAt) {
+		return nil, errs.ErrSessionExpired
 	}
-	return nil
+	if now.After(rt.ExpiresAt) {
+		return nil, errs.ErrSessionIdle
+	}
+	return &rt, nil
 }
 
+// RevokedRefreshTokenTx revokes a live token. Only one of two concurrent calls for the
+// same token wins, the other gets ErrTokenRevoked, so a rotation never forks a session.
 func (r *userRepository) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, token string) error {
-	query := `UPDATE refresh_tokens SET revoked = TRUE WHERE token = $1`
+	query := `UPDATE refresh_tokens SET revoked = TRUE WHERE token = $1 AND revoked = FALSE`
 	res, err := tx.ExecContext(ctx, query, token)
 	if err != nil {
 		return err
@@ -155,7 +185,61 @@ func (r *userRepository) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx,
 	}
 
 	if rows == 0 {
-		return errs.ErrTokenNotFound
+		var exists bool
+		query := `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE token = $1)`
+		if err := tx.QueryRowContext(ctx, query, token).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return errs.ErrTokenNotFound
+		}
+		return errs.ErrTokenRevoked
 	}
 	return nil
 }
+
+// LockUserTx takes a row lock on the user until the transaction ends, serializing
+// session changes for that user across instances.
+func (r *userRepository) LockUserTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	var id string
+	query := `SELECT id FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListActiveSessionIDsTx returns the user's live sessions, oldest login first.
+func (r *userRepository) ListActiveSessionIDsTx(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	query := `
+		SELECT session_id FROM refresh_tokens
+		WHERE user_id = $1
+		GROUP BY session_id
+		HAVING bool_or(NOT revoked AND expires_at > NOW() AND session_expires_at > NOW())
+		ORDER BY MIN(created_at) ASC
+	`
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessionIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	return sessionIDs, rows.Err()
+}
+
+func (r *userRepository) RevokeSessionTx(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	query := `UPDATE refresh_tokens SET revoked = TRUE WHERE session_id = $1 AND revoked = FALSE`
+	_, err := tx.ExecContext(ctx, query, sessionID)
+	return err
+}
@@ -109,6 +109,49 @@ func (mr *MockUserRepositoryMockRecorder) InsertUserTx(ctx, tx, u interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUserTx", reflect.TypeOf((*MockUserRepository)(nil).InsertUserTx), ctx, tx, u)
 }
 
+// ListActiveSessionIDsTx mocks base method.
+func (m *MockUserRepository) ListActiveSessionIDsTx(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveSessionIDsTx", ctx, tx, userID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveSessionIDsTx indicates an expected call of ListActiveSessionIDsTx.
+func (mr *MockUserRepositoryMockRecorder) ListActiveSessionIDsTx(ctx, tx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveSessionIDsTx", reflect.TypeOf((*MockUserRepository)(nil).ListActiveSessionIDsTx), ctx, tx, userID)
+}
+
+// LockUserTx mocks base method.
+func (m *MockUserRepository) LockUserTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUserTx", ctx, tx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUserTx indicates an expected call of LockUserTx.
+func (mr *MockUserRepositoryMockRecorder) LockUserTx(ctx, tx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUserTx", reflect.TypeOf((*MockUserRepository)(nil).LockUserTx), ctx, tx, userID)
+}
+
+// RevokeSessionTx mocks base method.
+func (m *MockUserRepository) RevokeSessionTx(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessionTx", ctx, tx, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSessionTx indicates an expected call of RevokeSessionTx.
+func (mr *MockUserRepositoryMockRecorder) RevokeSessionTx(ctx, tx, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessionTx", reflect.TypeOf((*MockUserRepository)(nil).RevokeSessionTx), ctx, tx, sessionID)
+}
+
 // RevokedRefreshTokenTx mocks base method.
 func (m *MockUserRepository) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, token string) error {
 	m.ctrl.T.Helper()
@@ -124,11 +167,12 @@ func (mr *MockUserRepositoryMockRecorder) RevokedRefreshTokenTx(ctx, tx, token i
 }
 
 // ValidateRefreshToken mocks base method.
-func (m *MockUserRepository) ValidateRefreshToken(ctx context.Context, token string) error {
+func (m *MockUserRepository) ValidateRefreshToken(ctx context.Context, token string) (*user.RefreshToken, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ValidateRefreshToken", ctx, token)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*user.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ValidateRefreshToken indicates an expected call of ValidateRefreshToken.
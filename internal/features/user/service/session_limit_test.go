@@ -0,0 +1,389 @@
+package userservice_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// lockStore stands in for Postgres: row locks taken through LockUserTx are held until
+// the fake transaction ends, session reads inside a transaction are not atomic and
+// inserted tokens are seen by others only once their transaction commits.
+type lockStore struct {
+	mu       sync.Mutex
+	rowLocks map[string]*sync.Mutex
+	held     map[*sql.Tx][]*sync.Mutex
+	pending  map[*sql.Tx][]*user.RefreshToken
+	sessions []*user.RefreshToken
+	nextID   int
+}
+
+func newLockStore() *lockStore {
+	return &lockStore{
+		rowLocks: make(map[string]*sync.Mutex),
+		held:     make(map[*sql.Tx][]*sync.Mutex),
+		pending:  make(map[*sql.Tx][]*user.RefreshToken),
+	}
+}
+
+func (s *lockStore) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx := new(sql.Tx)
+	err := fn(tx)
+
+	// Commit, then release the row locks
+	s.mu.Lock()
+	if err == nil {
+		s.sessions = append(s.sessions, s.pending[tx]...)
+	}
+	delete(s.pending, tx)
+	locks := s.held[tx]
+	delete(s.held, tx)
+	s.mu.Unlock()
+	for _, l := range locks {
+		l.Unlock()
+	}
+	return err
+}
+
+// activeSessions counts sessions that still have a live token.
+func (s *lockStore) activeSessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, rt := range s.sessions {
+		if !rt.Revoked {
+			count++
+		}
+	}
+	return count
+}
+
+type lockRepo struct {
+	userrepository.UserRepository
+	store *lockStore
+	user  *user.User
+}
+
+func (r *lockRepo) FindUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	return r.user, nil
+}
+
+func (r *lockRepo) LockUserTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	r.store.mu.Lock()
+	l, ok := r.store.rowLocks[userID]
+	if !ok {
+		l = new(sync.Mutex)
+		r.store.rowLocks[userID] = l
+	}
+	r.store.mu.Unlock()
+
+	l.Lock()
+
+	r.store.mu.Lock()
+	r.store.held[tx] = append(r.store.held[tx], l)
+	r.store.mu.Unlock()
+	return nil
+}
+
+func (r *lockRepo) ListActiveSessionIDsTx(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	r.store.mu.Lock()
+	var ids []string
+	for _, rt := range r.store.sessions {
+		if rt.UserID == userID && !rt.Revoked {
+			ids = append(ids, rt.SessionID)
+		}
+	}
+	r.store.mu.Unlock()
+
+	// Widen the read-then-write window so a missing lock shows up as extra sessions
+	time.Sleep(time.Millisecond)
+	return ids, nil
+}
+
+func (r *lockRepo) RevokeSessionTx(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, rt := range r.store.sessions {
+		if rt.SessionID == sessionID {
+			rt.Revoked = true
+		}
+	}
+	return nil
+}
+
+func (r *lockRepo) InsertRefreshTokenTx(ctx context.Context, tx *sql.Tx, token *user.RefreshToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// A refresh keeps its session, a login starts one
+	if token.SessionID == "" {
+		r.store.nextID++
+		token.SessionID = fmt.Sprintf("session-%d", r.store.nextID)
+	}
+	r.store.pending[tx] = append(r.store.pending[tx], token)
+	r.store.mu.Unlock()
+
+	// Widen the insert-to-commit window so an eviction without the lock misses the token
+	time.Sleep(time.Millisecond)
+	r.store.mu.Lock()
+	return nil
+}
+
+func (r *lockRepo) FindUserByID(ctx context.Context, id string) (*user.User, error) {
+	return r.user, nil
+}
+
+// ValidateRefreshToken reads outside any transaction, like the real check
+func (r *lockRepo) ValidateRefreshToken(ctx context.Context, token string) (*user.RefreshToken, error) {
+	r.store.mu.Lock()
+	var found *user.RefreshToken
+	for _, rt := range r.store.sessions {
+		if rt.Token == token {
+			current := *rt
+			found = &current
+		}
+	}
+	r.store.mu.Unlock()
+
+	if found == nil {
+		return nil, errs.ErrTokenNotFound
+	}
+	if found.Revoked {
+		return nil, errs.ErrTokenRevoked
+	}
+	// Widen the window between the check and the rotation
+	time.Sleep(time.Millisecond)
+	return found, nil
+}
+
+// RevokedRefreshTokenTx : the guarded UPDATE, only a live token is revoked
+func (r *lockRepo) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, token string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, rt := range r.store.sessions {
+		if rt.Token == token {
+			if rt.Revoked {
+				return errs.ErrTokenRevoked
+			}
+			rt.Revoked = true
+			return nil
+		}
+	}
+	return errs.ErrTokenNotFound
+}
+
+// seed adds a committed session with a live refresh token.
+func (s *lockStore) seed(userID, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	s.sessions = append(s.sessions, &user.RefreshToken{
+		UserID:           userID,
+		Token:            token,
+		SessionID:        fmt.Sprintf("session-%d", s.nextID),
+		ExpiresAt:        time.Now().Add(time.Hour),
+		SessionExpiresAt: time.Now().Add(time.Hour),
+	})
+}
+
+func TestLoginSessionLimitConcurrent(t *testing.T) {
+	type testCase struct {
+		name             string
+		policy           string
+		expectedSuccess  int
+		expectedRejected int
+	}
+
+	const (
+		maxSessions = 2
+		logins      = 20
+	)
+
+	testCases := []testCase{
+		{
+			name:             "evict oldest keeps limit",
+			policy:           user.SessionLimitEvictOldest,
+			expectedSuccess:  logins,
+			expectedRejected: 0,
+		},
+		{
+			name:             "reject admits only limit",
+			policy:           user.SessionLimitReject,
+			expectedSuccess:  maxSessions,
+			expectedRejected: logins - maxSessions,
+		},
+	}
+
+	// Cheap hash: the test is about locking, not bcrypt
+	hashed, err := bcrypt.GenerateFromPassword([]byte("test_password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		ctrl := gomock.NewController(t)
+		mockToken := jwttoken.NewMockJWTToken(ctrl)
+		mockToken.EXPECT().GenerateAccessToken(gomock.Any(), gomock.Any()).Return("mock-access-token", nil).AnyTimes()
+		mockToken.EXPECT().GenerateRefreshToken(gomock.Any(), gomock.Any()).Return("mock-refresh-token", nil).AnyTimes()
+
+		store := newLockStore()
+		repo := &lockRepo{
+			store: store,
+			user:  &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: string(hashed)},
+		}
+
+		cfg := *testJWTConfig
+		cfg.Session.MaxSessions = maxSessions
+		cfg.Session.LimitPolicy = tc.policy
+		service := userservice.NewUserService(&cfg, store, mockToken, repo)
+
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			success  int
+			rejected int
+		)
+		for range logins {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.Login(context.Background(), "test1@mail.com", "test_password", "", false)
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch err {
+				case nil:
+					success++
+				case errs.ErrSessionLimitReached:
+					rejected++
+				default:
+					t.Errorf("%s: unexpected error: %v", tc.name, err)
+				}
+			}()
+		}
+		wg.Wait()
+		ctrl.Finish()
+
+		assert.Equal(t, tc.expectedSuccess, success, tc.name)
+		assert.Equal(t, tc.expectedRejected, rejected, tc.name)
+		assert.Equal(t, maxSessions, store.activeSessions(), tc.name)
+	}
+}
+
+// newTokenMock issues a distinct refresh token on every call.
+func newTokenMock(ctrl *gomock.Controller, userID string) *jwttoken.MockJWTToken {
+	var issued atomic.Int64
+	mockToken := jwttoken.NewMockJWTToken(ctrl)
+	mockToken.EXPECT().VerifyRefreshToken(gomock.Any()).Return(&jwttoken.UserClaims{UserID: userID}, nil).AnyTimes()
+	mockToken.EXPECT().GenerateAccessToken(gomock.Any(), gomock.Any()).Return("mock-access-token", nil).AnyTimes()
+	mockToken.EXPECT().GenerateRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(*user.User, ...jwttoken.TokenOption) (string, error) {
+			return fmt.Sprintf("refresh-%d", issued.Add(1)), nil
+		},
+	).AnyTimes()
+	return mockToken
+}
+
+func TestRefreshTokenConcurrent(t *testing.T) {
+	const refreshes = 10
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := newLockStore()
+	store.seed("mock-uuid-1", "refresh-0")
+	repo := &lockRepo{store: store, user: &user.User{ID: "mock-uuid-1"}}
+	service := userservice.NewUserService(testJWTConfig, store, newTokenMock(ctrl, "mock-uuid-1"), repo)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		success  int
+		rejected int
+	)
+	for range refreshes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.RefreshToken(context.Background(), "refresh-0")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				success++
+			case errs.ErrTokenRevoked:
+				rejected++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// One rotation wins, the session does not fork into several token chains
+	assert.Equal(t, 1, success)
+	assert.Equal(t, refreshes-1, rejected)
+	assert.Equal(t, 1, store.activeSessions())
+}
+
+func TestRefreshTokenRacesEviction(t *testing.T) {
+	// Cheap hash: the test is about locking, not bcrypt
+	hashed, err := bcrypt.GenerateFromPassword([]byte("test_password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	cfg := *testJWTConfig
+	cfg.Session.MaxSessions = 1
+	cfg.Session.LimitPolicy = user.SessionLimitEvictOldest
+
+	for i := range 20 {
+		ctrl := gomock.NewController(t)
+
+		store := newLockStore()
+		store.seed("mock-uuid-1", "refresh-0")
+		repo := &lockRepo{
+			store: store,
+			user:  &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: string(hashed)},
+		}
+		service := userservice.NewUserService(&cfg, store, newTokenMock(ctrl, "mock-uuid-1"), repo)
+
+		// The login evicts the session being refreshed
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := service.RefreshToken(context.Background(), "refresh-0")
+			if err != nil && err != errs.ErrTokenRevoked {
+				t.Errorf("run %d: refresh: %v", i, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := service.Login(context.Background(), "test1@mail.com", "test_password", "", false); err != nil {
+				t.Errorf("run %d: login: %v", i, err)
+			}
+		}()
+		wg.Wait()
+		ctrl.Finish()
+
+		// Either order leaves the login's session only: a rotation never revives an
+		// evicted session
+		assert.Equal(t, 1, store.activeSessions(), "run %d", i)
+	}
+}
@@ -3,6 +3,7 @@ package userservice
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -13,25 +14,34 @@ import (
 	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
 	"github.com/codepnw/go-starter-kit/pkg/database"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/metrics"
 	"github.com/codepnw/go-starter-kit/pkg/utils/password"
 )
 
+//go:generate mockgen -source=user_service.go -destination=user_service_mock.go -package=userservice
 type UserService interface {
 	Register(ctx context.Context, u *user.User) (*UserTokenResponse, error)
-	Login(ctx context.Context, email, password string) (*UserTokenResponse, error)
+	RegisterTx(ctx context.Context, tx *sql.Tx, u *user.User) (*UserTokenResponse, error)
+	Login(ctx context.Context, email, password, clientID string, rememberMe bool) (*UserTokenResponse, error)
+	Authenticate(ctx context.Context, email, password string) (*user.User, error)
 	RefreshToken(ctx context.Context, token string) (*UserTokenResponse, error)
 	Logout(ctx context.Context, token string) error
 	GetProfile(ctx context.Context) (*user.User, error)
+	GetUserByID(ctx context.Context, userID string) (*user.User, error)
+	IssueTokens(ctx context.Context, userID, clientID string, opts ...jwttoken.TokenOption) (*UserTokenResponse, error)
+	IssueTokensTx(ctx context.Context, tx *sql.Tx, userID, clientID string, opts ...jwttoken.TokenOption) (*UserTokenResponse, error)
 }
 
 type userService struct {
+	cfg   *config.JWTConfig
 	tx    database.TxManager
 	token jwttoken.JWTToken
 	repo  userrepository.UserRepository
 }
 
-func NewUserService(tx database.TxManager, token jwttoken.JWTToken, repo userrepository.UserRepository) UserService {
+func NewUserService(cfg *config.JWTConfig, tx database.TxManager, token jwttoken.JWTToken, repo userrepository.UserRepository) UserService {
 	return &userService{
+		cfg:   cfg,
 		tx:    tx,
 		token: token,
 		repo:  repo,
@@ -41,12 +51,11 @@ func NewUserService(tx database.TxManager, token jwttoken.JWTToken, repo userrep
 type UserTokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
 }
 
 func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenResponse, error) {
-	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
-	defer cancel()
-
 	// Check Email Exists
 	exists, err := s.repo.CheckEmailExists(ctx, u.Email)
 	if err != nil {
@@ -56,6 +65,23 @@ func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenRes
 		return nil, errs.ErrEmailAlreadyExists
 	}
 
+	var response *UserTokenResponse
+	// DB Transaction
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		resp, err := s.RegisterTx(ctx, tx, u)
+		response = resp
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RegisterTx creates the user and its first session in tx, for callers whose own writes
+// must commit with the new account (accepting an invitation).
+func (s *userService) RegisterTx(ctx context.Context, tx *sql.Tx, u *user.User) (*UserTokenResponse, error) {
 	// Hash Password
 	hashedPassword, err := password.GenerateHashPassword(u.Password)
 	if err != nil {
@@ -63,23 +89,59 @@ func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenRes
 	}
 	u.Password = hashedPassword
 
+	// Insert User
+	if err := s.repo.InsertUserTx(ctx, tx, u); err != nil {
+		return nil, err
+	}
+
+	// Session Limit
+	if err := s.enforceSessionLimit(ctx, tx, u.ID); err != nil {
+		return nil, err
+	}
+
+	// Generate Token
+	session := s.newSession(u.ID, "", false)
+	resp, err := s.generateToken(u, session, tokenOptions(ctx, "")...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save Refresh Token
+	if err := s.repo.InsertRefreshTokenTx(ctx, tx, session); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *userService) Login(ctx context.Context, email, pwd, clientID string, rememberMe bool) (*UserTokenResponse, error) {
+	// Clients that must use DPoP are known by client_id, so a login has to name its client
+	// or a DPoP client could get unbound tokens by leaving it out
+	if clientID == "" && len(s.cfg.DPoPRequiredClients) > 0 {
+		return nil, errs.ErrClientIDRequired
+	}
+
+	foundUser, err := s.Authenticate(ctx, email, pwd)
+	if err != nil {
+		return nil, err
+	}
+
 	var response *UserTokenResponse
 	// DB Transaction
 	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
-		// Insert User
-		if err := s.repo.InsertUserTx(ctx, tx, u); err != nil {
+		// Session Limit
+		if err := s.enforceSessionLimit(ctx, tx, foundUser.ID); err != nil {
 			return err
 		}
 
 		// Generate Token
-		resp, err := s.generateToken(u)
+		session := s.newSession(foundUser.ID, clientID, rememberMe)
+		resp, err := s.generateToken(foundUser, session, tokenOptions(ctx, clientID)...)
 		if err != nil {
 			return err
 		}
 
 		// Save Refresh Token
-		insertTokenInput := s.insertRefreshTokenInput(u.ID, resp.RefreshToken)
-		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
+		if err := s.repo.InsertRefreshTokenTx(ctx, tx, session); err != nil {
 			return err
 		}
 
@@ -87,68 +149,63 @@ func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenRes
 		return nil
 	})
 	if err != nil {
+		if err == errs.ErrSessionLimitReached {
+			authEvent("login", metrics.AuthSessionLimit)
+		}
 		return nil, err
 	}
 
+	authEvent("login", metrics.AuthSuccess)
 	return response, nil
 }
 
-func (s *userService) Login(ctx context.Context, email string, pwd string) (*UserTokenResponse, error) {
-	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
-	defer cancel()
-
+// Authenticate checks the user's credentials without starting a session, for callers
+// that issue their own single-purpose credential (the device verification page).
+func (s *userService) Authenticate(ctx context.Context, email, pwd string) (*user.User, error) {
 	// Find User Email
 	foundUser, err := s.repo.FindUserByEmail(ctx, email)
 	if err != nil {
+		authEvent("login", metrics.AuthFailure)
 		return nil, errs.ErrInvalidEmailOrPassword
 	}
 
 	// Verify Password
 	if ok := password.CompareHashedPassword(foundUser.Password, pwd); !ok {
+		authEvent("login", metrics.AuthFailure)
 		return nil, errs.ErrInvalidEmailOrPassword
 	}
-
-	var response *UserTokenResponse
-	// DB Transaction
-	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
-		// Generate Token
-		resp, err := s.generateToken(foundUser)
-		if err != nil {
-			return err
-		}
-
-		// Save Refresh Token
-		insertTokenInput := s.insertRefreshTokenInput(foundUser.ID, resp.RefreshToken)
-		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
-			return err
-		}
-
-		response = resp
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return response, nil
+	return foundUser, nil
 }
 
 func (s *userService) RefreshToken(ctx context.Context, token string) (*UserTokenResponse, error) {
-	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
-	defer cancel()
+	claims, err := s.token.VerifyRefreshToken(token)
+	if err != nil {
+		authEvent("refresh", metrics.AuthFailure)
+		return nil, errs.ErrInvalidToken
+	}
 
-	// Validate Token
-	if err := s.repo.ValidateRefreshToken(ctx, token); err != nil {
-		return nil, err
+	// DPoP-bound refresh token: the request must be signed by the same key
+	if claims.Cnf != nil {
+		jkt, ok := auth.GetDPoPKeyFromContext(ctx)
+		if !ok || jkt != claims.Cnf.JKT {
+			authEvent("refresh", metrics.AuthFailure)
+			return nil, errs.ErrInvalidToken
+		}
 	}
 
-	// Get UserID From Context
-	userID, err := auth.GetUserIDFromContext(ctx)
+	// Validate Token & Session
+	current, err := s.repo.ValidateRefreshToken(ctx, token)
 	if err != nil {
+		switch err {
+		case errs.ErrTokenRevoked:
+			authEvent("refresh", metrics.AuthReuse)
+		case errs.ErrTokenNotFound, errs.ErrSessionExpired, errs.ErrSessionIdle:
+			authEvent("refresh", metrics.AuthFailure)
+		}
 		return nil, err
 	}
 
-	userData, err := s.repo.FindUserByID(ctx, userID)
+	userData, err := s.repo.FindUserByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -156,20 +213,29 @@ func (s *userService) RefreshToken(ctx context.Context, token string) (*UserToke
 	var response *UserTokenResponse
 	// DB Transaction
 	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
-		// Revoked Old Token
+		// Serialized with logins evicting this user's sessions (enforceSessionLimit)
+		if err := s.repo.LockUserTx(ctx, tx, claims.UserID); err != nil {
+			return err
+		}
+
+		// Revoked Old Token: fails if a concurrent refresh or an eviction got there first
 		if err := s.repo.RevokedRefreshTokenTx(ctx, tx, token); err != nil {
+			if err == errs.ErrTokenRevoked {
+				authEvent("refresh", metrics.AuthReuse)
+			}
 			return err
 		}
 
-		// Generate New Token
-		resp, err := s.generateToken(userData)
+		// Generate New Token, sliding the session
+		session := s.extendSession(current)
+		opts := append(tokenOptions(ctx, current.ClientID), sessionOptions(claims)...)
+		resp, err := s.generateToken(userData, session, opts...)
 		if err != nil {
 			return err
 		}
 
 		// Save New Token
-		insertTokenInput := s.insertRefreshTokenInput(userData.ID, resp.RefreshToken)
-		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
+		if err := s.repo.InsertRefreshTokenTx(ctx, tx, session); err != nil {
 			return err
 		}
 
@@ -180,13 +246,11 @@ func (s *userService) RefreshToken(ctx context.Context, token string) (*UserToke
 		return nil, err
 	}
 
+	authEvent("refresh", metrics.AuthSuccess)
 	return response, nil
 }
 
 func (s *userService) Logout(ctx context.Context, token string) error {
-	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
-	defer cancel()
-
 	err := s.tx.WithTx(ctx, func(tx *sql.Tx) error {
 		if err := s.repo.RevokedRefreshTokenTx(ctx, tx, token); err != nil {
 			return err
@@ -201,9 +265,6 @@ func (s *userService) Logout(ctx context.Context, token string) error {
 }
 
 func (s *userService) GetProfile(ctx context.Context) (*user.User, error) {
-	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
-	defer cancel()
-
 	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -216,31 +277,187 @@ func (s *userService) GetProfile(ctx context.Context) (*user.User, error) {
 	return userData, nil
 }
 
+func (s *userService) GetUserByID(ctx context.Context, userID string) (*user.User, error) {
+	return s.repo.FindUserByID(ctx, userID)
+}
+
+// IssueTokens creates a new token pair for an existing user, e.g. after switching organization.
+func (s *userService) IssueTokens(ctx context.Context, userID, clientID string, opts ...jwttoken.TokenOption) (*UserTokenResponse, error) {
+	var response *UserTokenResponse
+	// DB Transaction
+	err := s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		resp, err := s.IssueTokensTx(ctx, tx, userID, clientID, opts...)
+		response = resp
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// IssueTokensTx is IssueTokens in tx, for callers whose own writes must commit with the
+// new session (redeeming a device code).
+func (s *userService) IssueTokensTx(ctx context.Context, tx *sql.Tx, userID, clientID string, opts ...jwttoken.TokenOption) (*UserTokenResponse, error) {
+	userData, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Session Limit
+	if err := s.enforceSessionLimit(ctx, tx, userData.ID); err != nil {
+		return nil, err
+	}
+
+	// Generate Token
+	session := s.newSession(userData.ID, clientID, false)
+	resp, err := s.generateToken(userData, session, append(tokenOptions(ctx, clientID), opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save Refresh Token
+	if err := s.repo.InsertRefreshTokenTx(ctx, tx, session); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // ------------------ Private Method -------------------
 
-func (s *userService) generateToken(u *user.User) (*UserTokenResponse, error) {
-	accessToken, err := s.token.GenerateAccessToken(u)
+// enforceSessionLimit makes room for one more session, in every transaction that inserts
+// one (register, login, IssueTokensTx). The user row lock is held until the transaction
+// commits, so concurrent logins on any instance are counted one at a time.
+func (s *userService) enforceSessionLimit(ctx context.Context, tx *sql.Tx, userID string) error {
+	limit := s.cfg.Session.MaxSessions
+	if limit <= 0 {
+		return nil
+	}
+
+	if err := s.repo.LockUserTx(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	sessionIDs, err := s.repo.ListActiveSessionIDsTx(ctx, tx, userID)
 	if err != nil {
+		return err
+	}
+
+	excess := len(sessionIDs) - limit + 1
+	if excess <= 0 {
+		return nil
+	}
+	if s.cfg.Session.LimitPolicy == user.SessionLimitReject {
+		return errs.ErrSessionLimitReached
+	}
+
+	// Evict Oldest
+	for _, id := range sessionIDs[:excess] {
+		if err := s.repo.RevokeSessionTx(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenOptions : records the client and binds tokens to the request's DPoP key, if any
+func tokenOptions(ctx context.Context, clientID string) []jwttoken.TokenOption {
+	var opts []jwttoken.TokenOption
+	if clientID != "" {
+		opts = append(opts, jwttoken.WithClient(clientID))
+	}
+	if jkt, ok := auth.GetDPoPKeyFromContext(ctx); ok {
+		opts = append(opts, jwttoken.WithConfirmation(jkt))
+	}
+	return opts
+}
+
+// sessionOptions : what the session was scoped to when it was issued (organization, scope),
+// kept on every refresh. Read from the verified refresh token, whose claims are the session's.
+func sessionOptions(claims *jwttoken.UserClaims) []jwttoken.TokenOption {
+	var opts []jwttoken.TokenOption
+	if claims.OrgID != "" {
+		opts = append(opts, jwttoken.WithOrganization(claims.OrgID, claims.OrgRole))
+	}
+	if claims.Scope != "" {
+		opts = append(opts, jwttoken.WithScope(claims.Scope))
+	}
+	return opts
+}
+
+// generateToken : the refresh token expires with the session's idle deadline, session.Token is set
+func (s *userService) generateToken(u *user.User, session *user.RefreshToken, opts ...jwttoken.TokenOption) (*UserTokenResponse, error) {
+	accessToken, err := s.token.GenerateAccessToken(u, opts...)
+	if err != nil {
+		if errors.Is(err, jwttoken.ErrDPoPRequired) {
+			return nil, errs.ErrDPoPRequired
+		}
 		return nil, fmt.Errorf("failed gen access token: %w", err)
 	}
 
-	refreshToken, err := s.token.GenerateRefreshToken(u)
+	refreshOpts := append(opts, jwttoken.WithTTL(time.Until(session.ExpiresAt)))
+	refreshToken, err := s.token.GenerateRefreshToken(u, refreshOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed gen refresh token: %w", err)
 	}
+	session.Token = refreshToken
 
 	response := &UserTokenResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.cfg.AccessTTL(session.ClientID).Seconds()),
 	}
 	return response, nil
 }
 
-func (s *userService) insertRefreshTokenInput(userID, token string) *user.RefreshToken {
-	return &user.RefreshToken{
-		UserID:    userID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(config.RefreshTokenDuration),
-		Revoked:   false,
+// newSession starts a refresh session with the short or "remember me" profile.
+func (s *userService) newSession(userID, clientID string, rememberMe bool) *user.RefreshToken {
+	absolute := s.cfg.Session.AbsoluteTimeout
+	if rememberMe {
+		absolute = s.cfg.Session.RememberAbsoluteTimeout
 	}
+
+	now := time.Now()
+	session := &user.RefreshToken{
+		UserID:           userID,
+		ClientID:         clientID,
+		RememberMe:       rememberMe,
+		SessionExpiresAt: now.Add(absolute),
+	}
+	session.ExpiresAt = s.idleDeadline(session, now)
+	return session
+}
+
+// extendSession : the next token of the same session, with a new idle deadline
+func (s *userService) extendSession(current *user.RefreshToken) *user.RefreshToken {
+	session := &user.RefreshToken{
+		UserID:           current.UserID,
+		SessionID:        current.SessionID,
+		ClientID:         current.ClientID,
+		RememberMe:       current.RememberMe,
+		SessionExpiresAt: current.SessionExpiresAt,
+	}
+	session.ExpiresAt = s.idleDeadline(session, time.Now())
+	return session
+}
+
+// idleDeadline : now + idle timeout, capped by the client's refresh TTL and the absolute expiry
+func (s *userService) idleDeadline(session *user.RefreshToken, now time.Time) time.Time {
+	idle := s.cfg.Session.IdleTimeout
+	if session.RememberMe {
+		idle = s.cfg.Session.RememberIdleTimeout
+	}
+	idle = min(idle, s.cfg.RefreshTTL(session.ClientID))
+
+	deadline := now.Add(idle)
+	if deadline.After(session.SessionExpiresAt) {
+		return session.SessionExpiresAt
+	}
+	return deadline
+}
+
+func authEvent(event, outcome string) {
+	metrics.AuthEvents.WithLabelValues(event, outcome).Inc()
 }
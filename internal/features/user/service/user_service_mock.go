@@ -0,0 +1,197 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: user_service.go
+
+// Package userservice is a generated GoMock package.
+package userservice
+
+import (
+	context "context"
+	sql "database/sql"
+	reflect "reflect"
+
+	user "github.com/codepnw/go-starter-kit/internal/features/user"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockUserService is a mock of UserService interface.
+type MockUserService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserServiceMockRecorder
+}
+
+// MockUserServiceMockRecorder is the mock recorder for MockUserService.
+type MockUserServiceMockRecorder struct {
+	mock *MockUserService
+}
+
+// NewMockUserService creates a new mock instance.
+func NewMockUserService(ctrl *gomock.Controller) *MockUserService {
+	mock := &MockUserService{ctrl: ctrl}
+	mock.recorder = &MockUserServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockUserService) Authenticate(ctx context.Context, email, password string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, email, password)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockUserServiceMockRecorder) Authenticate(ctx, email, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockUserService)(nil).Authenticate), ctx, email, password)
+}
+
+// GetProfile mocks base method.
+func (m *MockUserService) GetProfile(ctx context.Context) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfile", ctx)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfile indicates an expected call of GetProfile.
+func (mr *MockUserServiceMockRecorder) GetProfile(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfile", reflect.TypeOf((*MockUserService)(nil).GetProfile), ctx)
+}
+
+// GetUserByID mocks base method.
+func (m *MockUserService) GetUserByID(ctx context.Context, userID string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, userID)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockUserServiceMockRecorder) GetUserByID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserService)(nil).GetUserByID), ctx, userID)
+}
+
+// IssueTokens mocks base method.
+func (m *MockUserService) IssueTokens(ctx context.Context, userID, clientID string, opts ...jwttoken.TokenOption) (*UserTokenResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, userID, clientID}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "IssueTokens", varargs...)
+	ret0, _ := ret[0].(*UserTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueTokens indicates an expected call of IssueTokens.
+func (mr *MockUserServiceMockRecorder) IssueTokens(ctx, userID, clientID interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, userID, clientID}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueTokens", reflect.TypeOf((*MockUserService)(nil).IssueTokens), varargs...)
+}
+
+// IssueTokensTx mocks base method.
+func (m *MockUserService) IssueTokensTx(ctx context.Context, tx *sql.Tx, userID, clientID string, opts ...jwttoken.TokenOption) (*UserTokenResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, tx, userID, clientID}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "IssueTokensTx", varargs...)
+	ret0, _ := ret[0].(*UserTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueTokensTx indicates an expected call of IssueTokensTx.
+func (mr *MockUserServiceMockRecorder) IssueTokensTx(ctx, tx, userID, clientID interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, tx, userID, clientID}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueTokensTx", reflect.TypeOf((*MockUserService)(nil).IssueTokensTx), varargs...)
+}
+
+// Login mocks base method.
+func (m *MockUserService) Login(ctx context.Context, email, password, clientID string, rememberMe bool) (*UserTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", ctx, email, password, clientID, rememberMe)
+	ret0, _ := ret[0].(*UserTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockUserServiceMockRecorder) Login(ctx, email, password, clientID, rememberMe interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserService)(nil).Login), ctx, email, password, clientID, rememberMe)
+}
+
+// Logout mocks base method.
+func (m *MockUserService) Logout(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockUserServiceMockRecorder) Logout(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockUserService)(nil).Logout), ctx, token)
+}
+
+// RefreshToken mocks base method.
+func (m *MockUserService) RefreshToken(ctx context.Context, token string) (*UserTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, token)
+	ret0, _ := ret[0].(*UserTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshToken indicates an expected call of RefreshToken.
+func (mr *MockUserServiceMockRecorder) RefreshToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockUserService)(nil).RefreshToken), ctx, token)
+}
+
+// Register mocks base method.
+func (m *MockUserService) Register(ctx context.Context, u *user.User) (*UserTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", ctx, u)
+	ret0, _ := ret[0].(*UserTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockUserServiceMockRecorder) Register(ctx, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockUserService)(nil).Register), ctx, u)
+}
+
+// RegisterTx mocks base method.
+func (m *MockUserService) RegisterTx(ctx context.Context, tx *sql.Tx, u *user.User) (*UserTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterTx", ctx, tx, u)
+	ret0, _ := ret[0].(*UserTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterTx indicates an expected call of RegisterTx.
+func (mr *MockUserServiceMockRecorder) RegisterTx(ctx, tx, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterTx", reflect.TypeOf((*MockUserService)(nil).RegisterTx), ctx, tx, u)
+}
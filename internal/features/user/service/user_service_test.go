@@ -5,20 +5,31 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
 	"github.com/codepnw/go-starter-kit/internal/errs"
 	"github.com/codepnw/go-starter-kit/internal/features/user"
 	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
 	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
 	"github.com/codepnw/go-starter-kit/pkg/database"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/metrics"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
 var ErrDB = errors.New("DB Error")
 
+var mockSession = &user.RefreshToken{
+	UserID:           "mock-uuid-1",
+	SessionID:        "mock-session-1",
+	SessionExpiresAt: time.Now().Add(7 * 24 * time.Hour),
+}
+
 func TestRegister(t *testing.T) {
 	type testCase struct {
 		name        string
@@ -43,7 +54,7 @@ func TestRegister(t *testing.T) {
 				repo.EXPECT().InsertUserTx(gomock.Any(), nil, input).Return(nil).Times(1)
 
 				token.EXPECT().GenerateAccessToken(input).Return("mock-access-token", nil).Times(1)
-				token.EXPECT().GenerateRefreshToken(input).Return("mock-refresh-token", nil).Times(1)
+				token.EXPECT().GenerateRefreshToken(input, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
 
 				repo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
 			},
@@ -88,7 +99,7 @@ func TestRegister(t *testing.T) {
 				repo.EXPECT().InsertUserTx(gomock.Any(), nil, input).Return(nil).Times(1)
 
 				token.EXPECT().GenerateAccessToken(input).Return("mock-access-token", nil).Times(1)
-				token.EXPECT().GenerateRefreshToken(input).Return("", ErrDB).Times(1)
+				token.EXPECT().GenerateRefreshToken(input, gomock.Any()).Return("", ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
@@ -107,7 +118,7 @@ func TestRegister(t *testing.T) {
 				repo.EXPECT().InsertUserTx(gomock.Any(), nil, input).Return(nil).Times(1)
 
 				token.EXPECT().GenerateAccessToken(input).Return("mock-access-token", nil).Times(1)
-				token.EXPECT().GenerateRefreshToken(input).Return("mock-refresh-token", nil).Times(1)
+				token.EXPECT().GenerateRefreshToken(input, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
 
 				repo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(ErrDB).Times(1)
 			},
@@ -154,7 +165,7 @@ func TestLogin(t *testing.T) {
 				).Times(1)
 
 				mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
-				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
 
 				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
 			},
@@ -191,7 +202,7 @@ func TestLogin(t *testing.T) {
 				).Times(1)
 
 				mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
-				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("", ErrDB).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("", ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
@@ -209,7 +220,7 @@ func TestLogin(t *testing.T) {
 				).Times(1)
 
 				mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
-				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
 
 				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(ErrDB).Times(1)
 			},
@@ -222,7 +233,7 @@ func TestLogin(t *testing.T) {
 
 		tc.mockFn(mockTx, mockToken, mockRepo, tc.input)
 
-		resp, err := service.Login(context.Background(), tc.input.Email, tc.input.Password)
+		resp, err := service.Login(context.Background(), tc.input.Email, tc.input.Password, "", false)
 
 		if tc.expectedErr != nil {
 			assert.Error(t, err)
@@ -233,6 +244,53 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestLoginRequiresClientID(t *testing.T) {
+	cfg := *testJWTConfig
+	cfg.DPoPRequiredClients = []string{"mobile"}
+
+	type testCase struct {
+		name        string
+		clientID    string
+		mockFn      func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:     "fail no client id",
+			clientID: "",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {
+			},
+			expectedErr: errs.ErrClientIDRequired,
+		},
+		{
+			name:     "fail dpop client without proof",
+			clientID: "mobile",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {
+				mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(1)
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				mockToken.EXPECT().GenerateAccessToken(mockUser, gomock.Any()).Return("", jwttoken.ErrDPoPRequired).Times(1)
+			},
+			expectedErr: errs.ErrDPoPRequired,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockToken, mockTx, mockRepo, service := setupWithConfig(t, &cfg)
+
+		tc.mockFn(mockTx, mockToken, mockRepo)
+
+		_, err := service.Login(context.Background(), "test1@mail.com", "test_password", tc.clientID, false)
+
+		assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+	}
+}
+
 func TestRefreshToken(t *testing.T) {
 	type testCase struct {
 		name        string
@@ -246,10 +304,11 @@ func TestRefreshToken(t *testing.T) {
 			name:  "success",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(&jwttoken.UserClaims{UserID: "mock-uuid-1"}, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(mockSession, nil).Times(1)
 
 				mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
-				mockRepo.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(mockUser, nil).Times(1)
 
 				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
 					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
@@ -257,10 +316,11 @@ func TestRefreshToken(t *testing.T) {
 					},
 				).Times(1)
 
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, "mock-uuid-1").Return(nil).Times(1)
 				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(nil).Times(1)
 
 				mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
-				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
 
 				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
 			},
@@ -270,17 +330,36 @@ func TestRefreshToken(t *testing.T) {
 			name:  "fail validate token",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(ErrDB).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(&jwttoken.UserClaims{UserID: "mock-uuid-1"}, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil, ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
+		{
+			name:  "fail verify token",
+			token: "mock-refresh-token",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(nil, ErrDB).Times(1)
+			},
+			expectedErr: errs.ErrInvalidToken,
+		},
+		{
+			name:  "fail dpop-bound token without proof",
+			token: "mock-refresh-token",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
+				claims := &jwttoken.UserClaims{UserID: "mock-uuid-1", Cnf: &jwttoken.Confirmation{JKT: "mock-jkt"}}
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(claims, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidToken,
+		},
 		{
 			name:  "fail find user",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(&jwttoken.UserClaims{UserID: "mock-uuid-1"}, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(mockSession, nil).Times(1)
 
-				mockRepo.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(nil, ErrDB).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(nil, ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
@@ -288,10 +367,11 @@ func TestRefreshToken(t *testing.T) {
 			name:  "fail revoked token",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(&jwttoken.UserClaims{UserID: "mock-uuid-1"}, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(mockSession, nil).Times(1)
 
 				mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
-				mockRepo.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(mockUser, nil).Times(1)
 
 				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
 					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
@@ -299,6 +379,7 @@ func TestRefreshToken(t *testing.T) {
 					},
 				).Times(1)
 
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, "mock-uuid-1").Return(nil).Times(1)
 				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
@@ -307,10 +388,11 @@ func TestRefreshToken(t *testing.T) {
 			name:  "fail insert new token",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(&jwttoken.UserClaims{UserID: "mock-uuid-1"}, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(mockSession, nil).Times(1)
 
 				mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
-				mockRepo.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(mockUser, nil).Times(1)
 
 				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
 					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
@@ -318,10 +400,11 @@ func TestRefreshToken(t *testing.T) {
 					},
 				).Times(1)
 
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, "mock-uuid-1").Return(nil).Times(1)
 				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(nil).Times(1)
 
 				mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
-				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
 
 				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(ErrDB).Times(1)
 			},
@@ -340,7 +423,7 @@ func TestRefreshToken(t *testing.T) {
 		resp, err := service.RefreshToken(ctx, tc.token)
 
 		if tc.expectedErr != nil {
-			assert.Error(t, err)
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
 		} else {
 			assert.NoError(t, err)
 			assert.NotEmpty(t, resp)
@@ -348,6 +431,300 @@ func TestRefreshToken(t *testing.T) {
 	}
 }
 
+func TestRefreshTokenSlidingSession(t *testing.T) {
+	type testCase struct {
+		name            string
+		current         *user.RefreshToken
+		expectedExpires time.Time
+	}
+
+	now := time.Now()
+	testCases := []testCase{
+		{
+			name:            "extends idle deadline",
+			current:         &user.RefreshToken{UserID: "mock-uuid-1", SessionID: "mock-session-1", SessionExpiresAt: now.Add(7 * 24 * time.Hour)},
+			expectedExpires: now.Add(24 * time.Hour),
+		},
+		{
+			name:            "capped at absolute expiry",
+			current:         &user.RefreshToken{UserID: "mock-uuid-1", SessionID: "mock-session-1", SessionExpiresAt: now.Add(time.Hour)},
+			expectedExpires: now.Add(time.Hour),
+		},
+		{
+			name:            "remember me uses long idle timeout",
+			current:         &user.RefreshToken{UserID: "mock-uuid-1", SessionID: "mock-session-1", RememberMe: true, SessionExpiresAt: now.Add(90 * 24 * time.Hour)},
+			expectedExpires: now.Add(30 * 24 * time.Hour),
+		},
+	}
+
+	for _, tc := range testCases {
+		mockToken, mockTx, mockRepo, service := setup(t)
+
+		token := "mock-refresh-token"
+		mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
+		mockToken.EXPECT().VerifyRefreshToken(token).Return(&jwttoken.UserClaims{UserID: "mock-uuid-1"}, nil).Times(1)
+		mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(tc.current, nil).Times(1)
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(mockUser, nil).Times(1)
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, "mock-uuid-1").Return(nil).Times(1)
+		mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(nil).Times(1)
+		mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
+		mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("mock-refresh-token-2", nil).Times(1)
+
+		var inserted *user.RefreshToken
+		mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, tx *sql.Tx, rt *user.RefreshToken) error {
+				inserted = rt
+				return nil
+			},
+		).Times(1)
+
+		_, err := service.RefreshToken(context.Background(), token)
+		assert.NoError(t, err, tc.name)
+
+		assert.Equal(t, tc.current.SessionID, inserted.SessionID, tc.name)
+		assert.Equal(t, tc.current.SessionExpiresAt, inserted.SessionExpiresAt, tc.name)
+		assert.Equal(t, "mock-refresh-token-2", inserted.Token, tc.name)
+		assert.WithinDuration(t, tc.expectedExpires, inserted.ExpiresAt, time.Minute, tc.name)
+	}
+}
+
+func TestRefreshTokenKeepsOrganization(t *testing.T) {
+	mockToken, mockTx, mockRepo, service := setup(t)
+
+	token := "mock-refresh-token"
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
+	claims := &jwttoken.UserClaims{UserID: "mock-uuid-1", OrgID: "mock-org-1", OrgRole: "admin"}
+	mockToken.EXPECT().VerifyRefreshToken(token).Return(claims, nil).Times(1)
+	mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(mockSession, nil).Times(1)
+	mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(mockUser, nil).Times(1)
+	mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+			return fn(nil)
+		},
+	).Times(1)
+	mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, "mock-uuid-1").Return(nil).Times(1)
+	mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(nil).Times(1)
+
+	// Both new tokens stay scoped to the organization
+	applied := func(opts []jwttoken.TokenOption) *jwttoken.UserClaims {
+		c := &jwttoken.UserClaims{RegisteredClaims: &jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now())}}
+		for _, opt := range opts {
+			opt(c)
+		}
+		return c
+	}
+	var access, refresh *jwttoken.UserClaims
+	mockToken.EXPECT().GenerateAccessToken(mockUser, gomock.Any()).DoAndReturn(
+		func(u *user.User, opts ...jwttoken.TokenOption) (string, error) {
+			access = applied(opts)
+			return "mock-access-token", nil
+		},
+	).Times(1)
+	mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).DoAndReturn(
+		func(u *user.User, opts ...jwttoken.TokenOption) (string, error) {
+			refresh = applied(opts)
+			return "mock-refresh-token-2", nil
+		},
+	).Times(1)
+	mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+
+	_, err := service.RefreshToken(context.Background(), token)
+	assert.NoError(t, err)
+
+	for _, c := range []*jwttoken.UserClaims{access, refresh} {
+		assert.Equal(t, "mock-org-1", c.OrgID)
+		assert.Equal(t, "admin", c.OrgRole)
+	}
+}
+
+func TestLoginRememberMe(t *testing.T) {
+	for _, rememberMe := range []bool{false, true} {
+		mockToken, mockTx, mockRepo, service := setup(t)
+
+		mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(1)
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		mockToken.EXPECT().GenerateAccessToken(mockUser, gomock.Any()).Return("mock-access-token", nil).Times(1)
+		mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
+
+		var inserted *user.RefreshToken
+		mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, tx *sql.Tx, rt *user.RefreshToken) error {
+				inserted = rt
+				return nil
+			},
+		).Times(1)
+
+		_, err := service.Login(context.Background(), mockUser.Email, "test_password", "web", rememberMe)
+		assert.NoError(t, err)
+
+		absolute := testJWTConfig.Session.AbsoluteTimeout
+		if rememberMe {
+			absolute = testJWTConfig.Session.RememberAbsoluteTimeout
+		}
+		assert.Equal(t, rememberMe, inserted.RememberMe)
+		assert.Equal(t, "web", inserted.ClientID)
+		assert.WithinDuration(t, time.Now().Add(absolute), inserted.SessionExpiresAt, time.Minute)
+	}
+}
+
+func TestLoginSessionLimit(t *testing.T) {
+	type testCase struct {
+		name        string
+		policy      string
+		mockFn      func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User)
+		expectedErr error
+	}
+
+	withTx := func(mockTx *database.MockTxManager) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+	}
+	issue := func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User) {
+		mockToken.EXPECT().GenerateAccessToken(u).Return("mock-access-token", nil).Times(1)
+		mockToken.EXPECT().GenerateRefreshToken(u, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
+		mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+	}
+
+	testCases := []testCase{
+		{
+			name:   "success under limit",
+			policy: user.SessionLimitReject,
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User) {
+				withTx(mockTx)
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, u.ID).Return(nil).Times(1)
+				mockRepo.EXPECT().ListActiveSessionIDsTx(gomock.Any(), nil, u.ID).Return([]string{"s1"}, nil).Times(1)
+				issue(mockToken, mockRepo, u)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "success evict oldest",
+			policy: user.SessionLimitEvictOldest,
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User) {
+				withTx(mockTx)
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, u.ID).Return(nil).Times(1)
+				mockRepo.EXPECT().ListActiveSessionIDsTx(gomock.Any(), nil, u.ID).Return([]string{"s1", "s2", "s3"}, nil).Times(1)
+				mockRepo.EXPECT().RevokeSessionTx(gomock.Any(), nil, "s1").Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeSessionTx(gomock.Any(), nil, "s2").Return(nil).Times(1)
+				issue(mockToken, mockRepo, u)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "fail reject over limit",
+			policy: user.SessionLimitReject,
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User) {
+				withTx(mockTx)
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, u.ID).Return(nil).Times(1)
+				mockRepo.EXPECT().ListActiveSessionIDsTx(gomock.Any(), nil, u.ID).Return([]string{"s1", "s2"}, nil).Times(1)
+			},
+			expectedErr: errs.ErrSessionLimitReached,
+		},
+		{
+			name:   "fail lock user",
+			policy: user.SessionLimitEvictOldest,
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User) {
+				withTx(mockTx)
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, u.ID).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		cfg := *testJWTConfig
+		cfg.Session.MaxSessions = 2
+		cfg.Session.LimitPolicy = tc.policy
+		mockToken, mockTx, mockRepo, service := setupWithConfig(t, &cfg)
+
+		u := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), u.Email).Return(u, nil).Times(1)
+		tc.mockFn(mockTx, mockToken, mockRepo, u)
+
+		resp, err := service.Login(context.Background(), u.Email, "test_password", "", false)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.NotEmpty(t, resp, tc.name)
+		}
+	}
+}
+
+func TestIssueTokensSessionLimit(t *testing.T) {
+	type testCase struct {
+		name        string
+		policy      string
+		mockFn      func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:   "success evict oldest",
+			policy: user.SessionLimitEvictOldest,
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User) {
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, u.ID).Return(nil).Times(1)
+				mockRepo.EXPECT().ListActiveSessionIDsTx(gomock.Any(), nil, u.ID).Return([]string{"s1", "s2"}, nil).Times(1)
+				mockRepo.EXPECT().RevokeSessionTx(gomock.Any(), nil, "s1").Return(nil).Times(1)
+				mockToken.EXPECT().GenerateAccessToken(u, gomock.Any()).Return("mock-access-token", nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(u, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
+				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "fail reject over limit",
+			policy: user.SessionLimitReject,
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, u *user.User) {
+				mockRepo.EXPECT().LockUserTx(gomock.Any(), nil, u.ID).Return(nil).Times(1)
+				mockRepo.EXPECT().ListActiveSessionIDsTx(gomock.Any(), nil, u.ID).Return([]string{"s1", "s2"}, nil).Times(1)
+			},
+			expectedErr: errs.ErrSessionLimitReached,
+		},
+	}
+
+	for _, tc := range testCases {
+		cfg := *testJWTConfig
+		cfg.Session.MaxSessions = 2
+		cfg.Session.LimitPolicy = tc.policy
+		mockToken, mockTx, mockRepo, service := setupWithConfig(t, &cfg)
+
+		u := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com"}
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), u.ID).Return(u, nil).Times(1)
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		tc.mockFn(mockToken, mockRepo, u)
+
+		// e.g. switching organization
+		resp, err := service.IssueTokens(context.Background(), u.ID, "", jwttoken.WithOrganization("mock-org-1", "owner"))
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.NotEmpty(t, resp, tc.name)
+		}
+	}
+}
+
 func TestLogout(t *testing.T) {
 	type testCase struct {
 		name        string
@@ -405,7 +782,165 @@ func TestLogout(t *testing.T) {
 	}
 }
 
+func TestIssueTokens(t *testing.T) {
+	type testCase struct {
+		name        string
+		userID      string
+		mockFn      func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, userID string)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:   "success",
+			userID: "mock-uuid-1",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, userID string) {
+				mockUser := &user.User{ID: userID, Email: "mock@mail.com"}
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), userID).Return(mockUser, nil).Times(1)
+
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockToken.EXPECT().GenerateAccessToken(mockUser, gomock.Any()).Return("mock-access-token", nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
+
+				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "fail find user",
+			userID: "mock-uuid-1",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, userID string) {
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), userID).Return(nil, errs.ErrUserNotFound).Times(1)
+			},
+			expectedErr: errs.ErrUserNotFound,
+		},
+		{
+			name:   "fail insert token",
+			userID: "mock-uuid-1",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, userID string) {
+				mockUser := &user.User{ID: userID, Email: "mock@mail.com"}
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), userID).Return(mockUser, nil).Times(1)
+
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockToken.EXPECT().GenerateAccessToken(mockUser, gomock.Any()).Return("mock-access-token", nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser, gomock.Any()).Return("mock-refresh-token", nil).Times(1)
+
+				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockToken, mockTx, mockRepo, service := setup(t)
+
+		tc.mockFn(mockTx, mockToken, mockRepo, tc.userID)
+
+		resp, err := service.IssueTokens(context.Background(), tc.userID, "", jwttoken.WithOrganization("mock-org-1", "owner"))
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.NotEmpty(t, resp)
+		}
+	}
+}
+
+var testJWTConfig = &config.JWTConfig{
+	AccessTokenTTL:  30 * time.Minute,
+	RefreshTokenTTL: 90 * 24 * time.Hour,
+	Session: config.SessionConfig{
+		IdleTimeout:             24 * time.Hour,
+		AbsoluteTimeout:         7 * 24 * time.Hour,
+		RememberIdleTimeout:     30 * 24 * time.Hour,
+		RememberAbsoluteTimeout: 90 * 24 * time.Hour,
+	},
+}
+
+func TestAuthEventMetrics(t *testing.T) {
+	type testCase struct {
+		name    string
+		call    func(service userservice.UserService) error
+		mockFn  func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository)
+		event   string
+		outcome string
+	}
+
+	testCases := []testCase{
+		{
+			name: "login failure",
+			call: func(service userservice.UserService) error {
+				_, err := service.Login(context.Background(), "test1@mail.com", "wrong_password", "", false)
+				return err
+			},
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {
+				mockUser := &user.User{Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), "test1@mail.com").Return(mockUser, nil).Times(1)
+			},
+			event:   "login",
+			outcome: metrics.AuthFailure,
+		},
+		{
+			name: "refresh token reuse",
+			call: func(service userservice.UserService) error {
+				_, err := service.RefreshToken(context.Background(), "mock-refresh-token")
+				return err
+			},
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {
+				mockToken.EXPECT().VerifyRefreshToken("mock-refresh-token").Return(&jwttoken.UserClaims{UserID: "mock-uuid-1"}, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), "mock-refresh-token").Return(nil, errs.ErrTokenRevoked).Times(1)
+			},
+			event:   "refresh",
+			outcome: metrics.AuthReuse,
+		},
+		{
+			name: "refresh failure",
+			call: func(service userservice.UserService) error {
+				_, err := service.RefreshToken(context.Background(), "mock-refresh-token")
+				return err
+			},
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {
+				mockToken.EXPECT().VerifyRefreshToken("mock-refresh-token").Return(nil, ErrDB).Times(1)
+			},
+			event:   "refresh",
+			outcome: metrics.AuthFailure,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockToken, mockTx, mockRepo, service := setup(t)
+
+		tc.mockFn(mockTx, mockToken, mockRepo)
+
+		counter := metrics.AuthEvents.WithLabelValues(tc.event, tc.outcome)
+		before := testutil.ToFloat64(counter)
+
+		assert.Error(t, tc.call(service), tc.name)
+		assert.Equal(t, float64(1), testutil.ToFloat64(counter)-before, tc.name)
+	}
+}
+
 func setup(t *testing.T) (*jwttoken.MockJWTToken, *database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService) {
+	return setupWithConfig(t, testJWTConfig)
+}
+
+func setupWithConfig(t *testing.T, cfg *config.JWTConfig) (*jwttoken.MockJWTToken, *database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -413,7 +948,7 @@ func setup(t *testing.T) (*jwttoken.MockJWTToken, *database.MockTxManager, *user
 	mockTx := database.NewMockTxManager(ctrl)
 	mockRepo := userrepository.NewMockUserRepository(ctrl)
 
-	service := userservice.NewUserService(mockTx, mockToken, mockRepo)
+	service := userservice.NewUserService(cfg, mockTx, mockToken, mockRepo)
 
 	return mockToken, mockTx, mockRepo, service
 }
@@ -0,0 +1,118 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedUserService puts a span around each UserService method. Credentials and tokens
+// are never recorded, only IDs.
+type tracedUserService struct {
+	next UserService
+}
+
+func NewTracedUserService(next UserService) UserService {
+	return &tracedUserService{next: next}
+}
+
+func (s *tracedUserService) Register(ctx context.Context, u *user.User) (resp *UserTokenResponse, err error) {
+	ctx, span := startSpan(ctx, "Register")
+	defer func() { endSpan(span, err) }()
+
+	resp, err = s.next.Register(ctx, u)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", u.ID))
+	}
+	return resp, err
+}
+
+func (s *tracedUserService) RegisterTx(ctx context.Context, tx *sql.Tx, u *user.User) (resp *UserTokenResponse, err error) {
+	ctx, span := startSpan(ctx, "RegisterTx")
+	defer func() { endSpan(span, err) }()
+
+	resp, err = s.next.RegisterTx(ctx, tx, u)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", u.ID))
+	}
+	return resp, err
+}
+
+func (s *tracedUserService) Login(ctx context.Context, email, password, clientID string, rememberMe bool) (resp *UserTokenResponse, err error) {
+	ctx, span := startSpan(ctx, "Login",
+		attribute.String("client.id", clientID),
+		attribute.Bool("remember_me", rememberMe),
+	)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Login(ctx, email, password, clientID, rememberMe)
+}
+
+func (s *tracedUserService) Authenticate(ctx context.Context, email, password string) (u *user.User, err error) {
+	ctx, span := startSpan(ctx, "Authenticate")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Authenticate(ctx, email, password)
+}
+
+func (s *tracedUserService) RefreshToken(ctx context.Context, token string) (resp *UserTokenResponse, err error) {
+	ctx, span := startSpan(ctx, "RefreshToken")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.RefreshToken(ctx, token)
+}
+
+func (s *tracedUserService) Logout(ctx context.Context, token string) (err error) {
+	ctx, span := startSpan(ctx, "Logout")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Logout(ctx, token)
+}
+
+func (s *tracedUserService) GetProfile(ctx context.Context) (u *user.User, err error) {
+	ctx, span := startSpan(ctx, "GetProfile")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.GetProfile(ctx)
+}
+
+func (s *tracedUserService) GetUserByID(ctx context.Context, userID string) (u *user.User, err error) {
+	ctx, span := startSpan(ctx, "GetUserByID", attribute.String("user.id", userID))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.GetUserByID(ctx, userID)
+}
+
+func (s *tracedUserService) IssueTokens(ctx context.Context, userID, clientID string, opts ...jwttoken.TokenOption) (resp *UserTokenResponse, err error) {
+	ctx, span := startSpan(ctx, "IssueTokens",
+		attribute.String("user.id", userID),
+		attribute.String("client.id", clientID),
+	)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.IssueTokens(ctx, userID, clientID, opts...)
+}
+
+func (s *tracedUserService) IssueTokensTx(ctx context.Context, tx *sql.Tx, userID, clientID string, opts ...jwttoken.TokenOption) (resp *UserTokenResponse, err error) {
+	ctx, span := startSpan(ctx, "IssueTokensTx",
+		attribute.String("user.id", userID),
+		attribute.String("client.id", clientID),
+	)
+	defer func() { endSpan(span, err) }()
+
+	return s.next.IssueTokensTx(ctx, tx, userID, clientID, opts...)
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "userService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	tracing.RecordError(span, err)
+	span.End()
+}
@@ -2,19 +2,38 @@ package user
 
 import "time"
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Session limit policies
+const (
+	SessionLimitEvictOldest = "evict_oldest"
+	SessionLimitReject      = "reject"
+)
+
 type User struct {
 	ID        string    `db:"id" json:"id"`
 	Email     string    `db:"email" json:"email"`
 	Password  string    `db:"password" json:"-"`
+	Role      string    `db:"role" json:"role"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
+// RefreshToken is one token of a refresh session. Rotation keeps the session ID and
+// absolute expiry; ExpiresAt is the idle deadline of this token.
 type RefreshToken struct {
-	ID        string    `db:"id" json:"id"`
-	UserID    string    `db:"user_id" json:"user_id"`
-	Token     string    `db:"token" json:"token"`
-	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
-	Revoked   bool      `db:"revoked" json:"revoked"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID               string    `db:"id" json:"id"`
+	UserID           string    `db:"user_id" json:"user_id"`
+	Token            string    `db:"token" json:"token"`
+	ExpiresAt        time.Time `db:"expires_at" json:"expires_at"`
+	Revoked          bool      `db:"revoked" json:"revoked"`
+	SessionID        string    `db:"session_id" json:"session_id"`
+	SessionExpiresAt time.Time `db:"session_expires_at" json:"session_expires_at"`
+	RememberMe       bool      `db:"remember_me" json:"remember_me"`
+	ClientID         string    `db:"client_id" json:"client_id"`
+	CreatedAt        time.Time `db:"created_at" json:"created_at"`
 }
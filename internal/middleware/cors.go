@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS applies the policy of the route group the request path falls under (the longest
+// matching CORS_GROUP_* path under the API prefix), the base policy otherwise. It is
+// meant for r.Use: preflight requests match no route, so a group-level middleware
+// would never see them.
+func (m *Middleware) CORS() gin.HandlerFunc {
+	cfg := m.cfg.CORS
+	base := cors.New(corsConfig(cfg.Policy("")))
+
+	type groupPolicy struct {
+		path    string
+		handler gin.HandlerFunc
+	}
+	groups := make([]groupPolicy, 0, len(cfg.Groups()))
+	for _, g := range cfg.Groups() {
+		groups = append(groups, groupPolicy{
+			path:    m.cfg.APP.Prefix + g,
+			handler: cors.New(corsConfig(cfg.Policy(g))),
+		})
+	}
+	// Longest first: /admin/clients wins over /admin
+	sort.Slice(groups, func(i, j int) bool { return len(groups[i].path) > len(groups[j].path) })
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		for _, g := range groups {
+			if path == g.path || strings.HasPrefix(path, g.path+"/") {
+				g.handler(c)
+				return
+			}
+		}
+		base(c)
+	}
+}
+
+// corsConfig matches subdomain patterns itself: gin-contrib/cors wildcards are plain
+// prefix/suffix matches, which would let https://*.example.com allow a.b.example.com.
+func corsConfig(p config.CORSPolicy) cors.Config {
+	var origins, patterns []string
+	for _, o := range p.AllowOrigins {
+		if strings.Contains(o, "*.") {
+			patterns = append(patterns, o)
+		} else {
+			origins = append(origins, o)
+		}
+	}
+
+	cfg := cors.Config{
+		AllowOrigins:     origins,
+		AllowMethods:     p.AllowMethods,
+		AllowHeaders:     p.AllowHeaders,
+		ExposeHeaders:    p.ExposeHeaders,
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           p.MaxAge,
+	}
+	if len(patterns) > 0 {
+		cfg.AllowOriginFunc = func(origin string) bool {
+			return slices.ContainsFunc(patterns, func(pattern string) bool {
+				return matchSubdomain(pattern, origin)
+			})
+		}
+	}
+	return cfg
+}
+
+// matchSubdomain : origin is pattern (https://*.example.com) with "*" replaced by
+// exactly one DNS label
+func matchSubdomain(pattern, origin string) bool {
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	if len(origin) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	label := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(label, ".:/@")
+}
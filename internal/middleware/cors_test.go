@@ -0,0 +1,166 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.EnvConfig{
+		APP: config.AppConfig{Prefix: "/api/v1"},
+		CORS: config.CORSConfig{
+			AllowOrigins:     []string{"https://app.example.com", "https://*.example.com"},
+			AllowMethods:     []string{"GET", "POST"},
+			AllowHeaders:     []string{"Content-Type", "Authorization"},
+			ExposeHeaders:    []string{"X-Request-ID"},
+			AllowCredentials: true,
+			MaxAge:           time.Hour,
+			GroupAllowOrigins: map[string]string{
+				"/integrations": "https://partner.io|https://*.partner.io",
+				"/oauth":        "*",
+			},
+			GroupAllowCredentials: map[string]bool{
+				"/integrations": false,
+				"/oauth":        false,
+			},
+		},
+	}
+
+	mid := middleware.InitMiddleware(cfg, nil, nil, nil, nil)
+	r := gin.New()
+	r.Use(mid.CORS())
+	r.GET("/api/v1/users/me", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/api/v1/integrations/events", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/api/v1/oauth/token", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	type testCase struct {
+		name                string
+		method              string
+		path                string
+		origin              string
+		expectedStatus      int
+		expectedOrigin      string
+		expectedCredentials string
+	}
+
+	testCases := []testCase{
+		{
+			name:                "success exact origin",
+			method:              http.MethodGet,
+			path:                "/api/v1/users/me",
+			origin:              "https://app.example.com",
+			expectedStatus:      http.StatusOK,
+			expectedOrigin:      "https://app.example.com",
+			expectedCredentials: "true",
+		},
+		{
+			name:                "success subdomain pattern",
+			method:              http.MethodGet,
+			path:                "/api/v1/users/me",
+			origin:              "https://admin.example.com",
+			expectedStatus:      http.StatusOK,
+			expectedOrigin:      "https://admin.example.com",
+			expectedCredentials: "true",
+		},
+		{
+			name:           "success preflight",
+			method:         http.MethodOptions,
+			path:           "/api/v1/users/me",
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusNoContent,
+			expectedOrigin: "https://app.example.com",
+		},
+		{
+			name:           "fail unknown origin",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/me",
+			origin:         "https://evil.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "fail lookalike domain",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/me",
+			origin:         "https://evilexample.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "fail nested subdomain",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/me",
+			origin:         "https://a.b.example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "fail empty subdomain",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/me",
+			origin:         "https://.example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "fail base origin on group",
+			method:         http.MethodPost,
+			path:           "/api/v1/integrations/events",
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "success group origin without credentials",
+			method:         http.MethodPost,
+			path:           "/api/v1/integrations/events",
+			origin:         "https://hooks.partner.io",
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://hooks.partner.io",
+		},
+		{
+			name:           "success group preflight",
+			method:         http.MethodOptions,
+			path:           "/api/v1/integrations/events",
+			origin:         "https://partner.io",
+			expectedStatus: http.StatusNoContent,
+			expectedOrigin: "https://partner.io",
+		},
+		{
+			name:           "success group any origin",
+			method:         http.MethodPost,
+			path:           "/api/v1/oauth/token",
+			origin:         "https://anything.dev",
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "*",
+		},
+		{
+			name:           "success same origin request",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/me",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		if tc.method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedStatus, w.Code, tc.name)
+		assert.Equal(t, tc.expectedOrigin, w.Header().Get("Access-Control-Allow-Origin"), tc.name)
+		if tc.method != http.MethodOptions {
+			assert.Equal(t, tc.expectedCredentials, w.Header().Get("Access-Control-Allow-Credentials"), tc.name)
+		}
+	}
+}
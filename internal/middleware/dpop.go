@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/pkg/dpop"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errDPoPProofRequired = errors.New("dpop proof required for this token")
+	errDPoPNotBound      = errors.New("token is not dpop-bound")
+	errDPoPKeyMismatch   = errors.New("dpop proof key does not match token")
+	errDPoPReplayed      = errors.New("dpop proof already used")
+	// errNonceStore : the jti could not be checked, not the client's fault
+	errNonceStore = errors.New("dpop proof replay check failed")
+)
+
+// DPoPProof verifies an optional DPoP proof on token endpoints (login, refresh).
+// When present, its key thumbprint is put in the context so issued tokens get bound to it.
+func (m *Middleware) DPoPProof() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(dpop.HeaderName) == "" {
+			c.Next()
+			return
+		}
+
+		proof, err := m.verifyDPoPProof(c, "")
+		if err != nil {
+			dpopError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx := auth.SetContextDPoPKey(c.Request.Context(), proof.JKT)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// checkDPoP : bound tokens (cnf.jkt) need the DPoP scheme and a proof from the same key
+func (m *Middleware) checkDPoP(c *gin.Context, scheme, accessToken string, claims *jwttoken.UserClaims) error {
+	bound := claims.Cnf != nil && claims.Cnf.JKT != ""
+	switch {
+	case !bound && scheme == "DPoP":
+		return errDPoPNotBound
+	case !bound:
+		return nil
+	case scheme != "DPoP":
+		return errDPoPProofRequired
+	}
+
+	proof, err := m.verifyDPoPProof(c, accessToken)
+	if err != nil {
+		return err
+	}
+	if proof.JKT != claims.Cnf.JKT {
+		return errDPoPKeyMismatch
+	}
+	return nil
+}
+
+func (m *Middleware) verifyDPoPProof(c *gin.Context, accessToken string) (*dpop.Proof, error) {
+	proof, err := dpop.Verify(
+		c.GetHeader(dpop.HeaderName),
+		c.Request.Method,
+		dpop.RequestURL(c.Request, m.fromTrustedProxy(c)),
+		accessToken,
+		m.cfg.JWT.DPoPProofWindow,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// jti is single use for twice the iat window
+	fresh, err := m.nonces.Use(c.Request.Context(), "dpop:"+proof.JKT+":"+proof.JTI, 2*m.cfg.JWT.DPoPProofWindow)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errNonceStore, err)
+	}
+	if !fresh {
+		return nil, errDPoPReplayed
+	}
+	return proof, nil
+}
+
+// fromTrustedProxy : the peer is one of HTTP_TRUSTED_PROXIES
+func (m *Middleware) fromTrustedProxy(c *gin.Context) bool {
+	peer, err := netip.ParseAddr(c.RemoteIP())
+	if err != nil {
+		return false
+	}
+	peer = peer.Unmap()
+
+	for _, proxy := range m.cfg.HTTP.TrustedProxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil && prefix.Contains(peer) {
+			return true
+		}
+		if addr, err := netip.ParseAddr(proxy); err == nil && addr.Unmap() == peer {
+			return true
+		}
+	}
+	return false
+}
+
+func dpopError(c *gin.Context, status int, err error) {
+	if errors.Is(err, errNonceStore) {
+		response.ResponseError(c, http.StatusInternalServerError, err)
+		c.Abort()
+		return
+	}
+	c.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof", algs="ES256 RS256 PS256"`)
+	response.ResponseError(c, status, err)
+	c.Abort()
+}
@@ -0,0 +1,272 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/dpop"
+	"github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/nonce"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizedDPoP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.EnvConfig{JWT: config.JWTConfig{
+		AppName:         "test",
+		SecretKey:       "test-secret",
+		RefreshKey:      "test-refresh",
+		AccessTokenTTL:  time.Minute,
+		DPoPProofWindow: time.Minute,
+	}}
+	token, err := jwttoken.NewJWTToken(&cfg.JWT)
+	require.NoError(t, err)
+
+	mid := middleware.InitMiddleware(cfg, token, nonce.NewMemoryStore(), nil, nil)
+	r := gin.New()
+	r.GET("/profile", mid.Authorized(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	const target = "http://api.example.com/profile"
+
+	clientKey := newKey(t)
+	otherKey := newKey(t)
+	jkt, err := dpop.ECPublicJWK(&clientKey.PublicKey).Thumbprint()
+	require.NoError(t, err)
+
+	u := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
+	boundToken, err := token.GenerateAccessToken(u, jwttoken.WithConfirmation(jkt))
+	require.NoError(t, err)
+	bearerToken, err := token.GenerateAccessToken(u)
+	require.NoError(t, err)
+
+	newRequest := func(scheme, accessToken string, proof func() string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", scheme+" "+accessToken)
+		if proof != nil {
+			req.Header.Set(dpop.HeaderName, proof())
+		}
+		return req
+	}
+	proofFor := func(key *ecdsa.PrivateKey, method, url, accessToken string) func() string {
+		return func() string {
+			p, err := dpop.NewProof(key, method, url, accessToken)
+			require.NoError(t, err)
+			return p
+		}
+	}
+
+	type testCase struct {
+		name         string
+		request      func() *http.Request
+		expectedCode int
+	}
+
+	replayedProof := proofFor(clientKey, http.MethodGet, target, boundToken)()
+
+	testCases := []testCase{
+		{
+			name: "success bound token with proof",
+			request: func() *http.Request {
+				return newRequest("DPoP", boundToken, func() string { return replayedProof })
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "success unbound bearer token",
+			request: func() *http.Request {
+				return newRequest("Bearer", bearerToken, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "fail replayed proof",
+			request: func() *http.Request {
+				return newRequest("DPoP", boundToken, func() string { return replayedProof })
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail bound token as bearer",
+			request: func() *http.Request {
+				return newRequest("Bearer", boundToken, nil)
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail proof from another key",
+			request: func() *http.Request {
+				return newRequest("DPoP", boundToken, proofFor(otherKey, http.MethodGet, target, boundToken))
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail proof for another method",
+			request: func() *http.Request {
+				return newRequest("DPoP", boundToken, proofFor(clientKey, http.MethodPost, target, boundToken))
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail proof for another url",
+			request: func() *http.Request {
+				return newRequest("DPoP", boundToken, proofFor(clientKey, http.MethodGet, "http://api.example.com/admin", boundToken))
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail proof for another access token",
+			request: func() *http.Request {
+				return newRequest("DPoP", boundToken, proofFor(clientKey, http.MethodGet, target, bearerToken))
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail unbound token with dpop scheme",
+			request: func() *http.Request {
+				return newRequest("DPoP", bearerToken, proofFor(clientKey, http.MethodGet, target, bearerToken))
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, tc.request())
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		if tc.expectedCode == http.StatusUnauthorized {
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), "DPoP", tc.name)
+		}
+	}
+}
+
+func TestDPoPForwardedProto(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type testCase struct {
+		name           string
+		trustedProxies []string
+		expectedCode   int
+	}
+
+	testCases := []testCase{
+		{
+			// httptest requests come from 192.0.2.1
+			name:           "success trusted proxy",
+			trustedProxies: []string{"192.0.2.0/24"},
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "success trusted proxy ip",
+			trustedProxies: []string{"192.0.2.1"},
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "fail header from untrusted peer",
+			trustedProxies: []string{"10.0.0.0/8"},
+			expectedCode:   http.StatusBadRequest,
+		},
+		{
+			name:           "fail no trusted proxies",
+			trustedProxies: nil,
+			expectedCode:   http.StatusBadRequest,
+		},
+	}
+
+	key := newKey(t)
+	for _, tc := range testCases {
+		cfg := &config.EnvConfig{
+			HTTP: config.HTTPConfig{TrustedProxies: tc.trustedProxies},
+			JWT:  config.JWTConfig{DPoPProofWindow: time.Minute},
+		}
+		mid := middleware.InitMiddleware(cfg, nil, nonce.NewMemoryStore(), nil, nil)
+		r := gin.New()
+		r.POST("/login", mid.DPoPProof(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		// TLS ends at the proxy, the proof names the public https URL
+		proof, err := dpop.NewProof(key, http.MethodPost, "https://api.example.com/login", "")
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "http://api.example.com/login", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set(dpop.HeaderName, proof)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+	}
+}
+
+type failingNonces struct{}
+
+func (failingNonces) Use(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestDPoPSharedNonces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.EnvConfig{JWT: config.JWTConfig{DPoPProofWindow: time.Minute}}
+	newRouter := func(nonces nonce.Store) *gin.Engine {
+		mid := middleware.InitMiddleware(cfg, nil, nonces, nil, nil)
+		r := gin.New()
+		r.POST("/login", mid.DPoPProof(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+
+	// Two instances, one store
+	shared := nonce.NewMemoryStore()
+	instances := []*gin.Engine{newRouter(shared), newRouter(shared)}
+
+	proof, err := dpop.NewProof(newKey(t), http.MethodPost, "http://api.example.com/login", "")
+	require.NoError(t, err)
+
+	type testCase struct {
+		name         string
+		router       *gin.Engine
+		expectedCode int
+	}
+
+	testCases := []testCase{
+		{name: "success first use", router: instances[0], expectedCode: http.StatusOK},
+		{name: "fail replayed on another instance", router: instances[1], expectedCode: http.StatusBadRequest},
+		{name: "fail store down is not the client's fault", router: newRouter(failingNonces{}), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "http://api.example.com/login", nil)
+		req.Header.Set(dpop.HeaderName, proof)
+
+		w := httptest.NewRecorder()
+		tc.router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
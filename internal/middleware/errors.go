@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Errors renders the error a handler recorded with c.Error: the status, code and message
+// come from its errs.AppError, anything else is a 500 that does not show the cause. The
+// body is problem+json or the envelope (ERROR_FORMAT), also for the errors later
+// middleware answer with response.ResponseError, so put it right after RequestID.
+func (m *Middleware) Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m.cfg.Error.Format == "problem" {
+			response.UseProblem(c, m.cfg.Error.ProblemTypeBaseURL)
+		}
+
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		response.Error(c, c.Errors.Last().Err)
+	}
+}
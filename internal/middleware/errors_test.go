@@ -0,0 +1,330 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/codepnw/go-starter-kit/pkg/utils/validate"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useValidator : binding checks `validate` tags, as set up by server.NewServer
+func useValidator(t *testing.T) {
+	prev := binding.Validator
+	binding.Validator = validate.Gin()
+	t.Cleanup(func() { binding.Validator = prev })
+}
+
+func TestErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs := useLogger(t)
+	useValidator(t)
+
+	sqlErr := errors.New(`pq: relation "users" does not exist`)
+
+	newRouter := func(cfg config.ErrorConfig) *gin.Engine {
+		mid := middleware.InitMiddleware(&config.EnvConfig{Error: cfg}, nil, nil, nil, nil)
+		r := gin.New()
+		r.Use(mid.RequestID(), mid.Errors())
+		r.GET("/users/me", func(c *gin.Context) { c.Error(errs.ErrUserNotFound) })
+		r.GET("/wrapped", func(c *gin.Context) {
+			c.Error(fmt.Errorf("get profile: %w", errs.ErrUserNotFound.Wrap(sqlErr)))
+		})
+		r.GET("/unknown", func(c *gin.Context) { c.Error(sqlErr) })
+		r.POST("/bind", func(c *gin.Context) {
+			var req struct {
+				Email string `json:"email" validate:"required"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.Error(errs.InvalidInput(err))
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+		r.GET("/middleware", func(c *gin.Context) {
+			response.ResponseError(c, http.StatusUnauthorized, errors.New("header is missing"))
+		})
+		return r
+	}
+
+	problem := newRouter(config.ErrorConfig{Format: "problem"})
+	typed := newRouter(config.ErrorConfig{Format: "problem", ProblemTypeBaseURL: "https://example.com/problems/"})
+	envelope := newRouter(config.ErrorConfig{Format: "envelope"})
+
+	type testCase struct {
+		name           string
+		router         *gin.Engine
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   map[string]any
+		hiddenText     string
+	}
+
+	testCases := []testCase{
+		{
+			name:           "success app error",
+			router:         problem,
+			method:         http.MethodGet,
+			path:           "/users/me",
+			expectedStatus: http.StatusNotFound,
+			expectedBody: map[string]any{
+				"type":     "about:blank",
+				"title":    "Not Found",
+				"status":   float64(http.StatusNotFound),
+				"detail":   "user not found",
+				"instance": "/users/me",
+				"code":     "user_not_found",
+			},
+		},
+		{
+			name:           "success problem type url",
+			router:         typed,
+			method:         http.MethodGet,
+			path:           "/users/me",
+			expectedStatus: http.StatusNotFound,
+			expectedBody: map[string]any{
+				"type": "https://example.com/problems/user_not_found",
+				"code": "user_not_found",
+			},
+		},
+		{
+			name:           "success wrapped cause hidden",
+			router:         problem,
+			method:         http.MethodGet,
+			path:           "/wrapped",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   map[string]any{"detail": "user not found", "code": "user_not_found"},
+			hiddenText:     "pq:",
+		},
+		{
+			name:           "fail unknown error",
+			router:         problem,
+			method:         http.MethodGet,
+			path:           "/unknown",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   map[string]any{"detail": "internal server error", "code": "internal_error"},
+			hiddenText:     "pq:",
+		},
+		{
+			name:           "fail invalid input",
+			router:         problem,
+			method:         http.MethodPost,
+			path:           "/bind",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]any{"detail": "invalid input", "code": "invalid_input"},
+		},
+		{
+			name:           "success middleware error",
+			router:         problem,
+			method:         http.MethodGet,
+			path:           "/middleware",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   map[string]any{"detail": "header is missing", "code": "unauthorized"},
+		},
+		{
+			name:           "success envelope",
+			router:         envelope,
+			method:         http.MethodGet,
+			path:           "/users/me",
+			expectedStatus: http.StatusNotFound,
+			expectedBody: map[string]any{
+				"success":    false,
+				"code":       float64(http.StatusNotFound),
+				"error":      "user not found",
+				"error_code": "user_not_found",
+			},
+		},
+		{
+			name:           "fail envelope unknown error",
+			router:         envelope,
+			method:         http.MethodGet,
+			path:           "/unknown",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   map[string]any{"error": "internal server error"},
+			hiddenText:     "pq:",
+		},
+	}
+
+	for _, tc := range testCases {
+		logs.Reset()
+
+		w := httptest.NewRecorder()
+		tc.router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{}`)))
+
+		assert.Equal(t, tc.expectedStatus, w.Code, tc.name)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), tc.name)
+		for k, v := range tc.expectedBody {
+			assert.Equal(t, v, body[k], tc.name+": "+k)
+		}
+		assert.NotEmpty(t, body["request_id"], tc.name)
+
+		if tc.router == envelope {
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json", tc.name)
+		} else {
+			assert.Equal(t, response.ContentTypeProblem, w.Header().Get("Content-Type"), tc.name)
+		}
+
+		if tc.hiddenText != "" {
+			assert.NotContains(t, w.Body.String(), tc.hiddenText, tc.name)
+		}
+		// server errors keep their cause in the logs
+		if tc.expectedStatus >= http.StatusInternalServerError {
+			assert.Contains(t, logs.String(), `relation \"users\" does not exist`, tc.name)
+		}
+	}
+}
+
+func TestErrorsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useValidator(t)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{Error: config.ErrorConfig{Format: "problem"}}, nil, nil, nil, nil)
+	r := gin.New()
+	r.Use(mid.RequestID(), mid.Errors())
+	r.POST("/register", func(c *gin.Context) {
+		var req struct {
+			Email    string `json:"email" validate:"required,email"`
+			Password string `json:"password" validate:"required,password"`
+			Age      int    `json:"age"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(errs.InvalidInput(err))
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/orgs/:org_id", func(c *gin.Context) {
+		var uri struct {
+			OrgID string `uri:"org_id" validate:"required,uuid"`
+		}
+		if err := c.ShouldBindUri(&uri); err != nil {
+			c.Error(errs.InvalidInput(err))
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	type testCase struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		acceptLanguage string
+		expectedStatus int
+		expectedErrors []validate.FieldError
+	}
+
+	testCases := []testCase{
+		{
+			name:           "success valid input",
+			method:         http.MethodPost,
+			path:           "/register",
+			body:           `{"email":"john@example.com","password":"Secret123"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "fail custom rules",
+			method:         http.MethodPost,
+			path:           "/register",
+			body:           `{"email":"John <john@example.com>","password":"secret123"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []validate.FieldError{
+				{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+				{Field: "password", Rule: "password", Message: "password must be 8 to 72 characters with an upper case letter, a lower case letter and a digit"},
+			},
+		},
+		{
+			name:           "fail email without dotted domain",
+			method:         http.MethodPost,
+			path:           "/register",
+			body:           `{"email":"john@localhost","password":"Secret123"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []validate.FieldError{
+				{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+			},
+		},
+		{
+			name:           "fail translated",
+			method:         http.MethodPost,
+			path:           "/register",
+			body:           `{}`,
+			acceptLanguage: "th-TH,th;q=0.9,en;q=0.8",
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []validate.FieldError{
+				{Field: "email", Rule: "required", Message: "โปรดระบุ email"},
+				{Field: "password", Rule: "required", Message: "โปรดระบุ password"},
+			},
+		},
+		{
+			name:           "fail unknown language falls back to english",
+			method:         http.MethodPost,
+			path:           "/register",
+			body:           `{"password":"Secret123"}`,
+			acceptLanguage: "fr-CH, fr;q=0.9",
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []validate.FieldError{
+				{Field: "email", Rule: "required", Message: "email is a required field"},
+			},
+		},
+		{
+			name:           "fail wrong json type",
+			method:         http.MethodPost,
+			path:           "/register",
+			body:           `{"email":"john@example.com","password":"Secret123","age":"ten"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []validate.FieldError{
+				{Field: "age", Rule: "type", Message: "age must be of type int"},
+			},
+		},
+		{
+			name:           "success uuid param",
+			method:         http.MethodGet,
+			path:           "/orgs/0b6f8a52-3f4e-4c6a-9d43-5b0c2f1e7a10",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "fail uuid param",
+			method:         http.MethodGet,
+			path:           "/orgs/42",
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []validate.FieldError{
+				{Field: "org_id", Rule: "uuid", Message: "org_id must be a valid UUID"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedStatus, w.Code, tc.name)
+		if tc.expectedStatus == http.StatusOK {
+			continue
+		}
+
+		var problem response.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem), tc.name)
+		assert.Equal(t, "invalid_input", problem.Code, tc.name)
+		assert.Equal(t, tc.expectedErrors, problem.Errors, tc.name)
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/pkg/httpsig"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientSecretProvider looks up the shared secret of an active API client.
+type ClientSecretProvider interface {
+	GetClientSecret(ctx context.Context, clientID string) (string, error)
+}
+
+// SignedRequest authenticates machine clients by their HMAC request signature.
+// It enforces the timestamp window and rejects replayed nonces.
+func (m *Middleware) SignedRequest(secrets ClientSecretProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cred, err := httpsig.Parse(c.Request)
+		if err != nil {
+			response.ResponseError(c, http.StatusUnauthorized, err)
+			c.Abort()
+			return
+		}
+
+		// Read body for hashing, then restore it for the handler
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, m.cfg.HMAC.MaxBodyBytes))
+		if err != nil {
+			response.ResponseError(c, http.StatusRequestEntityTooLarge, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		secret, err := secrets.GetClientSecret(ctx, cred.ClientID)
+		if err != nil {
+			// Unknown and revoked clients look the same as a bad signature
+			response.ResponseError(c, http.StatusUnauthorized, httpsig.ErrSignatureMismatch)
+			c.Abort()
+			return
+		}
+
+		if err := httpsig.Verify(c.Request, body, secret, cred, m.cfg.HMAC.MaxSkew); err != nil {
+			response.ResponseError(c, http.StatusUnauthorized, err)
+			c.Abort()
+			return
+		}
+
+		// Nonce is only recorded for valid signatures, within twice the skew window
+		fresh, err := m.nonces.Use(ctx, "hmac:"+cred.ClientID+":"+cred.Nonce, 2*m.cfg.HMAC.MaxSkew)
+		if err != nil {
+			response.ResponseError(c, http.StatusInternalServerError, err)
+			c.Abort()
+			return
+		}
+		if cred.Nonce == "" || !fresh {
+			response.ResponseError(c, http.StatusUnauthorized, errors.New("nonce already used"))
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.SetContextClientID(ctx, cred.ClientID))
+		c.Next()
+	}
+}
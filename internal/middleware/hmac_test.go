@@ -0,0 +1,140 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/httpsig"
+	"github.com/codepnw/go-starter-kit/pkg/nonce"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticSecrets map[string]string
+
+func (s staticSecrets) GetClientSecret(ctx context.Context, clientID string) (string, error) {
+	secret, ok := s[clientID]
+	if !ok {
+		return "", errors.New("client not found")
+	}
+	return secret, nil
+}
+
+func TestSignedRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.EnvConfig{HMAC: config.HMACConfig{MaxSkew: 5 * time.Minute, MaxBodyBytes: 1 << 20}}
+	mid := middleware.InitMiddleware(cfg, nil, nonce.NewMemoryStore(), nil, nil)
+
+	secrets := staticSecrets{"client-1": "secret-1"}
+
+	r := gin.New()
+	r.POST("/hooks", mid.SignedRequest(secrets), func(c *gin.Context) {
+		clientID, _ := auth.GetClientIDFromContext(c.Request.Context())
+		body := new(bytes.Buffer)
+		body.ReadFrom(c.Request.Body)
+		c.String(http.StatusOK, clientID+":"+body.String())
+	})
+
+	newSigned := func(clientID, secret, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "http://api.example.com/hooks?b=2&a=1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		require.NoError(t, httpsig.Sign(req, clientID, secret, []byte(body), "content-type"))
+		return req
+	}
+
+	type testCase struct {
+		name         string
+		request      func() *http.Request
+		expectedCode int
+		expectedBody string
+	}
+
+	replayed := newSigned("client-1", "secret-1", `{"event":"paid"}`)
+
+	testCases := []testCase{
+		{
+			name:         "success",
+			request:      func() *http.Request { return replayed },
+			expectedCode: http.StatusOK,
+			expectedBody: `client-1:{"event":"paid"}`,
+		},
+		{
+			name: "fail replayed nonce",
+			request: func() *http.Request {
+				req := newSigned("client-1", "secret-1", `{"event":"paid"}`)
+				req.Header = replayed.Header.Clone()
+				return req
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail wrong secret",
+			request: func() *http.Request {
+				return newSigned("client-1", "wrong-secret", `{}`)
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail unknown client",
+			request: func() *http.Request {
+				return newSigned("client-2", "secret-1", `{}`)
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail tampered body",
+			request: func() *http.Request {
+				req := newSigned("client-1", "secret-1", `{"amount":1}`)
+				req.Body = io.NopCloser(bytes.NewBufferString(`{"amount":1000}`))
+				return req
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail tampered signed header",
+			request: func() *http.Request {
+				req := newSigned("client-1", "secret-1", `{}`)
+				req.Header.Set("Content-Type", "text/plain")
+				return req
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail outside skew window",
+			request: func() *http.Request {
+				req := newSigned("client-1", "secret-1", `{}`)
+				req.Header.Set(httpsig.HeaderDate, time.Now().Add(-10*time.Minute).UTC().Format(httpsig.DateFormat))
+				return req
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail missing signature",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/hooks", nil)
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, tc.request())
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		if tc.expectedBody != "" {
+			assert.Equal(t, tc.expectedBody, w.Body.String(), tc.name)
+		}
+	}
+}
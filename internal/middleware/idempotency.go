@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotentBodySize : the body is buffered to fingerprint it
+const maxIdempotentBodySize = 1 << 20
+
+// recordingWriter keeps a copy of the body written to the client.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes retries of a request with an Idempotency-Key header safe. The first
+// request runs and its response is stored for IDEMPOTENCY_TTL; a retry with the same key
+// and payload gets that response again with Idempotent-Replayed: true, a different
+// payload gets 422 and a retry while the first is still running gets 409. 5xx and 429
+// are not stored so the client can retry them. Requests without the header are not
+// affected. Put it after Authorized and RateLimit: keys are scoped to the caller and
+// retries still count. If the store fails the request goes through.
+func (m *Middleware) Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotency.HeaderName)
+		if m.keys == nil || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			response.ResponseError(c, http.StatusBadRequest, errs.ErrInvalidIdempotencyKey)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		if err != nil {
+			response.ResponseError(c, http.StatusRequestEntityTooLarge, errs.ErrRequestTooLarge)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		cfg := m.cfg.Idempotency
+		bucket := fmt.Sprintf("%s %s|%s|%s", c.Request.Method, c.FullPath(), idempotencyScope(c), key)
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		record, token, err := m.keys.Lock(ctx, bucket, fingerprint, cfg.LockTimeout, cfg.TTL)
+		if err != nil {
+			slog.ErrorContext(ctx, "idempotency store failed", slog.String("error", err.Error()))
+			c.Next()
+			return
+		}
+
+		if token == "" {
+			switch {
+			case record.Fingerprint != fingerprint:
+				response.ResponseError(c, http.StatusUnprocessableEntity, errs.ErrIdempotencyKeyReused)
+			case record.Response == nil:
+				c.Header("Retry-After", "1")
+				response.ResponseError(c, http.StatusConflict, errs.ErrIdempotencyInProgress)
+			default:
+				replay(c, record.Response)
+			}
+			c.Abort()
+			return
+		}
+
+		// The client may be gone (that is why it retries): finish with a live context
+		storeCtx := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			// A panic left the key locked, free it for the retry
+			if !completed {
+				if err := m.keys.Unlock(storeCtx, bucket, token); err != nil {
+					logIdempotencyError(ctx, "idempotency unlock failed", err)
+				}
+			}
+		}()
+
+		before := c.Writer.Header().Clone()
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		c.Next()
+
+		status := w.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			return
+		}
+
+		// Keep only what the handler set, headers from earlier middleware are per request
+		header := make(http.Header)
+		for k, v := range w.Header() {
+			if !slices.Equal(before[k], v) {
+				header[k] = v
+			}
+		}
+
+		completed = true
+		res := idempotency.Response{Status: status, Header: header, Body: w.body.Bytes()}
+		if err := m.keys.Complete(storeCtx, bucket, token, res); err != nil {
+			logIdempotencyError(ctx, "idempotency store failed", err)
+		}
+	}
+}
+
+// logIdempotencyError : a lost lock means the request outlived IDEMPOTENCY_LOCK_TIMEOUT
+// and a retry runs in its place, worth a warning rather than an error.
+func logIdempotencyError(ctx context.Context, msg string, err error) {
+	if errors.Is(err, idempotency.ErrLockLost) {
+		slog.WarnContext(ctx, msg, slog.String("error", err.Error()))
+		return
+	}
+	slog.ErrorContext(ctx, msg, slog.String("error", err.Error()))
+}
+
+// replay writes a stored response.
+func replay(c *gin.Context, res *idempotency.Response) {
+	h := c.Writer.Header()
+	for k, v := range res.Header {
+		h[k] = v
+	}
+	h.Set(idempotency.ReplayedHeaderName, "true")
+	c.Writer.WriteHeader(res.Status)
+	c.Writer.Write(res.Body)
+}
+
+// idempotencyScope : keys belong to the caller. Anonymous callers share one space and
+// are told apart by the fingerprint, their IP may change between retries.
+func idempotencyScope(c *gin.Context) string {
+	if userID, err := auth.GetUserIDFromContext(c.Request.Context()); err == nil {
+		return "user:" + userID
+	}
+	if clientID, err := auth.GetClientIDFromContext(c.Request.Context()); err == nil {
+		return "client:" + clientID
+	}
+	return "anon"
+}
@@ -0,0 +1,154 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testIdempotencyConfig = config.IdempotencyConfig{
+	Enabled:     true,
+	TTL:         time.Hour,
+	LockTimeout: time.Minute,
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Sealed like in the server
+	keys := idempotency.NewSealedStore(idempotency.NewMemoryStore())
+	mid := middleware.InitMiddleware(&config.EnvConfig{Idempotency: testIdempotencyConfig}, nil, nil, nil, keys)
+
+	withUser := func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Request = c.Request.WithContext(auth.SetContextUserID(c.Request.Context(), userID))
+		}
+	}
+
+	runs := make(map[string]int)
+	handler := func(c *gin.Context) {
+		runs[c.FullPath()]++
+		c.Header("Location", "/users/"+strconv.Itoa(runs[c.FullPath()]))
+		c.JSON(http.StatusCreated, gin.H{"run": runs[c.FullPath()]})
+	}
+
+	r := gin.New()
+	r.Use(mid.RequestID())
+	r.POST("/register", mid.Idempotency(), handler)
+	r.POST("/orgs", withUser, mid.Idempotency(), handler)
+	r.POST("/flaky", mid.Idempotency(), func(c *gin.Context) {
+		runs[c.FullPath()]++
+		if runs[c.FullPath()] == 1 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"run": runs[c.FullPath()]})
+	})
+
+	type testCase struct {
+		name             string
+		path             string
+		key              string
+		body             string
+		user             string
+		expectedCode     int
+		expectedBody     string
+		expectedError    string
+		expectedReplayed bool
+		expectedLocation string
+		expectedRuns     int
+	}
+
+	testCases := []testCase{
+		{name: "success first", path: "/register", key: "key-1", body: `{"email":"a@example.com"}`, expectedCode: http.StatusCreated, expectedBody: `{"run":1}`, expectedRuns: 1},
+		{name: "success replay", path: "/register", key: "key-1", body: `{"email":"a@example.com"}`, expectedCode: http.StatusCreated, expectedBody: `{"run":1}`, expectedReplayed: true, expectedLocation: "/users/1", expectedRuns: 1},
+		{name: "fail different payload", path: "/register", key: "key-1", body: `{"email":"b@example.com"}`, expectedCode: http.StatusUnprocessableEntity, expectedRuns: 1},
+		{name: "success other key", path: "/register", key: "key-2", body: `{"email":"b@example.com"}`, expectedCode: http.StatusCreated, expectedBody: `{"run":2}`, expectedRuns: 2},
+		{name: "success without key", path: "/register", body: `{"email":"a@example.com"}`, expectedCode: http.StatusCreated, expectedBody: `{"run":3}`, expectedRuns: 3},
+		{name: "fail key too long", path: "/register", key: strings.Repeat("k", 256), expectedCode: http.StatusBadRequest, expectedRuns: 3},
+		{name: "fail body too large", path: "/register", key: "key-3", body: strings.Repeat("a", 1<<20+1), expectedCode: http.StatusRequestEntityTooLarge, expectedError: "request_too_large", expectedRuns: 3},
+		{name: "success same key other route", path: "/orgs", key: "key-1", body: `{"email":"a@example.com"}`, user: "user-1", expectedCode: http.StatusCreated, expectedBody: `{"run":1}`, expectedRuns: 1},
+		{name: "success same key other user", path: "/orgs", key: "key-1", body: `{"name":"acme"}`, user: "user-2", expectedCode: http.StatusCreated, expectedBody: `{"run":2}`, expectedRuns: 2},
+		{name: "success user replay", path: "/orgs", key: "key-1", body: `{"name":"acme"}`, user: "user-2", expectedCode: http.StatusCreated, expectedBody: `{"run":2}`, expectedReplayed: true, expectedLocation: "/users/2", expectedRuns: 2},
+		{name: "fail server error", path: "/flaky", key: "key-1", expectedCode: http.StatusInternalServerError, expectedRuns: 1},
+		{name: "success retry after server error", path: "/flaky", key: "key-1", expectedCode: http.StatusOK, expectedBody: `{"run":2}`, expectedRuns: 2},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		if tc.key != "" {
+			req.Header.Set(idempotency.HeaderName, tc.key)
+		}
+		if tc.user != "" {
+			req.Header.Set("X-Test-User", tc.user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		if tc.expectedBody != "" {
+			assert.JSONEq(t, tc.expectedBody, w.Body.String(), tc.name)
+		}
+		if tc.expectedError != "" {
+			assert.Contains(t, w.Body.String(), tc.expectedError, tc.name)
+		}
+		assert.Equal(t, tc.expectedRuns, runs[tc.path], tc.name)
+		if tc.expectedReplayed {
+			assert.Equal(t, "true", w.Header().Get(idempotency.ReplayedHeaderName), tc.name)
+			assert.Equal(t, tc.expectedLocation, w.Header().Get("Location"), tc.name)
+			// per request headers are not replayed
+			assert.Len(t, w.Header().Values("X-Request-ID"), 1, tc.name)
+		} else {
+			assert.Empty(t, w.Header().Get(idempotency.ReplayedHeaderName), tc.name)
+		}
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{Idempotency: testIdempotencyConfig}, nil, nil, nil, idempotency.NewMemoryStore())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := gin.New()
+	r.POST("/register", mid.Idempotency(), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{}`))
+		req.Header.Set(idempotency.HeaderName, "key-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- send() }()
+	<-started
+
+	w := send()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	require.Equal(t, http.StatusCreated, (<-first).Code)
+
+	w = send()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(idempotency.ReplayedHeaderName))
+}
@@ -0,0 +1,180 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/loadshed"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadShed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+
+	// Requests to /slow hold their slot until released
+	newRouter := func(l *loadshed.Limiter) (*gin.Engine, chan struct{}, chan struct{}) {
+		entered := make(chan struct{}, 8)
+		release := make(chan struct{})
+		r := gin.New()
+		r.Use(mid.LoadShed(l))
+		r.GET("/slow", func(c *gin.Context) {
+			entered <- struct{}{}
+			<-release
+			c.Status(http.StatusOK)
+		})
+		return r, entered, release
+	}
+	serve := func(r *gin.Engine) <-chan *httptest.ResponseRecorder {
+		out := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+			out <- w
+		}()
+		return out
+	}
+
+	t.Run("queued request gets the freed slot, overflow fails fast", func(t *testing.T) {
+		l := loadshed.New(loadshed.Options{Name: "test", MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Minute})
+		r, entered, release := newRouter(l)
+
+		first := serve(r)
+		<-entered
+
+		second := serve(r)
+		require.Eventually(t, func() bool { return l.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		w := <-serve(r)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+		close(release)
+		assert.Equal(t, http.StatusOK, (<-first).Code)
+		assert.Equal(t, http.StatusOK, (<-second).Code)
+
+		stats := l.Stats()
+		assert.Equal(t, uint64(2), stats.Accepted)
+		assert.Equal(t, uint64(1), stats.Rejected)
+		assert.Equal(t, 0, stats.InFlight)
+	})
+
+	t.Run("queue deadline", func(t *testing.T) {
+		l := loadshed.New(loadshed.Options{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 5 * time.Millisecond})
+		r, entered, release := newRouter(l)
+
+		first := serve(r)
+		<-entered
+
+		w := <-serve(r)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+		close(release)
+		assert.Equal(t, http.StatusOK, (<-first).Code)
+		assert.Equal(t, uint64(1), l.Stats().TimedOut)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		r, _, release := newRouter(nil)
+		close(release)
+		assert.Equal(t, http.StatusOK, (<-serve(r)).Code)
+	})
+}
+
+func TestLoadShedAdaptive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Fake clock: the handler advances it by the requested latency
+	var (
+		mu  sync.Mutex
+		now = time.Unix(0, 0)
+	)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	l := loadshed.New(loadshed.Options{
+		MaxInFlight: 4,
+		Adaptive:    &loadshed.AIMD{Min: 1, TargetLatency: 100 * time.Millisecond, Backoff: 0.5},
+		Now:         clock,
+	})
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+	r := gin.New()
+	r.Use(mid.LoadShed(l))
+	r.GET("/", func(c *gin.Context) {
+		ms, _ := strconv.Atoi(c.Query("ms"))
+		mu.Lock()
+		now = now.Add(time.Duration(ms) * time.Millisecond)
+		mu.Unlock()
+
+		code, _ := strconv.Atoi(c.DefaultQuery("code", "200"))
+		c.Status(code)
+	})
+
+	type testCase struct {
+		name          string
+		query         string
+		expectedLimit int
+	}
+
+	testCases := []testCase{
+		{name: "fast at max stays", query: "ms=10", expectedLimit: 4},
+		{name: "slow halves", query: "ms=500", expectedLimit: 2},
+		{name: "slow halves again", query: "ms=500", expectedLimit: 1},
+		{name: "slow stops at min", query: "ms=500", expectedLimit: 1},
+		{name: "fast adds one slot per cap", query: "ms=10", expectedLimit: 2},
+		{name: "fast adds half", query: "ms=10", expectedLimit: 2},
+		{name: "fast adds a third", query: "ms=10", expectedLimit: 2},
+		{name: "fast reaches three", query: "ms=10", expectedLimit: 3},
+		{name: "server error backs off", query: "ms=10&code=500", expectedLimit: 1},
+		{name: "gateway timeout does not back off", query: "ms=10&code=504", expectedLimit: 2},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil))
+		assert.Equal(t, tc.expectedLimit, l.Stats().Limit, tc.name)
+	}
+}
+
+func TestLoadShedNested(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	outer := loadshed.New(loadshed.Options{
+		MaxInFlight: 4,
+		Adaptive:    &loadshed.AIMD{Min: 1, TargetLatency: time.Minute, Backoff: 0.5},
+	})
+	inner := loadshed.New(loadshed.Options{MaxInFlight: 1})
+
+	// The inner limiter is full
+	done, err := inner.Acquire(context.Background())
+	require.NoError(t, err)
+	defer done(false)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+	r := gin.New()
+	r.Use(mid.LoadShed(outer))
+	r.GET("/", mid.LoadShed(inner), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// Shed by the inner limiter: not a failure for the outer one
+	assert.Equal(t, 4, outer.Stats().Limit)
+	assert.Equal(t, 0, outer.Stats().InFlight)
+}
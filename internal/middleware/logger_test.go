@@ -0,0 +1,144 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useLogger points slog.Default at a JSON logger writing to the returned buffer
+// for the duration of the test.
+func useLogger(t *testing.T) *bytes.Buffer {
+	var logs bytes.Buffer
+	h := logger.NewContextHandler(slog.NewJSONHandler(&logs, &slog.HandlerOptions{ReplaceAttr: logger.Redact}))
+
+	prev := slog.Default()
+	slog.SetDefault(slog.New(h))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &logs
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs := useLogger(t)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+	r := gin.New()
+	r.Use(mid.RequestID(), mid.Logger())
+	r.GET("/verify", func(c *gin.Context) {
+		ctx := auth.SetContextUserID(c.Request.Context(), "user-1")
+		ctx = auth.SetContextOrgID(ctx, "org-1")
+		c.Request = c.Request.WithContext(ctx)
+
+		slog.InfoContext(ctx, "login attempt",
+			slog.String("email", "john.doe@example.com"),
+			slog.String("password", "hunter2"),
+			slog.String("authorization", c.GetHeader("Authorization")),
+			slog.String("detail", "retry with Bearer abc.def.ghi"),
+			slog.String("code", "invalid_grant"),
+			slog.String("device_code", "device-secret"),
+			slog.String("user_code", "WDJB-MJHT"),
+		)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/verify?token=secret-token&page=2", nil)
+	req.Header.Set("Authorization", "Bearer abc.def.ghi")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	out := logs.String()
+	assert.NotContains(t, out, "secret-token")
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "abc.def.ghi")
+	assert.NotContains(t, out, "john.doe")
+	assert.NotContains(t, out, "device-secret")
+	assert.NotContains(t, out, "WDJB-MJHT")
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+
+	var service, access map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &service))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &access))
+
+	assert.Equal(t, "j***@example.com", service["email"])
+	assert.Equal(t, "[REDACTED]", service["password"])
+	assert.Equal(t, "[REDACTED]", service["authorization"])
+	assert.Equal(t, "retry with Bearer [REDACTED]", service["detail"])
+	assert.Equal(t, "invalid_grant", service["code"])
+	assert.Equal(t, "user-1", service["user_id"])
+	assert.Equal(t, "org-1", service["org_id"])
+	assert.Equal(t, w.Header().Get("X-Request-ID"), service["request_id"])
+
+	assert.Equal(t, "token=[REDACTED]&page=2", access["query"])
+	assert.Equal(t, w.Header().Get("X-Request-ID"), access["request_id"])
+}
+
+func TestLoggerSampling(t *testing.T) {
+	type testCase struct {
+		name          string
+		rate          float64
+		level         slog.Level
+		expectedLines int
+	}
+
+	testCases := []testCase{
+		{
+			name:          "keep every info",
+			rate:          1,
+			level:         slog.LevelInfo,
+			expectedLines: 100,
+		},
+		{
+			name:          "keep a quarter of info",
+			rate:          0.25,
+			level:         slog.LevelInfo,
+			expectedLines: 25,
+		},
+		{
+			name:          "keep a tenth of debug",
+			rate:          0.1,
+			level:         slog.LevelInfo - 4,
+			expectedLines: 10,
+		},
+		{
+			name:          "always keep warn",
+			rate:          0.1,
+			level:         slog.LevelWarn,
+			expectedLines: 100,
+		},
+		{
+			name:          "always keep error",
+			rate:          0.1,
+			level:         slog.LevelError,
+			expectedLines: 100,
+		},
+	}
+
+	for _, tc := range testCases {
+		var logs bytes.Buffer
+		h := logger.NewSamplingHandler(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}), tc.rate)
+		log := slog.New(h).With(slog.String("component", "test"))
+
+		for range 100 {
+			log.Log(t.Context(), tc.level, "tick")
+		}
+
+		lines := strings.Count(logs.String(), "\n")
+		assert.Equal(t, tc.expectedLines, lines, tc.name)
+	}
+}
@@ -0,0 +1,96 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+	r := gin.New()
+	r.Use(mid.Metrics())
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	r.GET("/orgs/:id", func(c *gin.Context) {
+		// in-flight includes this request
+		assert.Equal(t, float64(1), testutil.ToFloat64(metrics.HTTPInFlight))
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	type testCase struct {
+		name   string
+		paths  []string
+		route  string
+		status string
+	}
+
+	testCases := []testCase{
+		{
+			name:   "labeled by route template",
+			paths:  []string{"/orgs/a", "/orgs/b", "/orgs/c"},
+			route:  "/orgs/:id",
+			status: "200",
+		},
+		{
+			name:   "labeled by status",
+			paths:  []string{"/fail", "/fail"},
+			route:  "/fail",
+			status: "500",
+		},
+		{
+			name:   "unmatched route",
+			paths:  []string{"/does-not-exist/a", "/does-not-exist/b"},
+			route:  "unmatched",
+			status: "404",
+		},
+	}
+
+	for _, tc := range testCases {
+		counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, tc.route, tc.status)
+		before := testutil.ToFloat64(counter)
+
+		for _, path := range tc.paths {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		assert.Equal(t, float64(len(tc.paths)), testutil.ToFloat64(counter)-before, tc.name)
+	}
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.HTTPInFlight))
+
+	// Made-up methods share one label
+	other := metrics.HTTPRequests.WithLabelValues("OTHER", "unmatched", "404")
+	before := testutil.ToFloat64(other)
+	for _, method := range []string{"FOO", "BAR-1", "PROPFIND"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/orgs/a", nil))
+	}
+	assert.Equal(t, float64(3), testutil.ToFloat64(other)-before)
+
+	// Exposition: raw paths never become labels
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `app_http_requests_total{method="GET",route="/orgs/:id",status="200"}`)
+	assert.Contains(t, string(body), `app_http_request_duration_seconds_bucket{method="GET",route="/orgs/:id"`)
+	assert.Contains(t, string(body), "app_http_requests_in_flight")
+	assert.NotContains(t, string(body), "/orgs/a")
+	assert.NotContains(t, string(body), "/does-not-exist")
+	assert.NotContains(t, string(body), `method="FOO"`)
+}
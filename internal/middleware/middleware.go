@@ -3,57 +3,179 @@ package middleware
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/codepnw/go-starter-kit/internal/auth"
 	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/logger"
+	"github.com/codepnw/go-starter-kit/pkg/nonce"
+	"github.com/codepnw/go-starter-kit/pkg/ratelimit"
 	"github.com/codepnw/go-starter-kit/pkg/utils/response"
 	"github.com/gin-gonic/gin"
 )
 
 type Middleware struct {
-	token jwttoken.JWTToken
+	cfg     *config.EnvConfig
+	token   jwttoken.JWTToken
+	nonces  nonce.Store
+	limiter ratelimit.Store
+	keys    idempotency.Store
 }
 
-func InitMiddleware(token jwttoken.JWTToken) *Middleware {
-	return &Middleware{token: token}
+// InitMiddleware : a nil limiter disables RateLimit, nil keys disable Idempotency.
+func InitMiddleware(cfg *config.EnvConfig, token jwttoken.JWTToken, nonces nonce.Store, limiter ratelimit.Store, keys idempotency.Store) *Middleware {
+	return &Middleware{
+		cfg:     cfg,
+		token:   token,
+		nonces:  nonces,
+		limiter: limiter,
+		keys:    keys,
+	}
 }
 
-func (m *Middleware) Authorized() gin.HandlerFunc {
+// Authorized verifies the access token, with opts for routes that also accept a
+// single-purpose token (see jwttoken.AlsoAcceptAudience).
+func (m *Middleware) Authorized(opts ...jwttoken.VerifyOption) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
+			c.Header("WWW-Authenticate", m.challenge("Bearer", "", ""))
 			response.ResponseError(c, http.StatusUnauthorized, errors.New("header is misstion"))
 			c.Abort()
 			return
 		}
 
 		args := strings.Fields(authHeader)
-		if len(args) != 2 || args[0] != "Bearer" {
-			response.ResponseError(c, http.StatusUnauthorized, errors.New("invalid token format"))
+		if len(args) != 2 || (args[0] != "Bearer" && args[0] != "DPoP") {
+			err := errors.New("invalid token format")
+			c.Header("WWW-Authenticate", m.challenge("Bearer", "invalid_request", err.Error()))
+			response.ResponseError(c, http.StatusBadRequest, err)
 			c.Abort()
 			return
 		}
 
-		claims, err := m.token.VerifyAccessToken(args[1])
+		claims, err := m.token.VerifyAccessToken(args[1], opts...)
 		if err != nil {
-			response.ResponseError(c, http.StatusUnauthorized, err)
-			c.Abort()
+			m.tokenError(c, args[0], err)
+			return
+		}
+
+		if err := m.checkDPoP(c, args[0], args[1], claims); err != nil {
+			dpopError(c, http.StatusUnauthorized, err)
 			return
 		}
 
 		ctx := c.Request.Context()
 		ctx = context.WithValue(ctx, config.ContextUserClaimsKey, claims)
 		ctx = context.WithValue(ctx, config.ContextUserIDKey, claims.UserID)
+		if claims.OrgID != "" {
+			ctx = context.WithValue(ctx, config.ContextOrgIDKey, claims.OrgID)
+		}
+		if claims.Cnf != nil {
+			ctx = auth.SetContextDPoPKey(ctx, claims.Cnf.JKT)
+		}
 
 		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
 }
 
+// tokenError : RFC 6750 section 3, tells the client whether to refresh (expired) or start over
+func (m *Middleware) tokenError(c *gin.Context, scheme string, err error) {
+	switch {
+	case errors.Is(err, jwttoken.ErrTokenExpired),
+		errors.Is(err, jwttoken.ErrTokenNotValidYet),
+		errors.Is(err, jwttoken.ErrTokenSignature),
+		errors.Is(err, jwttoken.ErrTokenMalformed),
+		errors.Is(err, jwttoken.ErrTokenDecryption),
+		errors.Is(err, jwttoken.ErrAudienceRestricted),
+		errors.Is(err, jwttoken.ErrTokenInvalidClaims):
+		// Typed errors are safe to describe
+	default:
+		err = errors.New("invalid token")
+	}
+
+	c.Header("WWW-Authenticate", m.challenge(scheme, "invalid_token", err.Error()))
+	response.ResponseError(c, http.StatusUnauthorized, err)
+	c.Abort()
+}
+
+func (m *Middleware) challenge(scheme, code, description string) string {
+	challenge := fmt.Sprintf(`%s realm=%q`, scheme, m.cfg.JWT.AppName)
+	if code != "" {
+		challenge += fmt.Sprintf(`, error=%q`, code)
+	}
+	if description != "" {
+		challenge += fmt.Sprintf(`, error_description=%q`, description)
+	}
+	return challenge
+}
+
+// TenantScoped rejects requests whose :org_id path param differs from the token's active organization.
+func (m *Middleware) TenantScoped() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, err := auth.GetOrgIDFromContext(c.Request.Context())
+		if err != nil {
+			response.ResponseError(c, http.StatusForbidden, err)
+			c.Abort()
+			return
+		}
+
+		if c.Param("org_id") != orgID {
+			response.ResponseError(c, http.StatusForbidden, errs.ErrOrganizationMismatch)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireOrgRole allows only tokens whose organization role is one of roles.
+func (m *Middleware) RequireOrgRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := auth.GetUserFromContext(c.Request.Context())
+		if err != nil {
+			response.ResponseError(c, http.StatusUnauthorized, err)
+			c.Abort()
+			return
+		}
+
+		if !slices.Contains(roles, claims.OrgRole) {
+			response.ResponseError(c, http.StatusForbidden, errs.ErrInsufficientRole)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole allows only users whose account role is one of roles.
+func (m *Middleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := auth.GetUserFromContext(c.Request.Context())
+		if err != nil {
+			response.ResponseError(c, http.StatusUnauthorized, err)
+			c.Abort()
+			return
+		}
+
+		if !slices.Contains(roles, claims.Role) {
+			response.ResponseError(c, http.StatusForbidden, errs.ErrInsufficientPermission)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 func (m *Middleware) Logger() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		start := time.Now()
@@ -76,15 +198,16 @@ func (m *Middleware) Logger() gin.HandlerFunc {
 		}
 
 		if raw != "" {
-			attrs = append(attrs, slog.String("query", raw))
+			attrs = append(attrs, slog.String("query", logger.RedactString(raw)))
 		}
 
+		reqCtx := ctx.Request.Context()
 		if status >= 500 {
-			slog.Error("Request failed", attrs...)
+			slog.ErrorContext(reqCtx, "Request failed", attrs...)
 		} else if status >= 400 {
-			slog.Warn("Bad request", attrs...)
+			slog.WarnContext(reqCtx, "Bad request", attrs...)
 		} else {
-			slog.Info("Request success", attrs...)
+			slog.InfoContext(reqCtx, "Request success", attrs...)
 		}
 	}
 }
@@ -0,0 +1,375 @@
+package middleware_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.EnvConfig{JWT: config.JWTConfig{
+		AppName:        "test-app",
+		SecretKey:      "test-secret",
+		RefreshKey:     "test-refresh",
+		AccessTokenTTL: time.Minute,
+		Leeway:         30 * time.Second,
+	}}
+	token, err := jwttoken.NewJWTToken(&cfg.JWT)
+	require.NoError(t, err)
+
+	mid := middleware.InitMiddleware(cfg, token, nil, nil, nil)
+	r := gin.New()
+	r.GET("/profile", mid.Authorized(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	validToken, err := token.GenerateAccessToken(&user.User{ID: "mock-uuid-1"})
+	require.NoError(t, err)
+
+	// sign builds tokens the generator would never produce
+	sign := func(method jwt.SigningMethod, key any, mutate func(c *jwt.RegisteredClaims)) string {
+		claims := &jwt.RegisteredClaims{
+			Subject:   "mock-uuid-1",
+			Issuer:    "test-app",
+			Audience:  jwt.ClaimStrings{"test-app"},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}
+		mutate(claims)
+		s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+	secret := []byte("test-secret")
+
+	type testCase struct {
+		name              string
+		authHeader        string
+		expectedCode      int
+		expectedChallenge string
+	}
+
+	testCases := []testCase{
+		{
+			name:         "success",
+			authHeader:   "Bearer " + validToken,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "success expired within leeway",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, secret, func(c *jwt.RegisteredClaims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+			}),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:              "fail missing header",
+			authHeader:        "",
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="test-app"`,
+		},
+		{
+			name:              "fail invalid format",
+			authHeader:        "Token " + validToken,
+			expectedCode:      http.StatusBadRequest,
+			expectedChallenge: `error="invalid_request"`,
+		},
+		{
+			name:              "fail malformed",
+			authHeader:        "Bearer not-a-jwt",
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error_description="token is malformed"`,
+		},
+		{
+			name: "fail expired",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, secret, func(c *jwt.RegisteredClaims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error_description="token is expired"`,
+		},
+		{
+			name:              "fail bad signature",
+			authHeader:        "Bearer " + sign(jwt.SigningMethodHS256, []byte("other-secret"), func(c *jwt.RegisteredClaims) {}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error_description="token signature is invalid"`,
+		},
+		{
+			name:              "fail unexpected algorithm",
+			authHeader:        "Bearer " + sign(jwt.SigningMethodHS512, secret, func(c *jwt.RegisteredClaims) {}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error_description="token signature is invalid"`,
+		},
+		{
+			name:              "fail alg none",
+			authHeader:        "Bearer " + sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, func(c *jwt.RegisteredClaims) {}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error_description="token signature is invalid"`,
+		},
+		{
+			name: "fail wrong issuer",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, secret, func(c *jwt.RegisteredClaims) {
+				c.Issuer = "other-app"
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error="invalid_token"`,
+		},
+		{
+			name: "fail wrong audience",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, secret, func(c *jwt.RegisteredClaims) {
+				c.Audience = jwt.ClaimStrings{"billing"}
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error_description="token is restricted to another audience"`,
+		},
+		{
+			name: "fail missing exp",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, secret, func(c *jwt.RegisteredClaims) {
+				c.ExpiresAt = nil
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error="invalid_token"`,
+		},
+		{
+			name: "fail missing iat",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, secret, func(c *jwt.RegisteredClaims) {
+				c.IssuedAt = nil
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error_description="token has invalid claims"`,
+		},
+		{
+			name: "fail issued in the future",
+			authHeader: "Bearer " + sign(jwt.SigningMethodHS256, secret, func(c *jwt.RegisteredClaims) {
+				c.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `error_description="token is not valid yet"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+		if tc.authHeader != "" {
+			req.Header.Set("Authorization", tc.authHeader)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		if tc.expectedChallenge != "" {
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), tc.expectedChallenge, tc.name)
+		}
+	}
+}
+
+func TestAuthorizedEncryptedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const (
+		encKey1 = "k1=" + "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+		encKey2 = "k2=" + "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+	)
+
+	newToken := func(alg string, keys ...string) jwttoken.JWTToken {
+		token, err := jwttoken.NewJWTToken(&config.JWTConfig{
+			AppName:        "test-app",
+			SecretKey:      "test-secret",
+			RefreshKey:     "test-refresh",
+			AccessTokenTTL: time.Minute,
+			EncryptionAlg:  alg,
+			EncryptionKeys: keys,
+		})
+		require.NoError(t, err)
+		return token
+	}
+	u := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
+
+	type testCase struct {
+		name         string
+		issuer       jwttoken.JWTToken
+		verifier     jwttoken.JWTToken
+		expectedCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:         "success direct",
+			issuer:       newToken("dir", encKey1),
+			verifier:     newToken("dir", encKey1),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "success key wrap",
+			issuer:       newToken("A256KW", encKey2),
+			verifier:     newToken("A256KW", encKey2),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "success rotated key still decrypts",
+			issuer:       newToken("dir", encKey1),
+			verifier:     newToken("dir", encKey2, encKey1),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "success plain token while encryption enabled",
+			issuer:       newToken(""),
+			verifier:     newToken("dir", encKey2),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "fail retired key",
+			issuer:       newToken("dir", encKey1),
+			verifier:     newToken("dir", encKey2),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "fail encryption disabled",
+			issuer:       newToken("dir", encKey1),
+			verifier:     newToken(""),
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		accessToken, err := tc.issuer.GenerateAccessToken(u)
+		require.NoError(t, err, tc.name)
+
+		mid := middleware.InitMiddleware(&config.EnvConfig{JWT: config.JWTConfig{AppName: "test-app"}}, tc.verifier, nil, nil, nil)
+		r := gin.New()
+		r.GET("/profile", mid.Authorized(), func(c *gin.Context) {
+			claims, _ := auth.GetUserFromContext(c.Request.Context())
+			c.String(http.StatusOK, claims.Email)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		if tc.expectedCode == http.StatusOK {
+			assert.Equal(t, "mock@mail.com", w.Body.String(), tc.name)
+		}
+	}
+
+	// The payload must not be readable without the key
+	accessToken, err := newToken("dir", encKey1).GenerateAccessToken(u)
+	require.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(accessToken, "."))
+	for _, part := range strings.Split(accessToken, ".") {
+		decoded, _ := base64.RawURLEncoding.DecodeString(part)
+		assert.NotContains(t, string(decoded), "mock@mail.com")
+	}
+}
+
+func TestAuthorizedKeyRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newToken := func(legacy string, retired []string, keys ...string) jwttoken.JWTToken {
+		token, err := jwttoken.NewJWTToken(&config.JWTConfig{
+			AppName:           "test-app",
+			SecretKey:         legacy,
+			RefreshKey:        "test-refresh",
+			AccessKeys:        keys,
+			AccessRetiredKeys: retired,
+			AccessTokenTTL:    time.Minute,
+		})
+		require.NoError(t, err)
+		return token
+	}
+	u := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
+
+	type testCase struct {
+		name         string
+		issuer       jwttoken.JWTToken
+		verifier     jwttoken.JWTToken
+		expectedCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:         "success active key",
+			issuer:       newToken("", nil, "k1=secret-1"),
+			verifier:     newToken("", nil, "k1=secret-1"),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "success staged key verifies before promotion",
+			issuer:       newToken("", nil, "k2=secret-2"),
+			verifier:     newToken("", nil, "k1=secret-1", "k2=secret-2"),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "success old key after promotion",
+			issuer:       newToken("", nil, "k1=secret-1"),
+			verifier:     newToken("", nil, "k2=secret-2", "k1=secret-1"),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "success legacy token without kid",
+			issuer:       newToken("test-secret", nil),
+			verifier:     newToken("test-secret", nil, "k1=secret-1"),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "fail retired key",
+			issuer:       newToken("", nil, "k1=secret-1"),
+			verifier:     newToken("", []string{"k1"}, "k2=secret-2", "k1=secret-1"),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "fail unknown kid",
+			issuer:       newToken("", nil, "k3=secret-1"),
+			verifier:     newToken("", nil, "k1=secret-1"),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "fail legacy token once secret removed",
+			issuer:       newToken("test-secret", nil),
+			verifier:     newToken("", nil, "k1=test-secret"),
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		accessToken, err := tc.issuer.GenerateAccessToken(u)
+		require.NoError(t, err, tc.name)
+
+		mid := middleware.InitMiddleware(&config.EnvConfig{JWT: config.JWTConfig{AppName: "test-app"}}, tc.verifier, nil, nil, nil)
+		r := gin.New()
+		r.GET("/profile", mid.Authorized(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+	}
+
+	// The signing key cannot be retired
+	_, err := jwttoken.NewJWTToken(&config.JWTConfig{
+		RefreshKey:        "test-refresh",
+		AccessKeys:        []string{"k1=secret-1"},
+		AccessRetiredKeys: []string{"k1"},
+	})
+	assert.Error(t, err)
+}
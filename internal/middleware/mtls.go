@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceAuthorized authenticates callers by their verified TLS client certificate.
+// The certificate maps to a service principal, which must match one of allowed.
+func (m *Middleware) ServiceAuthorized(allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cert := verifiedClientCert(c.Request)
+		if cert == nil {
+			response.ResponseError(c, http.StatusUnauthorized, errors.New("client certificate required"))
+			c.Abort()
+			return
+		}
+
+		principal := principalFromCert(cert)
+		if !principal.Matches(allowed) {
+			response.ResponseError(c, http.StatusForbidden, errors.New("service not allowed"))
+			c.Abort()
+			return
+		}
+
+		ctx := auth.SetContextServicePrincipal(c.Request.Context(), principal)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// IdentifyService attaches the service principal when the caller presents an allowed
+// client certificate, and lets every other request through untouched.
+func (m *Middleware) IdentifyService(allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cert := verifiedClientCert(c.Request); cert != nil {
+			if principal := principalFromCert(cert); principal.Matches(allowed) {
+				ctx := auth.SetContextServicePrincipal(c.Request.Context(), principal)
+				c.Request = c.Request.WithContext(ctx)
+			}
+		}
+		c.Next()
+	}
+}
+
+// verifiedClientCert : only chains verified against the client CA bundle count
+func verifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// principalFromCert : name is the first URI SAN (e.g. SPIFFE ID), else first DNS SAN, else subject CN
+func principalFromCert(cert *x509.Certificate) *auth.ServicePrincipal {
+	principal := &auth.ServicePrincipal{
+		Name:       cert.Subject.CommonName,
+		Subject:    cert.Subject.String(),
+		CommonName: cert.Subject.CommonName,
+		DNSNames:   cert.DNSNames,
+	}
+	for _, u := range cert.URIs {
+		principal.URIs = append(principal.URIs, u.String())
+	}
+
+	switch {
+	case len(principal.URIs) > 0:
+		principal.Name = principal.URIs[0]
+	case len(principal.DNSNames) > 0:
+		principal.Name = principal.DNSNames[0]
+	}
+	return principal
+}
@@ -0,0 +1,231 @@
+package middleware_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/tlsconfig"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func TestServiceAuthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ca := newTestCA(t, "Test Internal CA")
+	rogueCA := newTestCA(t, "Rogue CA")
+
+	// Server certificate & client CA bundle on disk, like production config
+	dir := t.TempDir()
+	serverCert, serverKey := ca.issue(t, "localhost", func(tmpl *x509.Certificate) {
+		tmpl.DNSNames = []string{"localhost"}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	})
+	writeFile(t, dir, "server.crt", serverCert)
+	writeFile(t, dir, "server.key", serverKey)
+	writeFile(t, dir, "ca.crt", ca.pem)
+
+	tlsCfg, err := tlsconfig.NewServerTLS(config.TLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		ClientAuth:   "verify_if_given",
+	})
+	require.NoError(t, err)
+
+	mid := middleware.InitMiddleware(nil, nil, nil, nil, nil)
+	r := gin.New()
+	r.GET("/internal", mid.ServiceAuthorized("billing.internal", "spiffe://example.org/reporting"), func(c *gin.Context) {
+		principal, err := auth.GetServicePrincipalFromContext(c.Request.Context())
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, principal)
+	})
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.TLS = tlsCfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	type testCase struct {
+		name         string
+		clientCert   func() *tls.Certificate
+		expectedCode int
+		expectedName string
+	}
+
+	testCases := []testCase{
+		{
+			name: "success dns san",
+			clientCert: func() *tls.Certificate {
+				return clientCert(t, ca, "billing", func(tmpl *x509.Certificate) {
+					tmpl.DNSNames = []string{"billing.internal"}
+				})
+			},
+			expectedCode: http.StatusOK,
+			expectedName: "billing.internal",
+		},
+		{
+			name: "success uri san",
+			clientCert: func() *tls.Certificate {
+				return clientCert(t, ca, "reporting", func(tmpl *x509.Certificate) {
+					u, _ := url.Parse("spiffe://example.org/reporting")
+					tmpl.URIs = []*url.URL{u}
+				})
+			},
+			expectedCode: http.StatusOK,
+			expectedName: "spiffe://example.org/reporting",
+		},
+		{
+			name: "fail service not allowed",
+			clientCert: func() *tls.Certificate {
+				return clientCert(t, ca, "search", func(tmpl *x509.Certificate) {
+					tmpl.DNSNames = []string{"search.internal"}
+				})
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "fail no client certificate",
+			clientCert:   func() *tls.Certificate { return nil },
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "fail certificate from unknown ca",
+			clientCert: func() *tls.Certificate {
+				return clientCert(t, rogueCA, "billing", func(tmpl *x509.Certificate) {
+					tmpl.DNSNames = []string{"billing.internal"}
+				})
+			},
+			// Not in the server's acceptable CAs, so never verified
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		rootCAs := x509.NewCertPool()
+		rootCAs.AddCert(ca.cert)
+
+		clientTLS := &tls.Config{RootCAs: rootCAs}
+		if cert := tc.clientCert(); cert != nil {
+			clientTLS.Certificates = []tls.Certificate{*cert}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+		resp, err := client.Get(srv.URL + "/internal")
+		require.NoError(t, err, tc.name)
+
+		assert.Equal(t, tc.expectedCode, resp.StatusCode, tc.name)
+		if tc.expectedName != "" {
+			var principal auth.ServicePrincipal
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&principal))
+			assert.Equal(t, tc.expectedName, principal.Name, tc.name)
+		}
+		resp.Body.Close()
+	}
+}
+
+// ------------------ Certificate Helpers -------------------
+
+func newTestCA(t *testing.T, cn string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (ca *testCA) issue(t *testing.T, cn string, fn func(tmpl *x509.Certificate)) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	fn(tmpl)
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM
+}
+
+func clientCert(t *testing.T, ca *testCA, cn string, fn func(tmpl *x509.Certificate)) *tls.Certificate {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, cn, func(tmpl *x509.Certificate) {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		fn(tmpl)
+	})
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return &cert
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
@@ -0,0 +1,95 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store down")
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, ratelimit.NewMemoryStore(), nil)
+
+	withUser := func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Request = c.Request.WithContext(auth.SetContextUserID(c.Request.Context(), userID))
+		}
+	}
+
+	r := gin.New()
+	r.POST("/login", mid.RateLimit(ratelimit.PerMinute(2), middleware.RateKeyIP), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/register", mid.RateLimit(ratelimit.PerMinute(2), middleware.RateKeyIP), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/orgs", withUser, mid.RateLimit(ratelimit.PerMinute(1), middleware.RateKeyUser), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	type testCase struct {
+		name              string
+		method            string
+		path              string
+		ip                string
+		user              string
+		expectedCode      int
+		expectedRemaining int
+	}
+
+	testCases := []testCase{
+		{name: "success first", method: http.MethodPost, path: "/login", ip: "10.0.0.1", expectedCode: http.StatusOK, expectedRemaining: 1},
+		{name: "success second", method: http.MethodPost, path: "/login", ip: "10.0.0.1", expectedCode: http.StatusOK, expectedRemaining: 0},
+		{name: "fail over limit", method: http.MethodPost, path: "/login", ip: "10.0.0.1", expectedCode: http.StatusTooManyRequests, expectedRemaining: 0},
+		{name: "success other ip", method: http.MethodPost, path: "/login", ip: "10.0.0.2", expectedCode: http.StatusOK, expectedRemaining: 1},
+		{name: "success other route", method: http.MethodPost, path: "/register", ip: "10.0.0.1", expectedCode: http.StatusOK, expectedRemaining: 1},
+		{name: "success user", method: http.MethodGet, path: "/orgs", ip: "10.0.0.1", user: "user-1", expectedCode: http.StatusOK, expectedRemaining: 0},
+		{name: "fail same user other ip", method: http.MethodGet, path: "/orgs", ip: "10.0.0.2", user: "user-1", expectedCode: http.StatusTooManyRequests, expectedRemaining: 0},
+		{name: "success other user same ip", method: http.MethodGet, path: "/orgs", ip: "10.0.0.1", user: "user-2", expectedCode: http.StatusOK, expectedRemaining: 0},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.RemoteAddr = tc.ip + ":1234"
+		if tc.user != "" {
+			req.Header.Set("X-Test-User", tc.user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		assert.Equal(t, strconv.Itoa(tc.expectedRemaining), w.Header().Get("RateLimit-Remaining"), tc.name)
+		assert.NotEmpty(t, w.Header().Get("RateLimit-Limit"), tc.name)
+		assert.NotEmpty(t, w.Header().Get("RateLimit-Reset"), tc.name)
+		assert.Contains(t, w.Header().Get("RateLimit-Policy"), ";w=60", tc.name)
+
+		if tc.expectedCode == http.StatusTooManyRequests {
+			retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+			assert.NoError(t, err, tc.name)
+			assert.Positive(t, retry, tc.name)
+		} else {
+			assert.Empty(t, w.Header().Get("Retry-After"), tc.name)
+		}
+	}
+
+	// A failing store lets requests through
+	mid = middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, failingStore{}, nil)
+	r = gin.New()
+	r.POST("/login", mid.RateLimit(ratelimit.PerMinute(1), middleware.RateKeyIP), func(c *gin.Context) { c.Status(http.StatusOK) })
+	for range 3 {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/codepnw/go-starter-kit/pkg/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID tags the request with the client's X-Request-ID, else the W3C traceparent
+// trace ID, else a new random ID. The ID is stored in the request context (picked up
+// by the logger.NewContextHandler and by error responses) and echoed back.
+func (m *Middleware) RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		traceID, traced := requestid.ParseTraceParent(c.GetHeader(requestid.TraceParentHeader))
+		if traced {
+			ctx = requestid.WithTraceID(ctx, traceID)
+		}
+
+		id := c.GetHeader(requestid.HeaderName)
+		switch {
+		case requestid.Valid(id):
+		case traced:
+			id = traceID
+		default:
+			id = requestid.New()
+		}
+		ctx = requestid.WithContext(ctx, id)
+
+		c.Header(requestid.HeaderName, id)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,108 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/logger"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var logs bytes.Buffer
+	log := slog.New(logger.NewContextHandler(slog.NewJSONHandler(&logs, nil)))
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+	r := gin.New()
+	r.Use(mid.RequestID())
+	r.GET("/fail", func(c *gin.Context) {
+		// e.g. a service logging with the request context
+		log.InfoContext(c.Request.Context(), "service call")
+		response.ResponseError(c, http.StatusBadRequest, errors.New("bad input"))
+	})
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	type testCase struct {
+		name          string
+		headers       map[string]string
+		expectedID    string
+		expectedTrace string
+	}
+
+	testCases := []testCase{
+		{
+			name:       "success client request id",
+			headers:    map[string]string{"X-Request-ID": "client-req-1"},
+			expectedID: "client-req-1",
+		},
+		{
+			name:          "success trace id when no request id",
+			headers:       map[string]string{"traceparent": traceParent},
+			expectedID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:          "success request id with trace",
+			headers:       map[string]string{"X-Request-ID": "client-req-2", "traceparent": traceParent},
+			expectedID:    "client-req-2",
+			expectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:    "generated for invalid request id",
+			headers: map[string]string{"X-Request-ID": "bad id\n"},
+		},
+		{
+			name:    "generated for invalid traceparent",
+			headers: map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		},
+		{
+			name: "generated",
+		},
+	}
+
+	for _, tc := range testCases {
+		logs.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		id := w.Header().Get("X-Request-ID")
+		if tc.expectedID != "" {
+			assert.Equal(t, tc.expectedID, id, tc.name)
+		} else {
+			assert.Len(t, id, 32, tc.name)
+		}
+
+		var body struct {
+			RequestID string `json:"request_id"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), tc.name)
+		assert.Equal(t, id, body.RequestID, tc.name)
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &record), tc.name)
+		assert.Equal(t, id, record["request_id"], tc.name)
+		if tc.expectedTrace != "" {
+			assert.Equal(t, tc.expectedTrace, record["trace_id"], tc.name)
+		} else {
+			assert.NotContains(t, record, "trace_id", tc.name)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/pkg/csp"
+	"github.com/gin-gonic/gin"
+)
+
+// maxCSPReportSize : browsers batch reports, but a single batch stays small
+const maxCSPReportSize = 64 << 10
+
+// SecurityOption overrides the configured headers for one route group.
+type SecurityOption func(cfg *config.SecurityConfig)
+
+// WithCSP replaces the Content-Security-Policy, e.g. with CSPHTML on HTML pages.
+func WithCSP(policy string) SecurityOption {
+	return func(cfg *config.SecurityConfig) {
+		cfg.CSP = policy
+	}
+}
+
+// WithCSPReportOnly switches the group's policy to or from report-only.
+func WithCSPReportOnly(reportOnly bool) SecurityOption {
+	return func(cfg *config.SecurityConfig) {
+		cfg.CSPReportOnly = reportOnly
+	}
+}
+
+// SecurityHeaders sets the hardening headers from SECURITY_*. Use it with r.Use for the
+// defaults, and again on a route group with options to override some of them: the
+// group's values replace the ones already set. When the CSP has a {nonce} placeholder,
+// a fresh nonce is put in the request context for templates (csp.NonceFromContext).
+func (m *Middleware) SecurityHeaders(opts ...SecurityOption) gin.HandlerFunc {
+	cfg := m.cfg.Security
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if !cfg.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	headers := make(map[string]string)
+	if cfg.HSTSMaxAge > 0 {
+		hsts := "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+		headers["Strict-Transport-Security"] = hsts
+	}
+	if cfg.ContentTypeNosniff {
+		headers["X-Content-Type-Options"] = "nosniff"
+	}
+	if cfg.ReferrerPolicy != "" {
+		headers["Referrer-Policy"] = cfg.ReferrerPolicy
+	}
+	if cfg.PermissionsPolicy != "" {
+		headers["Permissions-Policy"] = cfg.PermissionsPolicy
+	}
+	if cfg.COOP != "" {
+		headers["Cross-Origin-Opener-Policy"] = cfg.COOP
+	}
+	if cfg.COEP != "" {
+		headers["Cross-Origin-Embedder-Policy"] = cfg.COEP
+	}
+	if cfg.CSP != "" && cfg.CSPReportURI != "" {
+		headers["Reporting-Endpoints"] = fmt.Sprintf("%s=%q", csp.ReportGroup, cfg.CSPReportURI)
+	}
+
+	cspHeader, otherHeader := csp.HeaderName, csp.ReportOnlyHeaderName
+	if cfg.CSPReportOnly {
+		cspHeader, otherHeader = otherHeader, cspHeader
+	}
+	withNonce := csp.UsesNonce(cfg.CSP)
+	policy := csp.Render(cfg.CSP, "", cfg.CSPReportURI)
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		for k, v := range headers {
+			h.Set(k, v)
+		}
+
+		if cfg.CSP != "" {
+			value := policy
+			if withNonce {
+				nonce := csp.NewNonce()
+				c.Request = c.Request.WithContext(csp.WithNonce(c.Request.Context(), nonce))
+				value = csp.Render(cfg.CSP, nonce, cfg.CSPReportURI)
+			}
+			// A group may switch between enforced and report-only
+			h.Del(otherHeader)
+			h.Set(cspHeader, value)
+		}
+		c.Next()
+	}
+}
+
+// CSPReport receives the violation reports browsers send to CSP_REPORT_URI, in either
+// the report-uri or the Reporting API format, and logs them.
+func (m *Middleware) CSPReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxCSPReportSize))
+		if err != nil {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		reports, err := csp.ParseReports(c.ContentType(), body)
+		if err != nil {
+			if err == csp.ErrUnsupportedReport {
+				c.AbortWithStatus(http.StatusUnsupportedMediaType)
+				return
+			}
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		for _, r := range reports {
+			slog.WarnContext(c.Request.Context(), "CSP violation",
+				slog.String("document_uri", r.DocumentURI),
+				slog.String("blocked_uri", r.BlockedURI),
+				slog.String("directive", r.EffectiveDirective),
+				slog.String("disposition", r.Disposition),
+				slog.String("source_file", r.SourceFile),
+				slog.Int("line_number", r.LineNumber),
+			)
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
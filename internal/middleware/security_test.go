@@ -0,0 +1,235 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/csp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecurityConfig = config.SecurityConfig{
+	Enabled:               true,
+	HSTSMaxAge:            365 * 24 * time.Hour,
+	HSTSIncludeSubdomains: true,
+	HSTSPreload:           true,
+	CSP:                   "default-src 'none'; frame-ancestors 'none'",
+	CSPHTML:               "default-src 'none'; script-src 'nonce-{nonce}'",
+	ContentTypeNosniff:    true,
+	ReferrerPolicy:        "no-referrer",
+	PermissionsPolicy:     "camera=(), microphone=()",
+	COOP:                  "same-origin",
+	COEP:                  "require-corp",
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type testCase struct {
+		name            string
+		cfg             config.SecurityConfig
+		opts            []middleware.SecurityOption
+		expectedHeaders map[string]string
+		absentHeaders   []string
+		expectedNonce   bool
+	}
+
+	reportOnly := testSecurityConfig
+	reportOnly.CSPReportOnly = true
+	reportOnly.CSPReportURI = "/api/v1/csp-report"
+
+	noHSTS := testSecurityConfig
+	noHSTS.HSTSMaxAge = 0
+	noHSTS.COEP = ""
+
+	disabled := testSecurityConfig
+	disabled.Enabled = false
+
+	testCases := []testCase{
+		{
+			name: "success defaults",
+			cfg:  testSecurityConfig,
+			expectedHeaders: map[string]string{
+				"Strict-Transport-Security":    "max-age=31536000; includeSubDomains; preload",
+				"Content-Security-Policy":      "default-src 'none'; frame-ancestors 'none'",
+				"X-Content-Type-Options":       "nosniff",
+				"Referrer-Policy":              "no-referrer",
+				"Permissions-Policy":           "camera=(), microphone=()",
+				"Cross-Origin-Opener-Policy":   "same-origin",
+				"Cross-Origin-Embedder-Policy": "require-corp",
+			},
+			absentHeaders: []string{csp.ReportOnlyHeaderName, "Reporting-Endpoints"},
+		},
+		{
+			name:          "success group nonce policy",
+			cfg:           testSecurityConfig,
+			opts:          []middleware.SecurityOption{middleware.WithCSP(testSecurityConfig.CSPHTML)},
+			expectedNonce: true,
+			absentHeaders: []string{csp.ReportOnlyHeaderName},
+		},
+		{
+			name: "success report only",
+			cfg:  reportOnly,
+			expectedHeaders: map[string]string{
+				csp.ReportOnlyHeaderName: "default-src 'none'; frame-ancestors 'none'; report-uri /api/v1/csp-report; report-to csp-endpoint",
+				"Reporting-Endpoints":    `csp-endpoint="/api/v1/csp-report"`,
+			},
+			absentHeaders: []string{csp.HeaderName},
+		},
+		{
+			name: "success group enforces over report only",
+			cfg:  reportOnly,
+			opts: []middleware.SecurityOption{middleware.WithCSPReportOnly(false)},
+			expectedHeaders: map[string]string{
+				csp.HeaderName: "default-src 'none'; frame-ancestors 'none'; report-uri /api/v1/csp-report; report-to csp-endpoint",
+			},
+			absentHeaders: []string{csp.ReportOnlyHeaderName},
+		},
+		{
+			name:            "success empty values left out",
+			cfg:             noHSTS,
+			expectedHeaders: map[string]string{"X-Content-Type-Options": "nosniff"},
+			absentHeaders:   []string{"Strict-Transport-Security", "Cross-Origin-Embedder-Policy"},
+		},
+		{
+			name:          "disabled",
+			cfg:           disabled,
+			absentHeaders: []string{"Strict-Transport-Security", csp.HeaderName, "X-Content-Type-Options"},
+		},
+	}
+
+	for _, tc := range testCases {
+		mid := middleware.InitMiddleware(&config.EnvConfig{Security: tc.cfg}, nil, nil, nil, nil)
+
+		var nonces []string
+		r := gin.New()
+		r.Use(mid.SecurityHeaders())
+		r.GET("/page", mid.SecurityHeaders(tc.opts...), func(c *gin.Context) {
+			nonces = append(nonces, csp.NonceFromContext(c.Request.Context()))
+			c.Status(http.StatusOK)
+		})
+
+		var policies []string
+		for range 2 {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/page", nil))
+			require.Equal(t, http.StatusOK, w.Code, tc.name)
+
+			for k, v := range tc.expectedHeaders {
+				assert.Equal(t, v, w.Header().Get(k), tc.name+": "+k)
+			}
+			for _, k := range tc.absentHeaders {
+				assert.Empty(t, w.Header().Values(k), tc.name+": "+k)
+			}
+			// the group's policy replaces the global one, never adds a second
+			assert.LessOrEqual(t, len(w.Header().Values(csp.HeaderName)), 1, tc.name)
+			policies = append(policies, w.Header().Get(csp.HeaderName))
+		}
+
+		if tc.expectedNonce {
+			require.Len(t, nonces, 2, tc.name)
+			assert.NotEmpty(t, nonces[0], tc.name)
+			assert.NotEqual(t, nonces[0], nonces[1], tc.name)
+			for i, nonce := range nonces {
+				assert.Equal(t, "default-src 'none'; script-src 'nonce-"+nonce+"'", policies[i], tc.name)
+			}
+		} else {
+			for _, nonce := range nonces {
+				assert.Empty(t, nonce, tc.name)
+			}
+		}
+	}
+}
+
+func TestCSPReport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs := useLogger(t)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+	r := gin.New()
+	r.POST("/csp-report", mid.CSPReport())
+
+	type testCase struct {
+		name              string
+		contentType       string
+		body              string
+		expectedStatus    int
+		expectedBlocked   []string
+		expectedDirective string
+	}
+
+	testCases := []testCase{
+		{
+			name:              "success report-uri format",
+			contentType:       "application/csp-report",
+			body:              `{"csp-report":{"document-uri":"https://api.example.com/api/v1/oauth/device","blocked-uri":"inline","violated-directive":"script-src","disposition":"report"}}`,
+			expectedStatus:    http.StatusNoContent,
+			expectedBlocked:   []string{"inline"},
+			expectedDirective: "script-src",
+		},
+		{
+			name:        "success reporting api batch",
+			contentType: "application/reports+json",
+			body: `[
+				{"type":"csp-violation","body":{"documentURL":"https://api.example.com/page","blockedURL":"https://cdn.evil.com/x.js","effectiveDirective":"script-src-elem","disposition":"enforce"}},
+				{"type":"deprecation","body":{"id":"x"}},
+				{"type":"csp-violation","body":{"documentURL":"https://api.example.com/page","blockedURL":"eval","effectiveDirective":"script-src","disposition":"enforce"}}
+			]`,
+			expectedStatus:  http.StatusNoContent,
+			expectedBlocked: []string{"https://cdn.evil.com/x.js", "eval"},
+		},
+		{
+			name:           "fail unsupported content type",
+			contentType:    "text/plain",
+			body:           "hello",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "fail invalid json",
+			contentType:    "application/csp-report",
+			body:           "{",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "fail too large",
+			contentType:    "application/csp-report",
+			body:           `{"csp-report":{"blocked-uri":"` + strings.Repeat("a", 70<<10) + `"}}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		logs.Reset()
+
+		req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedStatus, w.Code, tc.name)
+
+		var blocked []string
+		for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(line, &record), tc.name)
+			assert.Equal(t, "CSP violation", record["msg"], tc.name)
+			blocked = append(blocked, record["blocked_uri"].(string))
+			if tc.expectedDirective != "" {
+				assert.Equal(t, tc.expectedDirective, record["directive"], tc.name)
+			}
+		}
+		assert.Equal(t, tc.expectedBlocked, blocked, tc.name)
+	}
+}
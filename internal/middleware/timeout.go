@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// gin context keys: what the first Timeout / BodyLimit saw, so a route-level one
+	// replaces the global one instead of nesting in it
+	timeoutBaseKey   = "middleware.timeout.base"
+	bodyLimitBaseKey = "middleware.body_limit.base"
+)
+
+// Timeout puts a deadline d on the request context, which services and the database
+// driver honor. A route-level Timeout replaces the global one (HTTP_REQUEST_TIMEOUT),
+// longer or shorter, and keeps the values set in between; only the innermost deadline
+// counts. A handler failing after the deadline answers 504 (see response.ResponseError).
+// Work that ignores the context is not interrupted: the server WriteTimeout is the
+// backstop.
+func (m *Middleware) Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		base, ok := c.Value(timeoutBaseKey).(context.Context)
+		if !ok {
+			base = c.Request.Context()
+			c.Set(timeoutBaseKey, base)
+		}
+
+		// Deadline from here, cancellation (client gone) from base
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), d)
+		defer cancel()
+		stop := context.AfterFunc(base, cancel)
+		defer stop()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		// The request context, not ctx: a route-level Timeout has already released its own
+		// deadline, and ctx of a replaced global one must not answer 504 in its place
+		if !c.Writer.Written() && errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+			response.ResponseError(c, http.StatusGatewayTimeout, errs.ErrRequestTimeout)
+		}
+		// The deadline is released on return: middleware running after (Errors, Logger)
+		// keep the request values but not the canceled context
+		c.Request = c.Request.WithContext(context.WithoutCancel(c.Request.Context()))
+	}
+}
+
+// BodyLimit caps the request body at n bytes. A larger Content-Length gets 413 at once;
+// a body that turns out larger fails to read and the handler answers 413 (see
+// response.ResponseError). Like Timeout, a route-level limit replaces the global one.
+func (m *Middleware) BodyLimit(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > n {
+			response.ResponseError(c, http.StatusRequestEntityTooLarge, errs.ErrRequestTooLarge)
+			c.Abort()
+			return
+		}
+
+		body, ok := c.Value(bodyLimitBaseKey).(io.ReadCloser)
+		if !ok {
+			body = c.Request.Body
+			c.Set(bodyLimitBaseKey, body)
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, body, n)
+		c.Next()
+	}
+}
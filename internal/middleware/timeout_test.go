@@ -0,0 +1,139 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+
+	// waitDB : a repository call that honors the context
+	waitDB := func(c *gin.Context, d time.Duration) {
+		select {
+		case <-time.After(d):
+			response.ResponseSuccess(c, http.StatusOK, "done")
+		case <-c.Request.Context().Done():
+			response.ResponseError(c, http.StatusInternalServerError, c.Request.Context().Err())
+		}
+	}
+
+	r := gin.New()
+	r.Use(mid.Errors(), mid.Timeout(20*time.Millisecond))
+	r.GET("/slow", func(c *gin.Context) { waitDB(c, time.Second) })
+	r.GET("/fast", func(c *gin.Context) { waitDB(c, time.Millisecond) })
+	r.GET("/report", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.SetContextUserID(c.Request.Context(), "user-1"))
+	}, mid.Timeout(200*time.Millisecond), func(c *gin.Context) {
+		// the route deadline replaced the global one, values set before it are kept
+		userID, err := auth.GetUserIDFromContext(c.Request.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", userID)
+		waitDB(c, 50*time.Millisecond)
+	})
+	r.GET("/recorded", mid.Timeout(200*time.Millisecond), func(c *gin.Context) {
+		// rendered by Errors once the global deadline has passed too
+		time.Sleep(50 * time.Millisecond)
+		c.Error(errs.ErrUserNotFound)
+	})
+	r.GET("/short", mid.Timeout(time.Millisecond), func(c *gin.Context) { waitDB(c, 10*time.Millisecond) })
+	r.GET("/silent", func(c *gin.Context) { <-c.Request.Context().Done() })
+
+	type testCase struct {
+		name          string
+		path          string
+		expectedCode  int
+		expectedError string
+	}
+
+	testCases := []testCase{
+		{name: "success within deadline", path: "/fast", expectedCode: http.StatusOK},
+		{name: "fail deadline passed", path: "/slow", expectedCode: http.StatusGatewayTimeout, expectedError: "request timed out"},
+		{name: "success route deadline longer than global", path: "/report", expectedCode: http.StatusOK},
+		{name: "fail recorded error within route deadline", path: "/recorded", expectedCode: http.StatusNotFound, expectedError: "user not found"},
+		{name: "fail route deadline shorter than global", path: "/short", expectedCode: http.StatusGatewayTimeout, expectedError: "request timed out"},
+		{name: "fail handler wrote nothing", path: "/silent", expectedCode: http.StatusGatewayTimeout, expectedError: "request timed out"},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		if tc.expectedError != "" {
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), tc.name)
+			assert.Equal(t, tc.expectedError, body["error"], tc.name)
+			assert.Equal(t, float64(tc.expectedCode), body["code"], tc.name)
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+
+	bind := func(c *gin.Context) {
+		var req map[string]string
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ResponseError(c, http.StatusBadRequest, err)
+			return
+		}
+		response.ResponseSuccess(c, http.StatusOK, len(req["data"]))
+	}
+
+	r := gin.New()
+	r.Use(mid.BodyLimit(64))
+	r.POST("/small", bind)
+	r.POST("/upload", mid.BodyLimit(1024), bind)
+
+	type testCase struct {
+		name         string
+		path         string
+		size         int
+		chunked      bool
+		expectedCode int
+	}
+
+	testCases := []testCase{
+		{name: "success under limit", path: "/small", size: 10, expectedCode: http.StatusOK},
+		{name: "fail content length over limit", path: "/small", size: 100, expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "fail chunked body over limit", path: "/small", size: 100, chunked: true, expectedCode: http.StatusRequestEntityTooLarge},
+		{name: "success route limit larger than global", path: "/upload", size: 500, chunked: true, expectedCode: http.StatusOK},
+		{name: "fail route limit", path: "/upload", size: 2000, expectedCode: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		var body io.Reader = strings.NewReader(`{"data":"` + strings.Repeat("a", tc.size) + `"}`)
+		if tc.chunked {
+			// unknown length: the limit is only hit while reading
+			body = io.MultiReader(body)
+		}
+		req := httptest.NewRequest(http.MethodPost, tc.path, body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		if tc.expectedCode == http.StatusRequestEntityTooLarge {
+			assert.Contains(t, w.Body.String(), "request body too large", tc.name)
+		}
+	}
+}
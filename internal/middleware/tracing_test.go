@@ -0,0 +1,185 @@
+package middleware_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/internal/middleware"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/tracing/tracingtest"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeConnector : a driver that accepts every exec, enough to trace a transaction
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := tracingtest.NewInMemory()
+
+	db := sql.OpenDB(database.NewTracedConnector(fakeConnector{}))
+	defer db.Close()
+	txManager := database.NewDBTransaction(db)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := userservice.NewMockUserService(ctrl)
+	service := userservice.NewTracedUserService(mockService)
+
+	mid := middleware.InitMiddleware(&config.EnvConfig{}, nil, nil, nil, nil)
+	r := gin.New()
+	r.Use(mid.Tracing())
+	r.GET("/users/:id", func(c *gin.Context) {
+		u, err := service.GetUserByID(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, u)
+	})
+
+	const (
+		traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		callerTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+		callerSpan  = "00f067aa0ba902b7"
+	)
+
+	type testCase struct {
+		name           string
+		headers        map[string]string
+		mockFn         func()
+		expectedSpans  []string
+		expectedStatus codes.Code
+	}
+
+	testCases := []testCase{
+		{
+			name:    "success continues caller trace",
+			headers: map[string]string{"traceparent": traceParent},
+			mockFn: func() {
+				mockService.EXPECT().GetUserByID(gomock.Any(), "user-1").DoAndReturn(
+					func(ctx context.Context, userID string) (*user.User, error) {
+						err := txManager.WithTx(ctx, func(tx *sql.Tx) error {
+							_, err := tx.ExecContext(ctx, "UPDATE users SET updated_at = NOW() WHERE id = $1", userID)
+							return err
+						})
+						return &user.User{ID: userID}, err
+					},
+				).Times(1)
+			},
+			expectedSpans:  []string{"GET /users/:id", "userService.GetUserByID", "db.WithTx", "sql.begin", "sql.exec", "sql.commit"},
+			expectedStatus: codes.Unset,
+		},
+		{
+			name: "fail rolls back and marks spans",
+			mockFn: func() {
+				mockService.EXPECT().GetUserByID(gomock.Any(), "user-1").DoAndReturn(
+					func(ctx context.Context, userID string) (*user.User, error) {
+						err := txManager.WithTx(ctx, func(tx *sql.Tx) error {
+							return errors.New("validation failed")
+						})
+						return nil, err
+					},
+				).Times(1)
+			},
+			expectedSpans:  []string{"GET /users/:id", "userService.GetUserByID", "db.WithTx", "sql.begin", "sql.rollback"},
+			expectedStatus: codes.Error,
+		},
+	}
+
+	for _, tc := range testCases {
+		exporter.Reset()
+		tc.mockFn()
+
+		req := httptest.NewRequest(http.MethodGet, "/users/user-1", nil)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := spansByName(exporter.GetSpans())
+		for _, name := range tc.expectedSpans {
+			require.Contains(t, spans, name, tc.name)
+		}
+		assert.Len(t, spans, len(tc.expectedSpans), tc.name)
+
+		server := spans["GET /users/:id"]
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind, tc.name)
+		assert.Equal(t, tc.expectedStatus, server.Status.Code, tc.name)
+		if tc.headers["traceparent"] != "" {
+			assert.Equal(t, callerTrace, server.SpanContext.TraceID().String(), tc.name)
+			assert.Equal(t, callerSpan, server.Parent.SpanID().String(), tc.name)
+		}
+
+		// handler > service > transaction > SQL, all in one trace. Statements run with
+		// the service's context (WithTx only hands out the tx), so they hang off the service.
+		parents := map[string]string{
+			"userService.GetUserByID": "GET /users/:id",
+			"db.WithTx":               "userService.GetUserByID",
+			"sql.begin":               "db.WithTx",
+			"sql.exec":                "userService.GetUserByID",
+			"sql.commit":              "db.WithTx",
+			"sql.rollback":            "db.WithTx",
+		}
+		for name, span := range spans {
+			assert.Equal(t, server.SpanContext.TraceID(), span.SpanContext.TraceID(), tc.name+": "+name)
+			if parent, ok := parents[name]; ok {
+				assert.Equal(t, spans[parent].SpanContext.SpanID(), span.Parent.SpanID(), tc.name+": "+name)
+			}
+		}
+		if exec, ok := spans["sql.exec"]; ok {
+			assert.Contains(t, exec.Attributes, attribute.String("db.query.text", "UPDATE users SET updated_at = NOW() WHERE id = $1"), tc.name)
+		}
+		if tc.expectedStatus == codes.Error {
+			assert.Equal(t, codes.Error, spans["userService.GetUserByID"].Status.Code, tc.name)
+			assert.Equal(t, codes.Error, spans["db.WithTx"].Status.Code, tc.name)
+		}
+	}
+
+	// SQL without a span in the context is not traced
+	exporter.Reset()
+	_, err := db.ExecContext(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	assert.Empty(t, exporter.GetSpans())
+}
+
+func spansByName(stubs tracetest.SpanStubs) map[string]tracetest.SpanStub {
+	spans := make(map[string]tracetest.SpanStub, len(stubs))
+	for _, s := range stubs {
+		spans[s.Name] = s
+	}
+	return spans
+}
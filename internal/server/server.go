@@ -2,71 +2,145 @@ package server
 
 import (
 	"database/sql"
+	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/config"
+	apiclienthandler "github.com/codepnw/go-starter-kit/internal/features/apiclient/handler"
+	apiclientrepository "github.com/codepnw/go-starter-kit/internal/features/apiclient/repository"
+	apiclientservice "github.com/codepnw/go-starter-kit/internal/features/apiclient/service"
+	"github.com/codepnw/go-starter-kit/internal/features/oauth"
+	oauthhandler "github.com/codepnw/go-starter-kit/internal/features/oauth/handler"
+	oauthrepository "github.com/codepnw/go-starter-kit/internal/features/oauth/repository"
+	oauthservice "github.com/codepnw/go-starter-kit/internal/features/oauth/service"
+	"github.com/codepnw/go-starter-kit/internal/features/org"
+	orghandler "github.com/codepnw/go-starter-kit/internal/features/org/handler"
+	orgrepository "github.com/codepnw/go-starter-kit/internal/features/org/repository"
+	orgservice "github.com/codepnw/go-starter-kit/internal/features/org/service"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
 	userhandler "github.com/codepnw/go-starter-kit/internal/features/user/handler"
 	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
 	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
 	"github.com/codepnw/go-starter-kit/internal/middleware"
 	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/loadshed"
+	"github.com/codepnw/go-starter-kit/pkg/mailer"
+	"github.com/codepnw/go-starter-kit/pkg/metrics"
+	"github.com/codepnw/go-starter-kit/pkg/nonce"
+	"github.com/codepnw/go-starter-kit/pkg/ratelimit"
 	"github.com/codepnw/go-starter-kit/pkg/utils/response"
-	"github.com/gin-contrib/cors"
+	"github.com/codepnw/go-starter-kit/pkg/utils/validate"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
+// authBodySize : body limit of the routes taking a password
+const authBodySize = 16 << 10
+
 type Server struct {
+	cfg    *config.EnvConfig
 	db     *sql.DB
 	router *gin.Engine
 	token  jwttoken.JWTToken
 	mid    *middleware.Middleware
 	tx     database.TxManager
+	mailer mailer.Mailer
 }
 
 func NewServer(cfg *config.EnvConfig, db *sql.DB) (*Server, error) {
+	// Request binding checks the `validate` rules, with the config's validator
+	binding.Validator = validate.Gin()
 	r := gin.New()
 
+	// Client IPs (rate limits, logs) come from X-Forwarded-For only behind these proxies,
+	// the peer address otherwise
+	if err := r.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		return nil, err
+	}
+
 	// JWT Token
-	token, err := jwttoken.NewJWTToken(cfg.JWT.AppName, cfg.JWT.SecretKey, cfg.JWT.RefreshKey)
+	token, err := jwttoken.NewJWTToken(&cfg.JWT)
 	if err != nil {
 		return nil, err
 	}
 
+	// Rate Limiter
+	var limiter ratelimit.Store
+	if cfg.RateLimit.Enabled {
+		limiter = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Store == "postgres" {
+			limiter = ratelimit.NewPostgresStore(db)
+		}
+	}
+
+	// Idempotency Keys: stored responses carry tokens and client secrets, keep them sealed
+	var keys idempotency.Store
+	if cfg.Idempotency.Enabled {
+		keys = idempotency.NewSealedStore(idempotency.NewPostgresStore(db))
+	}
+
+	// Used Nonces: HMAC request nonces and DPoP proof IDs
+	nonces := nonce.NewMemoryStore()
+	if cfg.Nonce.Store == "postgres" {
+		nonces = nonce.NewPostgresStore(db)
+	}
+
 	// Middleware
-	mid := middleware.InitMiddleware(token)
+	mid := middleware.InitMiddleware(cfg, token, nonces, limiter, keys)
 
 	// DB Transaction
 	tx := database.NewDBTransaction(db)
 
+	// Mailer
+	mail := mailer.NewMailer(cfg.Mail)
+
 	// Denpendency Injection
 	s := &Server{
+		cfg:    cfg,
 		db:     db,
 		router: r,
 		token:  token,
 		mid:    mid,
 		tx:     tx,
+		mailer: mail,
+	}
+
+	// Metrics
+	if cfg.Metrics.Enabled {
+		if err := metrics.RegisterDB(db, cfg.DB.Name); err != nil {
+			return nil, err
+		}
 	}
 
 	// Gin Middleware
 	r.Use(gin.Recovery())
+	r.Use(s.mid.RequestID())
+	r.Use(s.mid.Errors())
+	r.Use(s.mid.SecurityHeaders())
+	r.Use(s.mid.Tracing())
 	r.Use(s.mid.Logger())
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-	
+	if cfg.Metrics.Enabled {
+		r.Use(s.mid.Metrics())
+	}
+	r.Use(s.mid.Timeout(cfg.HTTP.RequestTimeout))
+	r.Use(s.mid.BodyLimit(cfg.HTTP.MaxBodySize))
+	r.Use(s.mid.CORS())
+
 	// Prefix Default: /api/v1
 	prefix := s.router.Group(cfg.APP.Prefix)
-	
-	// Register Routes
+
+	// Register Routes: health checks stay out of load shedding, so an overloaded
+	// instance is not taken for a dead one
 	s.registerHealthRoutes(prefix)
-	s.registerUserRoutes(prefix)
+
+	api := prefix.Group("", s.mid.LoadShed(s.newLoadShedder("api", cfg.LoadShed.MaxInFlight)))
+	s.registerSecurityRoutes(api)
+	s.registerUserRoutes(api)
+	s.registerOrgRoutes(api)
+	s.registerAPIClientRoutes(api)
+	s.registerOAuthRoutes(api)
 
 	return s, nil
 }
@@ -75,23 +149,65 @@ func (s *Server) Handler() http.Handler {
 	return s.router
 }
 
+// MetricsHandler serves /metrics for the admin listener (METRICS_ADDR).
+func (s *Server) MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(s.cfg.Metrics.Path, metrics.Handler())
+	return mux
+}
+
+// newLoadShedder returns nil when load shedding is disabled.
+func (s *Server) newLoadShedder(name string, maxInFlight int) *loadshed.Limiter {
+	cfg := s.cfg.LoadShed
+	if !cfg.Enabled {
+		return nil
+	}
+
+	opts := loadshed.Options{
+		Name:         name,
+		MaxInFlight:  maxInFlight,
+		MaxQueue:     cfg.MaxQueue,
+		QueueTimeout: cfg.QueueTimeout,
+	}
+	if cfg.Adaptive {
+		opts.Adaptive = &loadshed.AIMD{Min: cfg.MinInFlight, TargetLatency: cfg.TargetLatency}
+	}
+	l := loadshed.New(opts)
+
+	if s.cfg.Metrics.Enabled {
+		inFlight := func() float64 { return float64(l.Stats().InFlight) }
+		if err := metrics.RegisterInFlight(name, inFlight); err != nil {
+			slog.Warn("register load shed metrics failed", slog.String("limiter", name), slog.String("error", err.Error()))
+		}
+	}
+	return l
+}
+
 func (s *Server) registerHealthRoutes(r *gin.RouterGroup) {
 	r.GET("/health", func(c *gin.Context) {
 		response.ResponseSuccess(c, http.StatusOK, "Go Starter Kit Running...")
 	})
 }
 
+func (s *Server) registerSecurityRoutes(r *gin.RouterGroup) {
+	// Target for SECURITY_CSP_REPORT_URI
+	r.POST("/csp-report", s.mid.RateLimit(ratelimit.PerMinute(60), middleware.RateKeyIP), s.mid.CSPReport())
+}
+
 func (s *Server) registerUserRoutes(r *gin.RouterGroup) {
 	repo := userrepository.NewUserRepository(s.db)
-	service := userservice.NewUserService(s.tx, s.token, repo)
+	service := userservice.NewTracedUserService(userservice.NewUserService(&s.cfg.JWT, s.tx, s.token, repo))
 	handler := userhandler.NewUserHandler(service)
 
-	// Auth Routes
-	auth := r.Group("/auth")
+	// bcrypt routes get their own, tighter cap
+	passwordShed := s.mid.LoadShed(s.newLoadShedder("auth", s.cfg.LoadShed.AuthMaxInFlight))
+
+	// Auth Routes: credentials only, small bodies
+	auth := r.Group("/auth", s.mid.BodyLimit(authBodySize), s.mid.Timeout(s.cfg.HTTP.AuthRequestTimeout))
 	{
-		auth.POST("/register", handler.Register)
-		auth.POST("/login", handler.Login)
-		auth.POST("/refresh", handler.RefreshToken)
+		auth.POST("/register", s.mid.RateLimit(ratelimit.PerHour(20), middleware.RateKeyIP), s.mid.Idempotency(), passwordShed, handler.Register)
+		auth.POST("/login", s.mid.RateLimit(ratelimit.PerMinute(10), middleware.RateKeyIP), passwordShed, s.mid.DPoPProof(), handler.Login)
+		auth.POST("/refresh", s.mid.RateLimit(ratelimit.PerMinute(30), middleware.RateKeyIP), s.mid.DPoPProof(), handler.RefreshToken)
 
 		// Authorized
 		auth.POST("/logout", handler.Logout, s.mid.Authorized())
@@ -102,4 +218,101 @@ func (s *Server) registerUserRoutes(r *gin.RouterGroup) {
 	{
 		users.GET("/profile", handler.GetProfile)
 	}
+
+	// Internal Routes: service-to-service over mTLS
+	internal := r.Group("/internal", s.mid.ServiceAuthorized(s.cfg.TLS.AllowedServices...))
+	{
+		internal.GET("/users/:user_id", handler.GetUserByID)
+	}
+}
+
+func (s *Server) registerOrgRoutes(r *gin.RouterGroup) {
+	userRepo := userrepository.NewUserRepository(s.db)
+	userService := userservice.NewTracedUserService(userservice.NewUserService(&s.cfg.JWT, s.tx, s.token, userRepo))
+
+	repo := orgrepository.NewOrgRepository(s.db)
+	service := orgservice.NewOrgService(s.tx, repo, userService)
+	handler := orghandler.NewOrgHandler(service)
+
+	invitationRepo := orgrepository.NewInvitationRepository(s.db)
+	invitationService := orgservice.NewInvitationService(&s.cfg.Invite, s.tx, invitationRepo, repo, userRepo, userService, s.mailer)
+	invitationHandler := orghandler.NewInvitationHandler(invitationService)
+
+	// Organization Routes
+	orgs := r.Group("/orgs", s.mid.Authorized(), s.mid.RateLimit(ratelimit.PerMinute(120), middleware.RateKeyUser))
+	{
+		orgs.POST("", s.mid.Idempotency(), handler.CreateOrganization)
+		orgs.GET("", handler.ListMyOrganizations)
+		orgs.POST("/:org_id/switch", handler.SwitchOrganization)
+	}
+
+	// Tenant Routes: path org must match the token org
+	tenant := orgs.Group("/:org_id", s.mid.TenantScoped())
+	{
+		tenant.GET("", handler.GetOrganization)
+		tenant.GET("/members", handler.ListMembers)
+	}
+
+	// Invitation Admin Routes
+	invitations := tenant.Group("/invitations", s.mid.RequireOrgRole(org.RoleOwner, org.RoleAdmin))
+	{
+		invitations.POST("", s.mid.Idempotency(), invitationHandler.CreateInvitation)
+		invitations.GET("", invitationHandler.ListInvitations)
+		invitations.POST("/:invitation_id/resend", invitationHandler.ResendInvitation)
+		invitations.DELETE("/:invitation_id", invitationHandler.RevokeInvitation)
+	}
+
+	// Public: accept by signed link token
+	r.POST("/invitations/accept", s.mid.BodyLimit(authBodySize), s.mid.Timeout(s.cfg.HTTP.AuthRequestTimeout), s.mid.RateLimit(ratelimit.PerMinute(10), middleware.RateKeyIP), s.mid.Idempotency(), invitationHandler.AcceptInvitation)
+}
+
+func (s *Server) registerAPIClientRoutes(r *gin.RouterGroup) {
+	repo := apiclientrepository.NewAPIClientRepository(s.db)
+	service := apiclientservice.NewAPIClientService(repo)
+	handler := apiclienthandler.NewAPIClientHandler(service)
+
+	// Admin Routes
+	clients := r.Group("/admin/clients", s.mid.Authorized(), s.mid.RequireRole(user.RoleAdmin))
+	{
+		clients.POST("", s.mid.Idempotency(), handler.CreateClient)
+		clients.GET("", handler.ListClients)
+		clients.POST("/:client_id/rotate", handler.RotateSecret)
+		clients.DELETE("/:client_id", handler.RevokeClient)
+	}
+
+	// Integration Routes: HMAC signed requests. The IP limit runs first so unsigned or
+	// forged requests cannot make a secret lookup and an HMAC check each for free.
+	integrations := r.Group(
+		"/integrations",
+		s.mid.RateLimit(ratelimit.PerMinute(600), middleware.RateKeyIP),
+		s.mid.SignedRequest(service),
+		s.mid.RateLimit(ratelimit.PerMinute(600), middleware.RateKeyAPIClient),
+	)
+	{
+		integrations.GET("/whoami", handler.WhoAmI)
+	}
+}
+
+func (s *Server) registerOAuthRoutes(r *gin.RouterGroup) {
+	userRepo := userrepository.NewUserRepository(s.db)
+	userService := userservice.NewTracedUserService(userservice.NewUserService(&s.cfg.JWT, s.tx, s.token, userRepo))
+
+	repo := oauthrepository.NewOAuthRepository(s.db)
+	service := oauthservice.NewOAuthService(&s.cfg.OAuth, s.tx, s.token, repo, userService)
+	handler := oauthhandler.NewOAuthHandler(service, s.cfg.APP.Prefix)
+
+	// OAuth Routes
+	oauthRoutes := r.Group("/oauth")
+	{
+		// Token exchange callers are identified by their client certificate
+		oauthRoutes.POST("/token", s.mid.RateLimit(ratelimit.PerMinute(120), middleware.RateKeyIP), s.mid.IdentifyService(s.cfg.OAuth.ExchangeActors...), s.mid.DPoPProof(), handler.Token)
+
+		// Device Authorization Grant (RFC 8628)
+		oauthRoutes.POST("/device/code", s.mid.RateLimit(ratelimit.PerMinute(10), middleware.RateKeyIP), handler.RequestDeviceCode)
+		oauthRoutes.GET("/device", s.mid.SecurityHeaders(middleware.WithCSP(s.cfg.Security.CSPHTML)), handler.DevicePage)
+		oauthRoutes.POST("/device/login", s.mid.BodyLimit(authBodySize), s.mid.Timeout(s.cfg.HTTP.AuthRequestTimeout), s.mid.RateLimit(ratelimit.PerMinute(10), middleware.RateKeyIP), handler.DeviceLogin)
+
+		// Authorized: a user's access token, or the device page's login
+		oauthRoutes.POST("/device/verify", s.mid.Authorized(jwttoken.AlsoAcceptAudience(oauth.DeviceVerifyAudience)), handler.VerifyDevice)
+	}
 }
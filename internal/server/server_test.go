@@ -0,0 +1,182 @@
+package server_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/server"
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer builds the server from an empty .env with the required settings and env on
+// top. The env stays set until t ends: run each case in its own t.Run.
+func newServer(t *testing.T, env map[string]string) *server.Server {
+	gin.SetMode(gin.TestMode)
+
+	envPath := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(envPath, nil, 0o600))
+
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_PASSWORD", "postgres")
+	t.Setenv("DB_NAME", "starter")
+	t.Setenv("JWT_SECRET_KEY", "access-secret")
+	t.Setenv("JWT_REFRESH_KEY", "refresh-secret")
+	t.Setenv("INVITE_SECRET_KEY", "invite-secret")
+	t.Setenv("METRICS_ENABLED", "false")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	cfg, err := config.LoadConfig(envPath)
+	require.NoError(t, err)
+
+	db, err := sql.Open("postgres", cfg.GetDatabaseDSN())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	s, err := server.NewServer(cfg, db)
+	require.NoError(t, err)
+	return s
+}
+
+func TestTrustedProxies(t *testing.T) {
+	type testCase struct {
+		name           string
+		trustedProxies string
+		expectLimited  bool
+	}
+
+	testCases := []testCase{
+		{
+			// A spoofed X-Forwarded-For does not give each request its own bucket
+			name:          "success untrusted peer limited by its address",
+			expectLimited: true,
+		},
+		{
+			// httptest requests come from 192.0.2.1
+			name:           "success trusted proxy forwards client ips",
+			trustedProxies: "192.0.2.0/24",
+			expectLimited:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer(t, map[string]string{"HTTP_TRUSTED_PROXIES": tc.trustedProxies})
+
+			// The device code route allows 10 requests a minute per client IP
+			limited := false
+			for i := range 11 {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/oauth/device/code", strings.NewReader(`{}`))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+				w := httptest.NewRecorder()
+				s.Handler().ServeHTTP(w, req)
+
+				limited = limited || w.Code == http.StatusTooManyRequests
+			}
+			assert.Equal(t, tc.expectLimited, limited)
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	env := map[string]string{"METRICS_ENABLED": "true"}
+
+	// A second server replaces the database and load shedder collectors of the first
+	newServer(t, env)
+	s := newServer(t, env)
+
+	// Never on the API router
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	s.MetricsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "go_sql_open_connections")
+	assert.Contains(t, w.Body.String(), `app_loadshed_in_flight{limiter="auth"}`)
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	type testCase struct {
+		name           string
+		env            map[string]string
+		path           string
+		body           string
+		expectedStatus int
+	}
+
+	// Nothing listens on the database port: a query fails at once unless the deadline
+	// has already passed, which answers 504
+	db := map[string]string{"DB_HOST": "127.0.0.1", "DB_PORT": "1"}
+	with := func(env map[string]string) map[string]string {
+		for k, v := range db {
+			env[k] = v
+		}
+		return env
+	}
+	login := `{"email":"john@mail.com","password":"Password1!"}`
+
+	testCases := []testCase{
+		{
+			name:           "success auth route gets the auth deadline",
+			env:            with(map[string]string{"HTTP_AUTH_REQUEST_TIMEOUT": "1ns"}),
+			path:           "/api/v1/auth/login",
+			body:           login,
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:           "success other routes keep the global deadline",
+			env:            with(map[string]string{"HTTP_AUTH_REQUEST_TIMEOUT": "1ns"}),
+			path:           "/api/v1/oauth/device/code",
+			body:           `{"client_id":"cli"}`,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			// Replaced, not nested: the auth deadline is longer than the global one, so the
+			// failed user lookup answers as invalid credentials instead of 504
+			name:           "success auth deadline replaces the global one",
+			env:            with(map[string]string{"HTTP_REQUEST_TIMEOUT": "1ns"}),
+			path:           "/api/v1/auth/login",
+			body:           login,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer(t, tc.env)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestIntegrationsRateLimit(t *testing.T) {
+	s := newServer(t, nil)
+
+	// Unsigned requests are counted per IP before the signature is checked
+	codes := make(map[int]int)
+	for range 601 {
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/integrations/whoami", nil))
+		codes[w.Code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusUnauthorized: 600, http.StatusTooManyRequests: 1}, codes)
+}
@@ -0,0 +1,140 @@
+package csp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mime"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+)
+
+const (
+	HeaderName           = "Content-Security-Policy"
+	ReportOnlyHeaderName = "Content-Security-Policy-Report-Only"
+
+	// NoncePlaceholder in a policy is replaced by the request's nonce
+	NoncePlaceholder = "{nonce}"
+
+	// ReportGroup is the Reporting-Endpoints name used by report-to
+	ReportGroup = "csp-endpoint"
+)
+
+var ErrUnsupportedReport = errors.New("unsupported csp report content type")
+
+// NewNonce returns a random 128-bit nonce, base64 encoded as CSP expects.
+func NewNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// UsesNonce reports whether policy has a {nonce} placeholder.
+func UsesNonce(policy string) bool {
+	return strings.Contains(policy, NoncePlaceholder)
+}
+
+// Render fills the {nonce} placeholders and, with reportURI set, adds the report-uri
+// (older browsers) and report-to (Reporting API) directives.
+func Render(policy, nonce, reportURI string) string {
+	policy = strings.TrimSpace(strings.ReplaceAll(policy, NoncePlaceholder, nonce))
+	if reportURI == "" {
+		return policy
+	}
+	policy = strings.TrimSuffix(policy, ";")
+	return policy + "; report-uri " + reportURI + "; report-to " + ReportGroup
+}
+
+func WithNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, config.ContextCSPNonceKey, nonce)
+}
+
+// NonceFromContext returns the nonce to put on inline <script nonce="..."> tags.
+func NonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(config.ContextCSPNonceKey).(string)
+	return nonce
+}
+
+// Report : one violation, the fields common to both report formats
+type Report struct {
+	DocumentURI        string `json:"document_uri"`
+	BlockedURI         string `json:"blocked_uri"`
+	EffectiveDirective string `json:"effective_directive"`
+	Disposition        string `json:"disposition"`
+	SourceFile         string `json:"source_file,omitempty"`
+	LineNumber         int    `json:"line_number,omitempty"`
+}
+
+// ParseReports decodes a report-uri body (application/csp-report) or a Reporting API
+// batch (application/reports+json), keeping only the CSP violations of the latter.
+func ParseReports(contentType string, body []byte) ([]Report, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "application/csp-report", "application/json":
+		var legacy struct {
+			Report struct {
+				DocumentURI        string `json:"document-uri"`
+				BlockedURI         string `json:"blocked-uri"`
+				EffectiveDirective string `json:"effective-directive"`
+				ViolatedDirective  string `json:"violated-directive"`
+				Disposition        string `json:"disposition"`
+				SourceFile         string `json:"source-file"`
+				LineNumber         int    `json:"line-number"`
+			} `json:"csp-report"`
+		}
+		if err := json.Unmarshal(body, &legacy); err != nil {
+			return nil, err
+		}
+		r := legacy.Report
+		directive := r.EffectiveDirective
+		if directive == "" {
+			directive = r.ViolatedDirective
+		}
+		return []Report{{
+			DocumentURI:        r.DocumentURI,
+			BlockedURI:         r.BlockedURI,
+			EffectiveDirective: directive,
+			Disposition:        r.Disposition,
+			SourceFile:         r.SourceFile,
+			LineNumber:         r.LineNumber,
+		}}, nil
+
+	case "application/reports+json":
+		var batch []struct {
+			Type string `json:"type"`
+			Body struct {
+				DocumentURL        string `json:"documentURL"`
+				BlockedURL         string `json:"blockedURL"`
+				EffectiveDirective string `json:"effectiveDirective"`
+				Disposition        string `json:"disposition"`
+				SourceFile         string `json:"sourceFile"`
+				LineNumber         int    `json:"lineNumber"`
+			} `json:"body"`
+		}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		var reports []Report
+		for _, r := range batch {
+			if r.Type != "csp-violation" {
+				continue
+			}
+			reports = append(reports, Report{
+				DocumentURI:        r.Body.DocumentURL,
+				BlockedURI:         r.Body.BlockedURL,
+				EffectiveDirective: r.Body.EffectiveDirective,
+				Disposition:        r.Body.Disposition,
+				SourceFile:         r.Body.SourceFile,
+				LineNumber:         r.Body.LineNumber,
+			})
+		}
+		return reports, nil
+
+	default:
+		return nil, ErrUnsupportedReport
+	}
+}
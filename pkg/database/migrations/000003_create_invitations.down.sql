@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_invitations_pending_email;
+DROP INDEX IF EXISTS idx_invitations_org_id;
+
+DROP TABLE IF EXISTS invitations;
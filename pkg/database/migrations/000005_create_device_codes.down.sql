@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_device_codes_pending_user_code;
+
+DROP TABLE IF EXISTS device_codes;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS device_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_code_hash VARCHAR(64) UNIQUE NOT NULL,
+    user_code VARCHAR(16) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    scope TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    poll_interval INT NOT NULL,
+    last_polled_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_device_codes_pending_user_code ON device_codes(user_code) WHERE status = 'pending';
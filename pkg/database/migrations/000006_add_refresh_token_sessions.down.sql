@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_session_id;
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS client_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS remember_me;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS session_expires_at;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS session_id;
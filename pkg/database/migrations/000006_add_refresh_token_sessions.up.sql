@@ -0,0 +1,10 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_expires_at TIMESTAMPTZ;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS remember_me BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS client_id VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Existing tokens become single-token sessions
+UPDATE refresh_tokens SET session_expires_at = expires_at WHERE session_expires_at IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN session_expires_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
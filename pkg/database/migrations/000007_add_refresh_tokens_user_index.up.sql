@@ -0,0 +1,2 @@
+-- Session lookups by user for the per-user session limit
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
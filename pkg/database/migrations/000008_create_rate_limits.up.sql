@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(512) NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    count INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (key, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_expires_at ON rate_limits(expires_at);
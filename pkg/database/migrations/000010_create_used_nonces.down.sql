@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_used_nonces_expires_at;
+
+DROP TABLE IF EXISTS used_nonces;
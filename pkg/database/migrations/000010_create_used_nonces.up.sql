@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS used_nonces (
+    key VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_used_nonces_expires_at ON used_nonces(expires_at);
@@ -7,14 +7,15 @@ import (
 	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/config"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func ConnectPostgres(cfg *config.EnvConfig) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.GetDatabaseDSN())
+	connector, err := pq.NewConnector(cfg.GetDatabaseDSN())
 	if err != nil {
 		return nil, fmt.Errorf("db connect failed: %w", err)
 	}
+	db := sql.OpenDB(NewTracedConnector(connector))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/codepnw/go-starter-kit/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracedConnector wraps a driver connector so every statement, transaction begin,
+// commit and rollback gets a client span under the span in its context. Statements run
+// without a span in their context (pool pings, background jobs) are not traced.
+func NewTracedConnector(c driver.Connector) driver.Connector {
+	return &tracedConnector{Connector: c}
+}
+
+type tracedConnector struct {
+	driver.Connector
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver {
+	return &tracedDriver{Driver: c.Connector.Driver()}
+}
+
+type tracedDriver struct {
+	driver.Driver
+}
+
+func (d *tracedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn}, nil
+}
+
+type tracedConn struct {
+	driver.Conn
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	ctx, span := startSQLSpan(ctx, "prepare", query)
+	defer span.End()
+
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	spanCtx, span := startSQLSpan(ctx, "begin", "")
+	defer span.End()
+
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(spanCtx, opts)
+	} else {
+		tx, err = c.Conn.Begin() //nolint:staticcheck // driver without BeginTx
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, ctx: ctx}, nil
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		// database/sql falls back to prepare + exec
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startSQLSpan(ctx, "exec", query)
+	defer span.End()
+
+	res, err := e.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		tracing.RecordError(span, err)
+	}
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startSQLSpan(ctx, "query", query)
+	defer span.End()
+
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		tracing.RecordError(span, err)
+	}
+	return rows, err
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startSQLSpan(ctx, "exec", s.query)
+	defer span.End()
+
+	var (
+		res driver.Result
+		err error
+	)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(values(args)) //nolint:staticcheck // driver without ExecContext
+	}
+	tracing.RecordError(span, err)
+	return res, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startSQLSpan(ctx, "query", s.query)
+	defer span.End()
+
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args)) //nolint:staticcheck // driver without QueryContext
+	}
+	tracing.RecordError(span, err)
+	return rows, err
+}
+
+// tracedTx keeps the caller's BeginTx context so commit and rollback are siblings of
+// the statements run in the transaction.
+type tracedTx struct {
+	driver.Tx
+	ctx context.Context
+}
+
+func (t *tracedTx) Commit() error {
+	_, span := startSQLSpan(t.ctx, "commit", "")
+	defer span.End()
+
+	err := t.Tx.Commit()
+	tracing.RecordError(span, err)
+	return err
+}
+
+func (t *tracedTx) Rollback() error {
+	_, span := startSQLSpan(t.ctx, "rollback", "")
+	defer span.End()
+
+	err := t.Tx.Rollback()
+	tracing.RecordError(span, err)
+	return err
+}
+
+// startSQLSpan : query text only, arguments are never recorded
+func startSQLSpan(ctx context.Context, op, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	attrs := []attribute.KeyValue{semconv.DBSystemNamePostgreSQL, semconv.DBOperationName(op)}
+	if query != "" {
+		attrs = append(attrs, semconv.DBQueryText(query))
+	}
+	return tracing.Tracer().Start(ctx, "sql."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	return vals
+}
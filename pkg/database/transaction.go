@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+
+	"github.com/codepnw/go-starter-kit/pkg/tracing"
 )
 
 //go:generate mockgen -source=transaction.go -destination=transaction_mock.go -package=database
@@ -20,6 +22,12 @@ func NewDBTransaction(db *sql.DB) TxManager {
 }
 
 func (t *txManager) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "db.WithTx")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -0,0 +1,277 @@
+// Package dpop verifies RFC 9449 DPoP proofs: a JWT signed by the client's key,
+// with the public key in the "jwk" header, bound to one HTTP request.
+//
+// Tokens issued to the client carry the key's RFC 7638 thumbprint as cnf.jkt,
+// so only the holder of the private key can use them.
+package dpop
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	HeaderName = "DPoP"
+	ProofType  = "dpop+jwt"
+)
+
+// SupportedAlgs : asymmetric algorithms only, the key must prove possession
+var SupportedAlgs = []string{"ES256", "RS256", "PS256"}
+
+var (
+	ErrInvalidProof      = errors.New("invalid dpop proof")
+	ErrInvalidKey        = errors.New("invalid dpop proof key")
+	ErrMethodMismatch    = errors.New("dpop proof htm mismatch")
+	ErrURLMismatch       = errors.New("dpop proof htu mismatch")
+	ErrProofExpired      = errors.New("dpop proof iat outside allowed window")
+	ErrTokenHashMismatch = errors.New("dpop proof ath mismatch")
+)
+
+// Proof is a verified DPoP proof.
+type Proof struct {
+	JKT      string
+	JTI      string
+	HTM      string
+	HTU      string
+	IssuedAt time.Time
+}
+
+type proofClaims struct {
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// JWK : public members of an EC (P-256) or RSA key
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+}
+
+// Verify checks the proof signature, that it was made for method and url, that iat is
+// within window, and, when accessToken is set, the ath hash. Replay (jti) is left to the caller.
+func Verify(proof, method, url, accessToken string, window time.Duration) (*Proof, error) {
+	var jkt string
+	token, err := jwt.ParseWithClaims(proof, &proofClaims{}, func(t *jwt.Token) (any, error) {
+		if typ, _ := t.Header["typ"].(string); typ != ProofType {
+			return nil, ErrInvalidProof
+		}
+		key, err := parseJWKHeader(t.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+		if jkt, err = key.Thumbprint(); err != nil {
+			return nil, err
+		}
+		return key.PublicKey()
+	}, jwt.WithValidMethods(SupportedAlgs), jwt.WithoutClaimsValidation())
+	if err != nil {
+		if errors.Is(err, ErrInvalidKey) {
+			return nil, ErrInvalidKey
+		}
+		return nil, ErrInvalidProof
+	}
+
+	claims, ok := token.Claims.(*proofClaims)
+	if !ok || claims.ID == "" || claims.IssuedAt == nil {
+		return nil, ErrInvalidProof
+	}
+
+	if claims.HTM != method {
+		return nil, ErrMethodMismatch
+	}
+	if claims.HTU != url {
+		return nil, ErrURLMismatch
+	}
+	if skew := time.Since(claims.IssuedAt.Time); skew > window || skew < -window {
+		return nil, ErrProofExpired
+	}
+	if accessToken != "" && claims.ATH != TokenHash(accessToken) {
+		return nil, ErrTokenHashMismatch
+	}
+
+	return &Proof{
+		JKT:      jkt,
+		JTI:      claims.ID,
+		HTM:      claims.HTM,
+		HTU:      claims.HTU,
+		IssuedAt: claims.IssuedAt.Time,
+	}, nil
+}
+
+// NewProof creates a proof signed with an ES256 key. Used by clients and tests.
+func NewProof(key *ecdsa.PrivateKey, method, url, accessToken string) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	claims := &proofClaims{
+		HTM: method,
+		HTU: url,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       hex.EncodeToString(jti),
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if accessToken != "" {
+		claims.ATH = TokenHash(accessToken)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = ProofType
+	token.Header["jwk"] = ECPublicJWK(&key.PublicKey)
+	return token.SignedString(key)
+}
+
+// RequestURL is the htu of a request: scheme, host and path, without query or fragment.
+// X-Forwarded-Proto is only read when the request came through a trusted proxy, anyone
+// else could set it.
+func RequestURL(r *http.Request, fromTrustedProxy bool) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); fromTrustedProxy && (proto == "http" || proto == "https") {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.EscapedPath()
+}
+
+// TokenHash is the ath claim: base64url(SHA-256(access token)).
+func TokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ------------------ JWK -------------------
+
+// ECPublicJWK returns the JWK of a P-256 public key.
+func ECPublicJWK(pub *ecdsa.PublicKey) *JWK {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return &JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+// Thumbprint : RFC 7638, SHA-256 over the required members in lexicographic order
+func (k *JWK) Thumbprint() (string, error) {
+	var members any
+	switch k.Kty {
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y}
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	default:
+		return "", ErrInvalidKey
+	}
+
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// PublicKey decodes the JWK into an *ecdsa.PublicKey or *rsa.PublicKey.
+func (k *JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, ErrInvalidKey
+		}
+		x, err := decodeFixed(k.X, 32)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeFixed(k.Y, 32)
+		if err != nil {
+			return nil, err
+		}
+		// Rejects points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, ErrInvalidKey
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil || len(n) < 256 {
+			return nil, ErrInvalidKey
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidKey
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	default:
+		return nil, ErrInvalidKey
+	}
+}
+
+func parseJWKHeader(raw any) (*JWK, error) {
+	if raw == nil {
+		return nil, ErrInvalidKey
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	key := new(JWK)
+	if err := json.Unmarshal(b, key); err != nil {
+		return nil, ErrInvalidKey
+	}
+	// A private key in the header is a client bug we must not accept
+	if key.D != "" {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+func decodeFixed(s string, size int) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) != size {
+		return nil, ErrInvalidKey
+	}
+	return b, nil
+}
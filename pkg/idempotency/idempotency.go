@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	HeaderName = "Idempotency-Key"
+	// ReplayedHeaderName is set to "true" on responses replayed from the store
+	ReplayedHeaderName = "Idempotent-Replayed"
+
+	// MaxKeyLength : clients send a UUID, anything much longer is a mistake
+	MaxKeyLength = 255
+)
+
+// ErrLockLost : the request ran past its lock and a retry took the key over, so what it
+// had to store or forget belongs to the retry now.
+var ErrLockLost = errors.New("idempotency key lock lost")
+
+// Response : what a retry gets back, byte for byte.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record : a key as stored. Response is nil while the first request is still running.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+	// LockedUntil : after this an unfinished request is considered dead
+	LockedUntil time.Time
+}
+
+// Store keeps one record per key until it expires. Lock is the only way in, so two
+// requests with the same key never both run the handler.
+type Store interface {
+	// Lock claims key for a new request and returns its lock token. If the key is taken it
+	// returns the existing record and an empty token. Expired records are replaced, and an
+	// unfinished record past its lock is taken over by a request with the same fingerprint.
+	Lock(ctx context.Context, key, fingerprint string, lock, ttl time.Duration) (*Record, string, error)
+	// Complete stores the response of the request holding the lock token. It returns
+	// ErrLockLost if the key was taken over since.
+	Complete(ctx context.Context, key, token string, res Response) error
+	// Unlock forgets the key so the client can retry, e.g. after a 5xx. It returns
+	// ErrLockLost if the key was taken over since, and leaves it to its new owner.
+	Unlock(ctx context.Context, key, token string) error
+}
+
+// newLockToken : tells the request holding a key apart from one that took it over
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Fingerprint identifies the request a key was first used with.
+func Fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
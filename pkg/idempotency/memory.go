@@ -0,0 +1,97 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	record  Record
+	token   string
+	expires time.Time
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore returns a process-local Store, for tests and single instance setups.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStore) Lock(ctx context.Context, key, fingerprint string, lock, ttl time.Duration) (*Record, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		abandoned := e.record.Response == nil && !now.Before(e.record.LockedUntil)
+		if !abandoned || e.record.Fingerprint != fingerprint {
+			record := e.record
+			return &record, "", nil
+		}
+	}
+
+	token, err := newLockToken()
+	if err != nil {
+		return nil, "", err
+	}
+	s.entries[key] = &entry{
+		record: Record{
+			Fingerprint: fingerprint,
+			LockedUntil: now.Add(lock),
+		},
+		token:   token,
+		expires: now.Add(ttl),
+	}
+	return nil, token, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, key, token string, res Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.token != token || e.record.Response != nil {
+		return ErrLockLost
+	}
+	e.record.Response = &res
+	e.record.LockedUntil = time.Time{}
+	e.token = ""
+	return nil
+}
+
+func (s *memoryStore) Unlock(ctx context.Context, key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.token != token || e.record.Response != nil {
+		return ErrLockLost
+	}
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep : drop expired records at most once a minute
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	for k, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,56 @@
+package idempotency_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLockTakeover : a request that outlives its lock cannot touch the record of the
+// retry that took the key over.
+func TestLockTakeover(t *testing.T) {
+	ctx := context.Background()
+
+	stores := map[string]idempotency.Store{
+		"memory": idempotency.NewMemoryStore(),
+		"sealed": idempotency.NewSealedStore(idempotency.NewMemoryStore()),
+	}
+
+	for name, store := range stores {
+		key := "POST /orgs|user:1|key-1"
+		fingerprint := idempotency.Fingerprint(http.MethodPost, "/orgs", []byte(`{}`))
+
+		// A zero lock is past at once, so the retry takes over
+		_, slow, err := store.Lock(ctx, key, fingerprint, 0, time.Hour)
+		require.NoError(t, err, name)
+		_, retry, err := store.Lock(ctx, key, fingerprint, time.Minute, time.Hour)
+		require.NoError(t, err, name)
+		require.NotEmpty(t, retry, name)
+		require.NotEqual(t, slow, retry, name)
+
+		// The slow request can neither free nor fill the retry's record
+		assert.ErrorIs(t, store.Unlock(ctx, key, slow), idempotency.ErrLockLost, name)
+		assert.ErrorIs(t, store.Complete(ctx, key, slow, idempotency.Response{Status: http.StatusCreated, Body: []byte("slow")}), idempotency.ErrLockLost, name)
+
+		record, token, err := store.Lock(ctx, key, fingerprint, time.Minute, time.Hour)
+		require.NoError(t, err, name)
+		assert.Empty(t, token, name)
+		assert.Nil(t, record.Response, "%s: still running", name)
+
+		// The retry finishes, and only once
+		res := idempotency.Response{Status: http.StatusCreated, Body: []byte("retry")}
+		require.NoError(t, store.Complete(ctx, key, retry, res), name)
+		assert.ErrorIs(t, store.Complete(ctx, key, retry, res), idempotency.ErrLockLost, name)
+		assert.ErrorIs(t, store.Unlock(ctx, key, retry), idempotency.ErrLockLost, "%s: completed", name)
+
+		record, _, err = store.Lock(ctx, key, fingerprint, time.Minute, time.Hour)
+		require.NoError(t, err, name)
+		require.NotNil(t, record.Response, name)
+		assert.Equal(t, []byte("retry"), record.Response.Body, name)
+	}
+}
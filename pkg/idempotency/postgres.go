@@ -0,0 +1,173 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sweepInterval : how often an instance deletes expired rows
+const sweepInterval = time.Minute
+
+type postgresStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewPostgresStore returns a Store shared by every instance using the same database
+// (table idempotency_keys, see migrations).
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (s *postgresStore) Lock(ctx context.Context, key, fingerprint string, lock, ttl time.Duration) (*Record, string, error) {
+	now := s.now()
+	s.sweep(ctx, now)
+
+	token, err := newLockToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The primary key is the lock: only one insert (or takeover) wins
+	query := `
+		INSERT INTO idempotency_keys (key, fingerprint, lock_token, locked_until, expires_at)
+		VALUES ($1, $2, $6, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status_code = NULL,
+			headers = NULL,
+			body = NULL,
+			lock_token = EXCLUDED.lock_token,
+			locked_until = EXCLUDED.locked_until,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= $5
+			OR (
+				idempotency_keys.status_code IS NULL
+				AND idempotency_keys.locked_until <= $5
+				AND idempotency_keys.fingerprint = EXCLUDED.fingerprint
+			)
+		RETURNING key
+	`
+	// The winner may unlock between our insert and select, then try again once
+	for range 2 {
+		var claimed string
+		err := s.db.QueryRowContext(ctx, query, key, fingerprint, now.Add(lock), now.Add(ttl), now, token).Scan(&claimed)
+		if err == nil {
+			return nil, token, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, "", err
+		}
+
+		record, err := s.get(ctx, key)
+		if err == nil {
+			return record, "", nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, "", err
+		}
+	}
+	return nil, "", errors.New("idempotency key contended")
+}
+
+func (s *postgresStore) get(ctx context.Context, key string) (*Record, error) {
+	query := `
+		SELECT fingerprint, status_code, headers, body, locked_until
+		FROM idempotency_keys WHERE key = $1
+	`
+	var (
+		record      Record
+		status      sql.NullInt64
+		headers     []byte
+		body        []byte
+		lockedUntil sql.NullTime
+	)
+	if err := s.db.QueryRowContext(ctx, query, key).Scan(
+		&record.Fingerprint,
+		&status,
+		&headers,
+		&body,
+		&lockedUntil,
+	); err != nil {
+		return nil, err
+	}
+
+	record.LockedUntil = lockedUntil.Time
+	if status.Valid {
+		res := &Response{Status: int(status.Int64), Body: body}
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &res.Header); err != nil {
+				return nil, err
+			}
+		}
+		record.Response = res
+	}
+	return &record, nil
+}
+
+func (s *postgresStore) Complete(ctx context.Context, key, token string, res Response) error {
+	headers, err := json.Marshal(res.Header)
+	if err != nil {
+		return err
+	}
+
+	// Only the lock holder writes, a request that took the key over has another token
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $2, headers = $3, body = $4, lock_token = NULL, locked_until = NULL
+		WHERE key = $1 AND lock_token = $5 AND status_code IS NULL
+	`
+	result, err := s.db.ExecContext(ctx, query, key, res.Status, headers, res.Body, token)
+	if err != nil {
+		return err
+	}
+	return lockHeld(result)
+}
+
+func (s *postgresStore) Unlock(ctx context.Context, key, token string) error {
+	query := `DELETE FROM idempotency_keys WHERE key = $1 AND lock_token = $2 AND status_code IS NULL`
+	result, err := s.db.ExecContext(ctx, query, key, token)
+	if err != nil {
+		return err
+	}
+	return lockHeld(result)
+}
+
+// lockHeld : ErrLockLost when the statement matched no row
+func lockHeld(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// sweep : delete expired keys at most once per sweepInterval, best effort
+func (s *postgresStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	query := `DELETE FROM idempotency_keys WHERE expires_at < $1`
+	if _, err := s.db.ExecContext(ctx, query, now); err != nil {
+		slog.WarnContext(ctx, "idempotency sweep failed", slog.String("error", err.Error()))
+	}
+}
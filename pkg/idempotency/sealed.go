@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var errSealedResponse = errors.New("stored response cannot be decrypted")
+
+// sealedPayload : what is encrypted into Response.Body
+type sealedPayload struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+type sealedStore struct {
+	store Store
+}
+
+// NewSealedStore wraps store so what it keeps is useless without the Idempotency-Key:
+// records are filed under a keyed hash of the key, the request fingerprint is keyed too
+// and the response headers and body are encrypted (AES-GCM) with a key derived from it.
+// Responses carrying tokens or client secrets can then be replayed without sitting in
+// the database in plaintext. Keys must be random (a UUID), a guessable key is as weak
+// as the plaintext.
+func NewSealedStore(store Store) Store {
+	return &sealedStore{store: store}
+}
+
+func (s *sealedStore) Lock(ctx context.Context, key, fingerprint string, lock, ttl time.Duration) (*Record, string, error) {
+	sealedFingerprint := hex.EncodeToString(derive(key, "fingerprint:"+fingerprint))
+
+	record, token, err := s.store.Lock(ctx, storeKey(key), sealedFingerprint, lock, ttl)
+	if err != nil || record == nil {
+		return record, token, err
+	}
+
+	if record.Fingerprint == sealedFingerprint {
+		record.Fingerprint = fingerprint
+	}
+	if record.Response != nil {
+		res, err := open(key, record.Response)
+		if err != nil {
+			return nil, "", err
+		}
+		record.Response = res
+	}
+	return record, token, nil
+}
+
+func (s *sealedStore) Complete(ctx context.Context, key, token string, res Response) error {
+	sealed, err := seal(key, res)
+	if err != nil {
+		return err
+	}
+	return s.store.Complete(ctx, storeKey(key), token, *sealed)
+}
+
+func (s *sealedStore) Unlock(ctx context.Context, key, token string) error {
+	return s.store.Unlock(ctx, storeKey(key), token)
+}
+
+func storeKey(key string) string {
+	return hex.EncodeToString(derive(key, "id"))
+}
+
+// derive : a 32 byte value bound to key, one per purpose
+func derive(key, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(derive(key, "response"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal : the status stays readable, headers and body become nonce || ciphertext
+func seal(key string, res Response) (*Response, error) {
+	plaintext, err := json.Marshal(sealedPayload{Header: res.Header, Body: res.Body})
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &Response{Status: res.Status, Body: aead.Seal(nonce, nonce, plaintext, nil)}, nil
+}
+
+func open(key string, sealed *Response) (*Response, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Body) < aead.NonceSize() {
+		return nil, errSealedResponse
+	}
+
+	nonce, ciphertext := sealed.Body[:aead.NonceSize()], sealed.Body[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errSealedResponse
+	}
+
+	var payload sealedPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return nil, err
+	}
+	return &Response{Status: sealed.Status, Header: payload.Header, Body: payload.Body}, nil
+}
@@ -0,0 +1,87 @@
+package idempotency_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spyStore records what reaches the underlying store.
+type spyStore struct {
+	idempotency.Store
+	keys         []string
+	fingerprints []string
+	responses    []idempotency.Response
+}
+
+func (s *spyStore) Lock(ctx context.Context, key, fingerprint string, lock, ttl time.Duration) (*idempotency.Record, string, error) {
+	s.keys = append(s.keys, key)
+	s.fingerprints = append(s.fingerprints, fingerprint)
+	return s.Store.Lock(ctx, key, fingerprint, lock, ttl)
+}
+
+func (s *spyStore) Complete(ctx context.Context, key, token string, res idempotency.Response) error {
+	s.responses = append(s.responses, res)
+	return s.Store.Complete(ctx, key, token, res)
+}
+
+func TestSealedStore(t *testing.T) {
+	ctx := context.Background()
+	spy := &spyStore{Store: idempotency.NewMemoryStore()}
+	store := idempotency.NewSealedStore(spy)
+
+	key := "POST /auth/register|anon|7f1c3c1e-5d0e-4c55-9a57-0f3c1e6f2b11"
+	fingerprint := idempotency.Fingerprint(http.MethodPost, "/auth/register", []byte(`{"password":"hunter22"}`))
+	res := idempotency.Response{
+		Status: http.StatusCreated,
+		Header: http.Header{"Location": {"/users/1"}},
+		Body:   []byte(`{"access_token":"secret-access-token"}`),
+	}
+
+	record, token, err := store.Lock(ctx, key, fingerprint, time.Minute, time.Hour)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	assert.Nil(t, record)
+	require.NoError(t, store.Complete(ctx, key, token, res))
+
+	// Nothing readable reaches the underlying store
+	require.Len(t, spy.responses, 1)
+	assert.NotContains(t, spy.keys[0], "7f1c3c1e")
+	assert.NotEqual(t, fingerprint, spy.fingerprints[0])
+	assert.Equal(t, http.StatusCreated, spy.responses[0].Status)
+	assert.Empty(t, spy.responses[0].Header)
+	assert.NotContains(t, string(spy.responses[0].Body), "secret-access-token")
+	assert.NotContains(t, string(spy.responses[0].Body), "/users/1")
+
+	// A retry gets the response back
+	record, token, err = store.Lock(ctx, key, fingerprint, time.Minute, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, token)
+	assert.Equal(t, fingerprint, record.Fingerprint)
+	assert.Equal(t, &res, record.Response)
+
+	// Another payload is told apart
+	other := idempotency.Fingerprint(http.MethodPost, "/auth/register", []byte(`{"password":"other"}`))
+	record, token, err = store.Lock(ctx, key, other, time.Minute, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, token)
+	assert.NotEqual(t, other, record.Fingerprint)
+
+	// Keys differing in case or suffix get their own record
+	record, token, err = store.Lock(ctx, strings.ToUpper(key), fingerprint, time.Minute, time.Hour)
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Nil(t, record)
+
+	// Unlock reaches the same record
+	require.NoError(t, store.Unlock(ctx, strings.ToUpper(key), token))
+	_, token, err = store.Lock(ctx, strings.ToUpper(key), fingerprint, time.Minute, time.Hour)
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+}
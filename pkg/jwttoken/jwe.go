@@ -0,0 +1,107 @@
+package jwttoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// ErrTokenDecryption : the token is not a JWE for any of our encryption keys
+var ErrTokenDecryption = errors.New("token cannot be decrypted")
+
+// contentEncryption : the only content encryption accepted
+const contentEncryption = jose.A256GCM
+
+// encrypter wraps signed tokens in a JWE (nested JWT, RFC 7519 section 5.2),
+// so claims like Email are not readable by proxies or logs.
+type encrypter struct {
+	alg    jose.KeyAlgorithm
+	keys   map[string][]byte
+	active Key
+}
+
+// newEncrypter : keys are "kid=base64(32 bytes)", the first one encrypts, all of them decrypt.
+// alg is "dir" (the key is the content key) or "A256KW" (the key wraps a random content key).
+func newEncrypter(alg string, list []string) (*encrypter, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	keyAlg := jose.KeyAlgorithm(alg)
+	if keyAlg != jose.DIRECT && keyAlg != jose.A256KW {
+		return nil, fmt.Errorf("unsupported encryption algorithm %q", alg)
+	}
+
+	keys, err := ParseKeys(list, decodeEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &encrypter{
+		alg:    keyAlg,
+		keys:   make(map[string][]byte, len(keys)),
+		active: keys[0],
+	}
+	for _, k := range keys {
+		e.keys[k.ID] = k.Secret
+	}
+	return e, nil
+}
+
+func (e *encrypter) encrypt(signed string) (string, error) {
+	opts := (&jose.EncrypterOptions{}).WithType("JWT").WithContentType("JWT")
+	enc, err := jose.NewEncrypter(contentEncryption, jose.Recipient{
+		Algorithm: e.alg,
+		Key:       e.active.Secret,
+		KeyID:     e.active.ID,
+	}, opts)
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := enc.Encrypt([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+	return obj.CompactSerialize()
+}
+
+func (e *encrypter) decrypt(token string) (string, error) {
+	obj, err := jose.ParseEncrypted(token, []jose.KeyAlgorithm{e.alg}, []jose.ContentEncryption{contentEncryption})
+	if err != nil {
+		return "", ErrTokenDecryption
+	}
+
+	key, ok := e.keys[obj.Header.KeyID]
+	if !ok {
+		return "", ErrTokenDecryption
+	}
+	if cty, _ := obj.Header.ExtraHeaders[jose.HeaderContentType].(string); cty != "JWT" {
+		return "", ErrTokenDecryption
+	}
+
+	signed, err := obj.Decrypt(key)
+	if err != nil {
+		return "", ErrTokenDecryption
+	}
+	return string(signed), nil
+}
+
+// isEncrypted : JWE compact serialization has five parts, JWS has three
+func isEncrypted(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+func decodeEncryptionKey(s string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes")
+	}
+	return b, nil
+}
@@ -3,6 +3,7 @@ package jwttoken
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/config"
@@ -12,86 +13,297 @@ import (
 
 //go:generate mockgen -source=jwt.go -destination=jwt_mock.go -package=jwttoken
 type JWTToken interface {
-	GenerateAccessToken(u *user.User) (string, error)
-	GenerateRefreshToken(u *user.User) (string, error)
-	VerifyAccessToken(tokenStr string) (*UserClaims, error)
-	VerifyRefreshToken(tokenStr string) (*UserClaims, error)
+	GenerateAccessToken(u *user.User, opts ...TokenOption) (string, error)
+	GenerateRefreshToken(u *user.User, opts ...TokenOption) (string, error)
+	VerifyAccessToken(tokenStr string, opts ...VerifyOption) (*UserClaims, error)
+	VerifyRefreshToken(tokenStr string, opts ...VerifyOption) (*UserClaims, error)
 }
 
+// Verification errors, from most to least specific
+var (
+	ErrTokenMalformed     = errors.New("token is malformed")
+	ErrTokenSignature     = errors.New("token signature is invalid")
+	ErrTokenExpired       = errors.New("token is expired")
+	ErrTokenNotValidYet   = errors.New("token is not valid yet")
+	ErrTokenInvalidClaims = errors.New("token has invalid claims")
+	// ErrAudienceRestricted : the token is meant for another service
+	ErrAudienceRestricted = errors.New("token is restricted to another audience")
+)
+
+// ErrDPoPRequired : the client must present a DPoP proof to get tokens
+var ErrDPoPRequired = errors.New("dpop proof required for this client")
+
+// signingMethod : the only algorithm accepted on verification
+var signingMethod = jwt.SigningMethodHS256
+
 type token struct {
-	appName    string
-	secretKey  string
-	refreshKey string
+	cfg         *config.JWTConfig
+	appName     string
+	audience    string
+	leeway      time.Duration
+	accessKeys  *signingKeys
+	refreshKeys *signingKeys
+	dpopClients []string
+	encrypter   *encrypter
 }
 
-func NewJWTToken(appName, secretKey, refreshKey string) (JWTToken, error) {
-	if secretKey == "" || refreshKey == "" {
-		return nil, errors.New("secret & refresh key is required")
+func NewJWTToken(cfg *config.JWTConfig) (JWTToken, error) {
+	accessKeys, err := newSigningKeys(cfg.AccessKeys, cfg.SecretKey, cfg.AccessRetiredKeys)
+	if err != nil {
+		return nil, fmt.Errorf("jwt access keys: %w", err)
+	}
+	refreshKeys, err := newSigningKeys(cfg.RefreshKeys, cfg.RefreshKey, cfg.RefreshRetiredKeys)
+	if err != nil {
+		return nil, fmt.Errorf("jwt refresh keys: %w", err)
+	}
+
+	audience := cfg.Audience
+	if audience == "" {
+		audience = cfg.AppName
+	}
+
+	encrypter, err := newEncrypter(cfg.EncryptionAlg, cfg.EncryptionKeys)
+	if err != nil {
+		return nil, fmt.Errorf("jwt encryption keys: %w", err)
 	}
 	return &token{
-		appName:    appName,
-		secretKey:  secretKey,
-		refreshKey: refreshKey,
+		cfg:         cfg,
+		appName:     cfg.AppName,
+		audience:    audience,
+		leeway:      cfg.Leeway,
+		accessKeys:  accessKeys,
+		refreshKeys: refreshKeys,
+		dpopClients: cfg.DPoPRequiredClients,
+		encrypter:   encrypter,
 	}, nil
 }
 
 type UserClaims struct {
-	UserID string
-	Email  string
+	UserID  string
+	Email   string
+	Role    string `json:"role,omitempty"`
+	OrgID   string `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+	Act     *Actor `json:"act,omitempty"`
+
+	ClientID string        `json:"client_id,omitempty"`
+	Cnf      *Confirmation `json:"cnf,omitempty"`
 	*jwt.RegisteredClaims
 }
 
+// Confirmation : RFC 7800 "cnf" claim, JKT binds the token to a DPoP key (RFC 9449)
+type Confirmation struct {
+	JKT string `json:"jkt"`
+}
+
+// Actor : RFC 8693 "act" claim, the party acting on behalf of the subject
+type Actor struct {
+	Sub string `json:"sub"`
+}
+
+// TokenOption customizes the claims of a generated token.
+type TokenOption func(c *UserClaims)
+
+// WithOrganization scopes the token to an organization (tenant).
+func WithOrganization(orgID, role string) TokenOption {
+	return func(c *UserClaims) {
+		c.OrgID = orgID
+		c.OrgRole = role
+	}
+}
+
+// WithAudience restricts the token to the given audiences.
+func WithAudience(aud ...string) TokenOption {
+	return func(c *UserClaims) {
+		c.Audience = aud
+	}
+}
+
+// WithScope limits the token to a space-delimited list of scopes.
+func WithScope(scope string) TokenOption {
+	return func(c *UserClaims) {
+		c.Scope = scope
+	}
+}
+
+// WithActor records the service acting on behalf of the user.
+func WithActor(sub string) TokenOption {
+	return func(c *UserClaims) {
+		c.Act = &Actor{Sub: sub}
+	}
+}
+
+// WithClient records the client the token was issued to.
+func WithClient(clientID string) TokenOption {
+	return func(c *UserClaims) {
+		c.ClientID = clientID
+	}
+}
+
+// WithConfirmation binds the token to the DPoP key with the given thumbprint.
+func WithConfirmation(jkt string) TokenOption {
+	return func(c *UserClaims) {
+		c.Cnf = &Confirmation{JKT: jkt}
+	}
+}
+
+// WithTTL overrides the configured token lifetime.
+func WithTTL(d time.Duration) TokenOption {
+	return func(c *UserClaims) {
+		c.ExpiresAt = jwt.NewNumericDate(c.IssuedAt.Add(d))
+	}
+}
+
+// VerifyOption customizes token verification.
+type VerifyOption func(v *verifyOptions)
+
+type verifyOptions struct {
+	audiences []string
+}
+
+// WithExpectedAudience accepts only tokens issued for aud, e.g. in a downstream
+// service. By default the token must be issued for this API's audience.
+func WithExpectedAudience(aud string) VerifyOption {
+	return func(v *verifyOptions) {
+		v.audiences = []string{aud}
+	}
+}
+
+// AlsoAcceptAudience accepts tokens issued for aud besides the expected audience, e.g.
+// a single-purpose token on the one route it was issued for.
+func AlsoAcceptAudience(aud string) VerifyOption {
+	return func(v *verifyOptions) {
+		v.audiences = append(v.audiences, aud)
+	}
+}
+
 // ------------- Generate Token ----------------
 
-func (j *token) GenerateAccessToken(u *user.User) (string, error) {
-	return j.generateToken(j.secretKey, u, config.AccessTokenDuration)
+func (j *token) GenerateAccessToken(u *user.User, opts ...TokenOption) (string, error) {
+	return j.generateToken(j.accessKeys, u, j.cfg.AccessTTL, opts...)
 }
 
-func (j *token) GenerateRefreshToken(u *user.User) (string, error) {
-	return j.generateToken(j.refreshKey, u, config.RefreshTokenDuration)
+func (j *token) GenerateRefreshToken(u *user.User, opts ...TokenOption) (string, error) {
+	return j.generateToken(j.refreshKeys, u, j.cfg.RefreshTTL, opts...)
 }
 
-func (j *token) generateToken(key string, u *user.User, duration time.Duration) (string, error) {
+// generateToken : ttl is looked up by client once options are applied, unless WithTTL was given
+func (j *token) generateToken(keys *signingKeys, u *user.User, ttl func(clientID string) time.Duration, opts ...TokenOption) (string, error) {
 	claims := &UserClaims{
 		UserID: u.ID,
 		Email:  u.Email,
+		Role:   u.Role,
 		RegisteredClaims: &jwt.RegisteredClaims{
-			Subject:   u.ID,
-			Issuer:    j.appName,
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+			Subject:  u.ID,
+			Issuer:   j.appName,
+			Audience: jwt.ClaimStrings{j.audience},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	for _, opt := range opts {
+		opt(claims)
+	}
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(claims.IssuedAt.Add(ttl(claims.ClientID)))
+	}
+	if claims.Cnf == nil && claims.ClientID != "" && slices.Contains(j.dpopClients, claims.ClientID) {
+		return "", ErrDPoPRequired
+	}
+	token := jwt.NewWithClaims(signingMethod, claims)
+	if keys.active.ID != legacyKeyID {
+		token.Header["kid"] = keys.active.ID
+	}
 
-	ss, err := token.SignedString([]byte(key))
+	ss, err := token.SignedString(keys.active.Secret)
 	if err != nil {
 		return "", fmt.Errorf("sign token failed: %w", err)
 	}
+
+	// Signed, then encrypted
+	if j.encrypter != nil {
+		if ss, err = j.encrypter.encrypt(ss); err != nil {
+			return "", fmt.Errorf("encrypt token failed: %w", err)
+		}
+	}
 	return ss, nil
 }
 
 // ------------- Verify Token ----------------
 
-func (j *token) VerifyAccessToken(tokenStr string) (*UserClaims, error) {
-	return j.verifyToken(j.secretKey, tokenStr)
+func (j *token) VerifyAccessToken(tokenStr string, opts ...VerifyOption) (*UserClaims, error) {
+	return j.verifyToken(j.accessKeys, tokenStr, opts...)
 }
 
-func (j *token) VerifyRefreshToken(tokenStr string) (*UserClaims, error) {
-	return j.verifyToken(j.refreshKey, tokenStr)
+func (j *token) VerifyRefreshToken(tokenStr string, opts ...VerifyOption) (*UserClaims, error) {
+	return j.verifyToken(j.refreshKeys, tokenStr, opts...)
 }
 
-func (j *token) verifyToken(key, tokenStr string) (*UserClaims, error) {
+func (j *token) verifyToken(keys *signingKeys, tokenStr string, opts ...VerifyOption) (*UserClaims, error) {
+	v := &verifyOptions{audiences: []string{j.audience}}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	// Plain signed tokens are still accepted, e.g. issued before encryption was enabled
+	if isEncrypted(tokenStr) {
+		if j.encrypter == nil {
+			return nil, ErrTokenDecryption
+		}
+		signed, err := j.encrypter.decrypt(tokenStr)
+		if err != nil {
+			return nil, err
+		}
+		tokenStr = signed
+	}
+
 	token, err := jwt.ParseWithClaims(tokenStr, &UserClaims{}, func(t *jwt.Token) (any, error) {
-		return []byte(key), nil
-	})
+		if t.Method != signingMethod {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		// Unknown and retired keys fail like a bad signature
+		kid, _ := t.Header["kid"].(string)
+		key, ok := keys.lookup(kid)
+		if !ok {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{signingMethod.Alg()}),
+		jwt.WithIssuer(j.appName),
+		jwt.WithAudience(v.audiences...),
+		jwt.WithLeeway(j.leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("parse token failed: %w", err)
+		return nil, verifyError(err)
 	}
 
 	claims, ok := token.Claims.(*UserClaims)
 	if !ok {
 		return nil, fmt.Errorf("type assertion claims failed")
 	}
+	if claims.IssuedAt == nil {
+		return nil, ErrTokenInvalidClaims
+	}
 	return claims, nil
 }
+
+// verifyError maps golang-jwt errors to the typed errors of this package.
+func verifyError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenSignature
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrAudienceRestricted
+	default:
+		return fmt.Errorf("%w: %v", ErrTokenInvalidClaims, err)
+	}
+}
@@ -35,61 +35,81 @@ func (m *MockJWTToken) EXPECT() *MockJWTTokenMockRecorder {
 }
 
 // GenerateAccessToken mocks base method.
-func (m *MockJWTToken) GenerateAccessToken(u *user.User) (string, error) {
+func (m *MockJWTToken) GenerateAccessToken(u *user.User, opts ...TokenOption) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateAccessToken", u)
+	varargs := []interface{}{u}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GenerateAccessToken", varargs...)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateAccessToken indicates an expected call of GenerateAccessToken.
-func (mr *MockJWTTokenMockRecorder) GenerateAccessToken(u interface{}) *gomock.Call {
+func (mr *MockJWTTokenMockRecorder) GenerateAccessToken(u interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAccessToken", reflect.TypeOf((*MockJWTToken)(nil).GenerateAccessToken), u)
+	varargs := append([]interface{}{u}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAccessToken", reflect.TypeOf((*MockJWTToken)(nil).GenerateAccessToken), varargs...)
 }
 
 // GenerateRefreshToken mocks base method.
-func (m *MockJWTToken) GenerateRefreshToken(u *user.User) (string, error) {
+func (m *MockJWTToken) GenerateRefreshToken(u *user.User, opts ...TokenOption) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateRefreshToken", u)
+	varargs := []interface{}{u}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GenerateRefreshToken", varargs...)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateRefreshToken indicates an expected call of GenerateRefreshToken.
-func (mr *MockJWTTokenMockRecorder) GenerateRefreshToken(u interface{}) *gomock.Call {
+func (mr *MockJWTTokenMockRecorder) GenerateRefreshToken(u interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateRefreshToken", reflect.TypeOf((*MockJWTToken)(nil).GenerateRefreshToken), u)
+	varargs := append([]interface{}{u}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateRefreshToken", reflect.TypeOf((*MockJWTToken)(nil).GenerateRefreshToken), varargs...)
 }
 
 // VerifyAccessToken mocks base method.
-func (m *MockJWTToken) VerifyAccessToken(tokenStr string) (*UserClaims, error) {
+func (m *MockJWTToken) VerifyAccessToken(tokenStr string, opts ...VerifyOption) (*UserClaims, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "VerifyAccessToken", tokenStr)
+	varargs := []interface{}{tokenStr}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "VerifyAccessToken", varargs...)
 	ret0, _ := ret[0].(*UserClaims)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // VerifyAccessToken indicates an expected call of VerifyAccessToken.
-func (mr *MockJWTTokenMockRecorder) VerifyAccessToken(tokenStr interface{}) *gomock.Call {
+func (mr *MockJWTTokenMockRecorder) VerifyAccessToken(tokenStr interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAccessToken", reflect.TypeOf((*MockJWTToken)(nil).VerifyAccessToken), tokenStr)
+	varargs := append([]interface{}{tokenStr}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAccessToken", reflect.TypeOf((*MockJWTToken)(nil).VerifyAccessToken), varargs...)
 }
 
 // VerifyRefreshToken mocks base method.
-func (m *MockJWTToken) VerifyRefreshToken(tokenStr string) (*UserClaims, error) {
+func (m *MockJWTToken) VerifyRefreshToken(tokenStr string, opts ...VerifyOption) (*UserClaims, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "VerifyRefreshToken", tokenStr)
+	varargs := []interface{}{tokenStr}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "VerifyRefreshToken", varargs...)
 	ret0, _ := ret[0].(*UserClaims)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // VerifyRefreshToken indicates an expected call of VerifyRefreshToken.
-func (mr *MockJWTTokenMockRecorder) VerifyRefreshToken(tokenStr interface{}) *gomock.Call {
+func (mr *MockJWTTokenMockRecorder) VerifyRefreshToken(tokenStr interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyRefreshToken", reflect.TypeOf((*MockJWTToken)(nil).VerifyRefreshToken), tokenStr)
+	varargs := append([]interface{}{tokenStr}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyRefreshToken", reflect.TypeOf((*MockJWTToken)(nil).VerifyRefreshToken), varargs...)
 }
@@ -0,0 +1,111 @@
+package jwttoken
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Key is a secret identified by its kid, configured as "kid=secret".
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKeys parses an ordered "kid=secret" list. The first key is the active one.
+// decode turns the configured secret into key bytes (e.g. base64).
+func ParseKeys(list []string, decode func(s string) ([]byte, error)) ([]Key, error) {
+	keys := make([]Key, 0, len(list))
+	seen := make(map[string]bool, len(list))
+
+	for _, entry := range list {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || kid == "" || secret == "" {
+			return nil, fmt.Errorf("invalid key entry %q, want kid=secret", kid)
+		}
+		if seen[kid] {
+			return nil, fmt.Errorf("duplicate key id %q", kid)
+		}
+		seen[kid] = true
+
+		b, err := decode(secret)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+		keys = append(keys, Key{ID: kid, Secret: b})
+	}
+	return keys, nil
+}
+
+// legacyKeyID : tokens signed with the single-secret config carry no kid
+const legacyKeyID = ""
+
+// LegacyEntry stands for the single-secret config in a key list (a bare "legacy"
+// entry), so it can keep signing kid-less tokens while a new key is rolled out.
+// It is retired by listing "legacy" in the retired keys.
+const LegacyEntry = "legacy"
+
+// signingKeys : HMAC keys of one token type. The first configured key signs, every
+// key that is not retired verifies. The legacy secret, if set, verifies tokens without
+// a kid. It signs when no key list is configured or when the list starts with LegacyEntry.
+type signingKeys struct {
+	active Key
+	keys   map[string][]byte
+}
+
+func newSigningKeys(list []string, legacy string, retired []string) (*signingKeys, error) {
+	list = slices.Clone(list)
+	at := slices.IndexFunc(list, func(entry string) bool { return strings.TrimSpace(entry) == LegacyEntry })
+	if at >= 0 {
+		if legacy == "" {
+			return nil, fmt.Errorf("key list has %q but no legacy secret is set", LegacyEntry)
+		}
+		list = slices.Delete(list, at, at+1)
+	}
+
+	keys, err := ParseKeys(list, func(s string) ([]byte, error) { return []byte(s), nil })
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(keys, func(k Key) bool { return k.ID == LegacyEntry }) {
+		return nil, fmt.Errorf("key id %q is reserved", LegacyEntry)
+	}
+
+	legacyKey := Key{ID: legacyKeyID, Secret: []byte(legacy)}
+	switch {
+	case at >= 0:
+		keys = slices.Insert(keys, at, legacyKey)
+	case legacy != "":
+		keys = append(keys, legacyKey)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing key configured")
+	}
+	isRetired := func(k Key) bool {
+		if k.ID == legacyKeyID {
+			return slices.Contains(retired, LegacyEntry)
+		}
+		return slices.Contains(retired, k.ID)
+	}
+	if isRetired(keys[0]) {
+		return nil, fmt.Errorf("active key %q is retired", keys[0].ID)
+	}
+
+	s := &signingKeys{
+		active: keys[0],
+		keys:   make(map[string][]byte, len(keys)),
+	}
+	for _, k := range keys {
+		if !isRetired(k) {
+			s.keys[k.ID] = k.Secret
+		}
+	}
+	return s, nil
+}
+
+// lookup returns the verification key for kid, false if unknown or retired.
+func (s *signingKeys) lookup(kid string) ([]byte, bool) {
+	key, ok := s.keys[kid]
+	return key, ok
+}
@@ -0,0 +1,100 @@
+package jwttoken_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newToken(t *testing.T, accessKeys, retired []string) jwttoken.JWTToken {
+	t.Helper()
+	tk, err := jwttoken.NewJWTToken(&config.JWTConfig{
+		AppName:           "test",
+		SecretKey:         "single-secret",
+		RefreshKey:        "refresh-secret",
+		AccessKeys:        accessKeys,
+		AccessRetiredKeys: retired,
+		AccessTokenTTL:    time.Minute,
+	})
+	require.NoError(t, err)
+	return tk
+}
+
+func kid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	require.NoError(t, err)
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+// TestLegacyMigration : moving from JWT_SECRET_KEY to a key list, one deploy per step,
+// never issues a token that an instance still on the previous step rejects.
+func TestLegacyMigration(t *testing.T) {
+	u := &user.User{ID: "user-1", Email: "john@mail.com"}
+
+	type testCase struct {
+		name        string
+		keys        []string
+		retired     []string
+		expectedKid string
+	}
+
+	steps := []testCase{
+		{name: "single secret"},
+		{name: "add k1", keys: []string{"legacy", "k1=k1-secret"}},
+		{name: "promote k1", keys: []string{"k1=k1-secret", "legacy"}, expectedKid: "k1"},
+		{name: "retire legacy", keys: []string{"k1=k1-secret", "legacy"}, retired: []string{"legacy"}, expectedKid: "k1"},
+	}
+
+	for i, step := range steps {
+		current := newToken(t, step.keys, step.retired)
+
+		token, err := current.GenerateAccessToken(u)
+		require.NoError(t, err, step.name)
+		assert.Equal(t, step.expectedKid, kid(t, token), step.name)
+
+		if i > 0 {
+			prev := steps[i-1]
+			_, err = newToken(t, prev.keys, prev.retired).VerifyAccessToken(token)
+			assert.NoError(t, err, "%s: verified by an instance on the previous step", step.name)
+		}
+	}
+
+	// Retired, the single secret no longer verifies kid-less tokens
+	legacyToken, err := newToken(t, nil, nil).GenerateAccessToken(u)
+	require.NoError(t, err)
+	_, err = newToken(t, []string{"k1=k1-secret", "legacy"}, []string{"legacy"}).VerifyAccessToken(legacyToken)
+	assert.ErrorIs(t, err, jwttoken.ErrTokenSignature)
+}
+
+func TestSigningKeysConfig(t *testing.T) {
+	type testCase struct {
+		name        string
+		cfg         config.JWTConfig
+		expectedErr bool
+	}
+
+	testCases := []testCase{
+		{name: "fail legacy entry without secret", cfg: config.JWTConfig{AccessKeys: []string{"legacy"}, RefreshKey: "r"}, expectedErr: true},
+		{name: "fail reserved kid", cfg: config.JWTConfig{AccessKeys: []string{"legacy=secret"}, RefreshKey: "r"}, expectedErr: true},
+		{name: "fail active legacy retired", cfg: config.JWTConfig{AccessKeys: []string{"legacy", "k1=s1"}, AccessRetiredKeys: []string{"legacy"}, SecretKey: "s", RefreshKey: "r"}, expectedErr: true},
+		{name: "success legacy entry", cfg: config.JWTConfig{AccessKeys: []string{"legacy", "k1=s1"}, SecretKey: "s", RefreshKey: "r"}},
+	}
+
+	for _, tc := range testCases {
+		_, err := jwttoken.NewJWTToken(&tc.cfg)
+
+		if tc.expectedErr {
+			assert.Error(t, err, tc.name)
+			continue
+		}
+		assert.NoError(t, err, tc.name)
+	}
+}
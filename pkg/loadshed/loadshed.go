@@ -0,0 +1,179 @@
+package loadshed
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOverloaded : no slot became free before the queue deadline, or the queue is full
+var ErrOverloaded = errors.New("server overloaded")
+
+// Options of a Limiter. Zero MaxQueue fails fast without queueing.
+type Options struct {
+	Name         string
+	MaxInFlight  int
+	MaxQueue     int
+	QueueTimeout time.Duration
+
+	// Adaptive, if set, moves the in-flight cap between its Min and MaxInFlight.
+	Adaptive *AIMD
+
+	// Now is the clock used to measure latency, time.Now by default.
+	Now func() time.Time
+}
+
+// AIMD : additive increase, multiplicative decrease. A request slower than TargetLatency
+// (or failed) cuts the cap by Backoff; fast requests grow it by about one per cap's worth
+// of requests.
+type AIMD struct {
+	Min           int
+	TargetLatency time.Duration
+	Backoff       float64
+}
+
+// Stats is a snapshot of a Limiter's state and counters.
+type Stats struct {
+	Name     string
+	Limit    int
+	InFlight int
+	Queued   int
+	Accepted uint64
+	Rejected uint64
+	TimedOut uint64
+}
+
+// Limiter caps in-flight requests and queues the overflow briefly, in FIFO order.
+type Limiter struct {
+	opts Options
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  list.List // chan struct{}, closed when granted a slot
+	accepted uint64
+	rejected uint64
+	timedOut uint64
+}
+
+func New(opts Options) *Limiter {
+	if opts.MaxInFlight < 1 {
+		opts.MaxInFlight = 1
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.Adaptive != nil {
+		a := *opts.Adaptive
+		a.Min = min(max(a.Min, 1), opts.MaxInFlight)
+		if a.Backoff <= 0 || a.Backoff >= 1 {
+			a.Backoff = 0.9
+		}
+		opts.Adaptive = &a
+	}
+	return &Limiter{
+		opts:  opts,
+		limit: float64(opts.MaxInFlight),
+	}
+}
+
+// Acquire waits for a slot until QueueTimeout or ctx is done. Call done exactly once
+// when the request has finished, with failed set for server errors.
+func (l *Limiter) Acquire(ctx context.Context) (done func(failed bool), err error) {
+	l.mu.Lock()
+	if l.inFlight < l.slots() && l.waiters.Len() == 0 {
+		l.inFlight++
+		l.accepted++
+		l.mu.Unlock()
+		return l.doneFunc(), nil
+	}
+	if l.waiters.Len() >= l.opts.MaxQueue {
+		l.rejected++
+		l.mu.Unlock()
+		return nil, ErrOverloaded
+	}
+	granted := make(chan struct{})
+	elem := l.waiters.PushBack(granted)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.opts.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-granted:
+		return l.doneFunc(), nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-granted:
+		// Granted while timing out: keep the slot
+		return l.doneFunc(), nil
+	default:
+	}
+	l.waiters.Remove(elem)
+	l.timedOut++
+	return nil, ErrOverloaded
+}
+
+// RetryAfter : a hint for clients that were shed
+func (l *Limiter) RetryAfter() time.Duration {
+	return max(l.opts.QueueTimeout, time.Second)
+}
+
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return Stats{
+		Name:     l.opts.Name,
+		Limit:    l.slots(),
+		InFlight: l.inFlight,
+		Queued:   l.waiters.Len(),
+		Accepted: l.accepted,
+		Rejected: l.rejected,
+		TimedOut: l.timedOut,
+	}
+}
+
+func (l *Limiter) doneFunc() func(failed bool) {
+	start := l.opts.Now()
+	var once sync.Once
+	return func(failed bool) {
+		once.Do(func() {
+			l.release(l.opts.Now().Sub(start), failed)
+		})
+	}
+}
+
+func (l *Limiter) release(latency time.Duration, failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	if a := l.opts.Adaptive; a != nil {
+		if failed || latency > a.TargetLatency {
+			l.limit = max(l.limit*a.Backoff, float64(a.Min))
+		} else {
+			l.limit = min(l.limit+1/l.limit, float64(l.opts.MaxInFlight))
+		}
+	}
+
+	// Hand free slots to waiters, oldest first
+	for l.inFlight < l.slots() && l.waiters.Len() > 0 {
+		granted := l.waiters.Remove(l.waiters.Front()).(chan struct{})
+		l.inFlight++
+		l.accepted++
+		close(granted)
+	}
+}
+
+// slots : the current whole number of slots
+func (l *Limiter) slots() int {
+	return max(int(l.limit), 1)
+}
@@ -0,0 +1,136 @@
+package loadshed_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/loadshed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fail fast without a queue", func(t *testing.T) {
+		l := loadshed.New(loadshed.Options{MaxInFlight: 1})
+
+		done, err := l.Acquire(ctx)
+		require.NoError(t, err)
+
+		_, err = l.Acquire(ctx)
+		assert.ErrorIs(t, err, loadshed.ErrOverloaded)
+
+		done(false)
+		done(false) // a second call is a no-op
+		assert.Equal(t, 0, l.Stats().InFlight)
+
+		done, err = l.Acquire(ctx)
+		require.NoError(t, err)
+		done(false)
+
+		stats := l.Stats()
+		assert.Equal(t, uint64(2), stats.Accepted)
+		assert.Equal(t, uint64(1), stats.Rejected)
+	})
+
+	t.Run("waiters get freed slots in order", func(t *testing.T) {
+		l := loadshed.New(loadshed.Options{MaxInFlight: 1, MaxQueue: 2, QueueTimeout: time.Minute})
+
+		done, err := l.Acquire(ctx)
+		require.NoError(t, err)
+
+		order := make(chan int, 2)
+		for i := range 2 {
+			go func() {
+				d, err := l.Acquire(ctx)
+				if err != nil {
+					order <- -1
+					return
+				}
+				order <- i
+				d(false)
+			}()
+			require.Eventually(t, func() bool { return l.Stats().Queued == i+1 }, time.Second, time.Millisecond)
+		}
+
+		// Queue full
+		_, err = l.Acquire(ctx)
+		assert.ErrorIs(t, err, loadshed.ErrOverloaded)
+
+		done(false)
+		assert.Equal(t, 0, <-order)
+		assert.Equal(t, 1, <-order)
+	})
+
+	t.Run("queue deadline", func(t *testing.T) {
+		l := loadshed.New(loadshed.Options{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 5 * time.Millisecond})
+
+		done, err := l.Acquire(ctx)
+		require.NoError(t, err)
+		defer done(false)
+
+		_, err = l.Acquire(ctx)
+		assert.ErrorIs(t, err, loadshed.ErrOverloaded)
+
+		stats := l.Stats()
+		assert.Equal(t, uint64(1), stats.TimedOut)
+		assert.Equal(t, 0, stats.Queued)
+	})
+
+	t.Run("context canceled while queued", func(t *testing.T) {
+		l := loadshed.New(loadshed.Options{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Minute})
+
+		done, err := l.Acquire(ctx)
+		require.NoError(t, err)
+		defer done(false)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = l.Acquire(canceled)
+		assert.ErrorIs(t, err, loadshed.ErrOverloaded)
+		assert.Equal(t, 0, l.Stats().Queued)
+	})
+}
+
+func TestAdaptive(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Unix(0, 0)
+	l := loadshed.New(loadshed.Options{
+		MaxInFlight: 4,
+		Adaptive:    &loadshed.AIMD{Min: 1, TargetLatency: 100 * time.Millisecond, Backoff: 0.5},
+		Now:         func() time.Time { return now },
+	})
+
+	type testCase struct {
+		name          string
+		latency       time.Duration
+		failed        bool
+		expectedLimit int
+	}
+
+	testCases := []testCase{
+		{name: "fast at max stays", latency: 10 * time.Millisecond, expectedLimit: 4},
+		{name: "slow halves", latency: 500 * time.Millisecond, expectedLimit: 2},
+		{name: "failed halves", latency: 10 * time.Millisecond, failed: true, expectedLimit: 1},
+		{name: "slow stops at min", latency: 500 * time.Millisecond, expectedLimit: 1},
+		{name: "fast adds one slot per cap", latency: 10 * time.Millisecond, expectedLimit: 2},
+		{name: "fast adds half", latency: 10 * time.Millisecond, expectedLimit: 2},
+	}
+
+	for _, tc := range testCases {
+		done, err := l.Acquire(ctx)
+		require.NoError(t, err, tc.name)
+		now = now.Add(tc.latency)
+		done(tc.failed)
+
+		assert.Equal(t, tc.expectedLimit, l.Stats().Limit, tc.name)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Second, loadshed.New(loadshed.Options{QueueTimeout: 100 * time.Millisecond}).RetryAfter())
+	assert.Equal(t, 3*time.Second, loadshed.New(loadshed.Options{QueueTimeout: 3 * time.Second}).RetryAfter())
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/pkg/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextHandler adds the request, trace, span, user and tenant IDs found in the context to
+// every record, so slog.InfoContext(ctx, ...) in any layer is tied to its request.
+type contextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(h slog.Handler) slog.Handler {
+	return &contextHandler{Handler: h}
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	// Trace ID from the incoming traceparent, else from the active span
+	traceID := requestid.TraceIDFromContext(ctx)
+	sc := trace.SpanContextFromContext(ctx)
+	if traceID == "" && sc.IsValid() {
+		traceID = sc.TraceID().String()
+	}
+	if traceID != "" {
+		r.AddAttrs(slog.String("trace_id", traceID))
+	}
+	if sc.IsValid() {
+		r.AddAttrs(slog.String("span_id", sc.SpanID().String()))
+	}
+	if userID, ok := ctx.Value(config.ContextUserIDKey).(string); ok && userID != "" {
+		r.AddAttrs(slog.String("user_id", userID))
+	}
+	if orgID, ok := ctx.Value(config.ContextOrgIDKey).(string); ok && orgID != "" {
+		r.AddAttrs(slog.String("org_id", orgID))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// samplingHandler keeps rate of the records below Warn, evenly spread, and every
+// Warn and Error record.
+type samplingHandler struct {
+	slog.Handler
+	rate float64
+	seen *atomic.Uint64
+}
+
+func NewSamplingHandler(h slog.Handler, rate float64) slog.Handler {
+	return &samplingHandler{Handler: h, rate: rate, seen: new(atomic.Uint64)}
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		// Keep record n when floor(n * rate) steps up: exact and deterministic
+		n := h.seen.Add(1)
+		if uint64(float64(n)*h.rate) == uint64(float64(n-1)*h.rate) {
+			return nil
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), rate: h.rate, seen: h.seen}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), rate: h.rate, seen: h.seen}
+}
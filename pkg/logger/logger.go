@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+)
+
+// New builds the application logger from cfg: level, text or JSON, destination and
+// sampling, with redaction and request context enrichment. close releases the output
+// file, if any.
+func New(cfg config.LogConfig) (l *slog.Logger, close func() error, err error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	}
+
+	out, close, err := openOutput(cfg.Output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		AddSource:   cfg.AddSource,
+		ReplaceAttr: Redact,
+	}
+
+	var h slog.Handler
+	if cfg.Format == "json" {
+		h = slog.NewJSONHandler(out, opts)
+	} else {
+		h = slog.NewTextHandler(out, opts)
+	}
+	h = NewContextHandler(h)
+	if cfg.SampleRate < 1 {
+		h = NewSamplingHandler(h, cfg.SampleRate)
+	}
+	return slog.New(h), close, nil
+}
+
+// openOutput : "stdout", "stderr" or a file path (appended to)
+func openOutput(output string) (io.Writer, func() error, error) {
+	noop := func() error { return nil }
+
+	switch strings.ToLower(output) {
+	case "", "stderr":
+		return os.Stderr, noop, nil
+	case "stdout":
+		return os.Stdout, noop, nil
+	}
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open log output: %w", err)
+	}
+	return f, f.Close, nil
+}
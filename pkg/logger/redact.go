@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys : attributes whose whole value is hidden, matched case-insensitively.
+// A bare "code" is left out: it is mostly an error code, log auth codes as
+// authorization_code.
+var sensitiveKeys = map[string]bool{
+	"authorization":      true,
+	"cookie":             true,
+	"set-cookie":         true,
+	"password":           true,
+	"secret":             true,
+	"client_secret":      true,
+	"token":              true,
+	"access_token":       true,
+	"refresh_token":      true,
+	"subject_token":      true,
+	"id_token":           true,
+	"device_code":        true,
+	"authorization_code": true,
+	"dpop":               true,
+	"x-api-key":          true,
+	"signature":          true,
+	"x-signature":        true,
+	"api_key":            true,
+	"new_password":       true,
+	"old_password":       true,
+	"user_code":          true,
+}
+
+var (
+	emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+	// key=value pairs in query strings, form bodies and links
+	paramPattern  = regexp.MustCompile(`(?i)\b(token|access_token|refresh_token|subject_token|id_token|password|secret|client_secret|code|device_code|api_key)=([^&\s"']+)`)
+	bearerPattern = regexp.MustCompile(`(?i)\b(Bearer|DPoP|Basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// Redact is a slog ReplaceAttr: it hides sensitive attributes by key, and masks
+// emails, credentials in query strings and Authorization values inside strings.
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if list, ok := a.Value.Any().([]string); ok {
+			masked := make([]string, len(list))
+			for i, s := range list {
+				masked[i] = RedactString(s)
+			}
+			return slog.Any(a.Key, masked)
+		}
+	}
+	return a
+}
+
+// RedactString masks emails (j***@example.com), credential parameters (token=[REDACTED])
+// and Authorization values (Bearer [REDACTED]) in s.
+func RedactString(s string) string {
+	if s == "" {
+		return s
+	}
+	s = bearerPattern.ReplaceAllString(s, "$1 "+redacted)
+	s = paramPattern.ReplaceAllString(s, "$1="+redacted)
+	s = emailPattern.ReplaceAllString(s, "$1***@$2")
+	return s
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/smtp"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+)
+
+// ErrHeaderInjection : an address or the subject contains a line break, which would end
+// the header and start another one
+var ErrHeaderInjection = errors.New("mail header contains a line break")
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+//go:generate mockgen -source=mailer.go -destination=mailer_mock.go -package=mailer
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewMailer returns an SMTP mailer, or a log mailer when no SMTP host is configured.
+func NewMailer(cfg config.MailConfig) Mailer {
+	if cfg.Host == "" {
+		return &logMailer{}
+	}
+	return &smtpMailer{cfg: cfg}
+}
+
+// ------------- SMTP ----------------
+
+type smtpMailer struct {
+	cfg config.MailConfig
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	body, err := Compose(m.cfg.From, msg)
+	if err != nil {
+		return err
+	}
+
+	addr := fmt.Sprintf("%s:%d", m.cfg.Host, m.cfg.Port)
+	if err := smtp.SendMail(addr, auth, m.cfg.From, msg.To, body); err != nil {
+		return fmt.Errorf("send mail failed: %w", err)
+	}
+	return nil
+}
+
+// Compose renders msg as a plain text mail. Header values may come from users (an
+// organization name in the subject), so line breaks are rejected and the subject is
+// Q-encoded, which also keeps non-ASCII subjects intact.
+func Compose(from string, msg *Message) ([]byte, error) {
+	for _, value := range append([]string{from, msg.Subject}, msg.To...) {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrHeaderInjection
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String()), nil
+}
+
+// ------------- Log (Development) ----------------
+
+type logMailer struct{}
+
+func (m *logMailer) Send(ctx context.Context, msg *Message) error {
+	slog.InfoContext(ctx, "Mail sent",
+		slog.Any("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
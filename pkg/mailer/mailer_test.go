@@ -0,0 +1,61 @@
+package mailer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompose(t *testing.T) {
+	type testCase struct {
+		name          string
+		msg           *mailer.Message
+		expectSubject string
+		expectedErr   error
+	}
+
+	testCases := []testCase{
+		{
+			name:          "success ascii subject",
+			msg:           &mailer.Message{To: []string{"invitee@mail.com"}, Subject: "Join Acme", Body: "hello"},
+			expectSubject: "Subject: Join Acme\r\n",
+		},
+		{
+			name:          "success encoded subject",
+			msg:           &mailer.Message{To: []string{"invitee@mail.com"}, Subject: "เชิญเข้าร่วม Acme", Body: "hello"},
+			expectSubject: "Subject: =?utf-8?q?",
+		},
+		{
+			name:        "fail subject with line break",
+			msg:         &mailer.Message{To: []string{"invitee@mail.com"}, Subject: "Join x\r\nBcc: evil@mail.com", Body: "hello"},
+			expectedErr: mailer.ErrHeaderInjection,
+		},
+		{
+			name:        "fail subject with bare line feed",
+			msg:         &mailer.Message{To: []string{"invitee@mail.com"}, Subject: "Join x\nBcc: evil@mail.com", Body: "hello"},
+			expectedErr: mailer.ErrHeaderInjection,
+		},
+		{
+			name:        "fail recipient with line break",
+			msg:         &mailer.Message{To: []string{"invitee@mail.com\r\nBcc: evil@mail.com"}, Subject: "Join Acme", Body: "hello"},
+			expectedErr: mailer.ErrHeaderInjection,
+		},
+	}
+
+	for _, tc := range testCases {
+		b, err := mailer.Compose("noreply@mail.com", tc.msg)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+			continue
+		}
+		assert.NoError(t, err, tc.name)
+
+		header, body, _ := strings.Cut(string(b), "\r\n\r\n")
+		assert.Contains(t, header, tc.expectSubject, tc.name)
+		assert.NotContains(t, header, "Bcc:", tc.name)
+		assert.Equal(t, tc.msg.Body, body, tc.name)
+	}
+}
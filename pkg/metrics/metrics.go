@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "app"
+
+// Auth outcome label values for AuthEvents
+const (
+	AuthSuccess      = "success"
+	AuthFailure      = "failure"
+	AuthReuse        = "reuse"         // revoked refresh token presented again
+	AuthSessionLimit = "session_limit" // login refused by the session limit
+)
+
+// Registry holds every collector served on /metrics. It is separate from the
+// prometheus default registry so only what this app registers is exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests : label route is the gin route template (/orgs/:id), never the raw path
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	// AuthEvents : event is login or refresh; outcome is one of the Auth* values
+	AuthEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "events_total",
+		Help:      "Authentication outcomes by event.",
+	}, []string{"event", "outcome"})
+
+	// PasswordHashDuration : op is hash or compare
+	PasswordHashDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "password_hash_duration_seconds",
+		Help:      "bcrypt hashing and comparison time.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"op"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected with 429 by route template.",
+	}, []string{"route"})
+
+	LoadShed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "shed_total",
+		Help:      "Requests rejected with 503 by load shedding limiter.",
+	}, []string{"limiter"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		AuthEvents,
+		PasswordHashDuration,
+		RateLimited,
+		LoadShed,
+	)
+}
+
+// RegisterDB exposes the sql.DBStats of db (open, in use and idle connections, waits)
+// under the given database name. It replaces a collector registered before under the
+// same name, so building a new server (tests, reload) does not fail.
+func RegisterDB(db *sql.DB, name string) error {
+	return replace(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterInFlight exposes fn as a gauge, e.g. the in-flight count of a load shedder.
+// Like RegisterDB, it replaces the gauge of a limiter with the same name.
+func RegisterInFlight(name string, fn func() float64) error {
+	return replace(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   "loadshed",
+		Name:        "in_flight",
+		Help:        "Requests holding a load shedding slot.",
+		ConstLabels: prometheus.Labels{"limiter": name},
+	}, fn))
+}
+
+// replace : collectors are matched by their descriptors, so c unregisters its predecessor
+func replace(c prometheus.Collector) error {
+	Registry.Unregister(c)
+	return Registry.Register(c)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,55 @@
+package nonce
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store remembers single-use values (nonces, JWT IDs) to reject replays.
+type Store interface {
+	// Use records key for ttl. It returns false when key was already used and has not expired.
+	Use(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore returns a process-local Store. Use NewPostgresStore when running several instances.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		seen: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (s *memoryStore) Use(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if exp, ok := s.seen[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.seen[key] = now.Add(ttl)
+	return true, nil
+}
+
+// sweep : drop expired keys at most once a minute
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	for k, exp := range s.seen {
+		if !now.Before(exp) {
+			delete(s.seen, k)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,74 @@
+package nonce
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sweepInterval : how often an instance deletes expired rows
+const sweepInterval = time.Minute
+
+type postgresStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewPostgresStore returns a Store shared by every instance using the same database
+// (table used_nonces, see migrations). Keys are kept as SHA-256 hashes, so client
+// chosen values of any length fit.
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (s *postgresStore) Use(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := s.now()
+	s.sweep(ctx, now)
+
+	// The primary key decides: only one insert (or reuse of an expired row) wins
+	query := `
+		INSERT INTO used_nonces (key, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE used_nonces.expires_at <= $3
+		RETURNING key
+	`
+	sum := sha256.Sum256([]byte(key))
+
+	var used string
+	err := s.db.QueryRowContext(ctx, query, hex.EncodeToString(sum[:]), now.Add(ttl), now).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sweep : delete expired nonces at most once per sweepInterval, best effort
+func (s *postgresStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	query := `DELETE FROM used_nonces WHERE expires_at < $1`
+	if _, err := s.db.ExecContext(ctx, query, now); err != nil {
+		slog.WarnContext(ctx, "nonce sweep failed", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,47 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("cursor is invalid or was made for another sort")
+
+// cursor : the sort values of the last row of a page. Opaque to clients, and only valid
+// with the sort it was made for.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func encodeCursor(sort string, values []string) string {
+	b, _ := json.Marshal(cursor{Sort: sort, Values: values})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor : the values after which the next page starts, typed like their fields
+func decodeCursor(s string, sort []sortField) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || len(c.Values) != len(sort) {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sortString(sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	after := make([]any, len(sort))
+	for i, f := range sort {
+		v, err := parseValue(f.Type, c.Values[i])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		after[i] = v
+	}
+	return after, nil
+}
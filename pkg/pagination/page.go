@@ -0,0 +1,89 @@
+package pagination
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Sortable : a listed item, giving its values by sort field name to build the next cursor
+type Sortable interface {
+	SortValues() map[string]any
+}
+
+// Page : one page of a list and what the response says about it
+type Page[T any] struct {
+	Items []T
+	Meta  Meta
+}
+
+type Meta struct {
+	Limit      int    `json:"limit"`
+	Page       int    `json:"page,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	// Total : only counted with ?total=true
+	Total *int `json:"total,omitempty"`
+}
+
+// NewPage takes the rows fetched with q.Clause, one more than the limit when there is a
+// next page, and keeps the page.
+func NewPage[T Sortable](q *Query, items []T) *Page[T] {
+	p := &Page[T]{
+		Items: items,
+		Meta:  Meta{Limit: q.Limit, Page: q.Page},
+	}
+	if len(items) <= q.Limit {
+		return p
+	}
+
+	p.Items = items[:q.Limit]
+	p.Meta.HasMore = true
+	if q.Page == 0 {
+		last := p.Items[q.Limit-1].SortValues()
+		values := make([]string, len(q.sort))
+		for i, f := range q.sort {
+			values[i] = formatValue(last[f.name])
+		}
+		p.Meta.NextCursor = encodeCursor(q.Sort(), values)
+	}
+	return p
+}
+
+// Links : the RFC 8288 Link header of the page requested at u. Keyset pages link to the
+// first and next page only, offset pages also to the previous and, with a total, the last.
+func (m Meta) Links(u *url.URL) string {
+	var links []string
+	add := func(rel string, set func(url.Values)) {
+		query := u.Query()
+		query.Del("cursor")
+		query.Del("page")
+		set(query)
+
+		target := url.URL{Path: u.Path, RawQuery: query.Encode()}
+		links = append(links, "<"+target.String()+`>; rel="`+rel+`"`)
+	}
+	page := func(n int) func(url.Values) {
+		return func(query url.Values) { query.Set("page", strconv.Itoa(n)) }
+	}
+
+	if m.Page == 0 {
+		add("first", func(url.Values) {})
+		if m.NextCursor != "" {
+			add("next", func(query url.Values) { query.Set("cursor", m.NextCursor) })
+		}
+		return strings.Join(links, ", ")
+	}
+
+	add("first", page(1))
+	if m.Page > 1 {
+		add("prev", page(m.Page-1))
+	}
+	if m.HasMore {
+		add("next", page(m.Page+1))
+	}
+	if m.Total != nil && m.Limit > 0 {
+		add("last", page(max(1, (*m.Total+m.Limit-1)/m.Limit)))
+	}
+	return strings.Join(links, ", ")
+}
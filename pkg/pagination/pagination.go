@@ -0,0 +1,255 @@
+package pagination
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+	// MaxOffset : the rows offset pages may skip. Deeper pages are slow to scan; use the
+	// cursor to go further.
+	MaxOffset = 10_000
+)
+
+// Type : how a query or cursor value is parsed before it reaches SQL
+type Type int
+
+const (
+	String Type = iota
+	Bool
+	Int
+	UUID
+	Time
+)
+
+// Field : a column clients may sort or filter by, under its API name
+type Field struct {
+	Column string
+	Type   Type
+}
+
+// Spec : the whitelist of a list endpoint. Only the fields named here reach SQL.
+type Spec struct {
+	Sorts   map[string]Field
+	Filters map[string]Field
+	// DefaultSort : e.g. "-created_at", newest first
+	DefaultSort string
+	// Key : a unique, non-null sort field ending every order, so keyset pages never skip
+	// or repeat rows
+	Key string
+	// DefaultLimit, MaxLimit and MaxOffset fall back to the package ones
+	DefaultLimit int
+	MaxLimit     int
+	MaxOffset    int
+}
+
+// Query : a parsed list request. Page > 0 selects offset pagination, keyset otherwise.
+type Query struct {
+	Limit int
+	Page  int
+	Total bool
+
+	sort    []sortField
+	filters []filter
+	after   []any
+}
+
+type sortField struct {
+	name string
+	Field
+	desc bool
+}
+
+type filter struct {
+	column string
+	values []any
+}
+
+// Parse reads ?limit, ?cursor or ?page, ?sort, ?total and ?filter[field] against spec.
+// Sorts are comma separated, "-" for descending (sort=-created_at,name); filter values are
+// comma separated and match any of them (filter[status]=pending,accepted).
+func Parse(values url.Values, spec Spec) (*Query, error) {
+	q := &Query{Limit: spec.DefaultLimit}
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	maxLimit := spec.MaxLimit
+	if maxLimit == 0 {
+		maxLimit = MaxLimit
+	}
+	maxOffset := spec.MaxOffset
+	if maxOffset == 0 {
+		maxOffset = MaxOffset
+	}
+
+	if s := values.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		q.Limit = n
+	}
+
+	cursor := values.Get("cursor")
+	if s := values.Get("page"); s != "" {
+		if cursor != "" {
+			return nil, fmt.Errorf("use either cursor or page, not both")
+		}
+		// Bounded, so (page-1)*limit neither overflows nor scans too deep
+		maxPage := maxOffset/q.Limit + 1
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPage {
+			return nil, fmt.Errorf("page must be between 1 and %d", maxPage)
+		}
+		q.Page = n
+	}
+
+	if s := values.Get("total"); s != "" {
+		total, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("total must be true or false")
+		}
+		q.Total = total
+	}
+
+	sort := values.Get("sort")
+	if sort == "" {
+		sort = spec.DefaultSort
+	}
+	if err := q.parseSort(sort, spec); err != nil {
+		return nil, err
+	}
+
+	if err := q.parseFilters(values, spec); err != nil {
+		return nil, err
+	}
+
+	if cursor != "" {
+		after, err := decodeCursor(cursor, q.sort)
+		if err != nil {
+			return nil, err
+		}
+		q.after = after
+	}
+	return q, nil
+}
+
+func (q *Query) parseSort(sort string, spec Spec) error {
+	seen := make(map[string]bool)
+	for name := range strings.SplitSeq(sort, ",") {
+		name = strings.TrimSpace(name)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+
+		field, ok := spec.Sorts[name]
+		if !ok {
+			return fmt.Errorf("sort by %q is not supported", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("sort by %q is repeated", name)
+		}
+		seen[name] = true
+		q.sort = append(q.sort, sortField{name: name, Field: field, desc: desc})
+	}
+
+	if !seen[spec.Key] {
+		key, ok := spec.Sorts[spec.Key]
+		if !ok {
+			panic("pagination: spec key " + spec.Key + " is not a sort field")
+		}
+		q.sort = append(q.sort, sortField{name: spec.Key, Field: key, desc: q.sort[len(q.sort)-1].desc})
+	}
+	return nil
+}
+
+func (q *Query) parseFilters(values url.Values, spec Spec) error {
+	// Sorted, so the same request builds the same SQL
+	for _, param := range slices.Sorted(maps.Keys(values)) {
+		name, ok := strings.CutPrefix(param, "filter[")
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, "]")
+		field, known := spec.Filters[name]
+		if !ok || !known {
+			return fmt.Errorf("filter by %q is not supported", name)
+		}
+
+		f := filter{column: field.Column}
+		for _, v := range values[param] {
+			for s := range strings.SplitSeq(v, ",") {
+				value, err := parseValue(field.Type, strings.TrimSpace(s))
+				if err != nil {
+					return fmt.Errorf("filter[%s]: %w", name, err)
+				}
+				f.values = append(f.values, value)
+			}
+		}
+		q.filters = append(q.filters, f)
+	}
+	return nil
+}
+
+// Sort : the resolved order, key included, e.g. "-created_at,-id"
+func (q *Query) Sort() string {
+	return sortString(q.sort)
+}
+
+func sortString(sort []sortField) string {
+	names := make([]string, len(sort))
+	for i, f := range sort {
+		names[i] = f.name
+		if f.desc {
+			names[i] = "-" + f.name
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func parseValue(t Type, s string) (any, error) {
+	switch t {
+	case Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", s)
+		}
+		return b, nil
+	case Int:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return n, nil
+	case UUID:
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a UUID", s)
+		}
+		return id.String(), nil
+	case Time:
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an RFC 3339 time", s)
+		}
+		return ts, nil
+	default:
+		return s, nil
+	}
+}
+
+// formatValue : the string parseValue reads back
+func formatValue(v any) string {
+	if ts, ok := v.(time.Time); ok {
+		return ts.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
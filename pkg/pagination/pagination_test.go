@@ -0,0 +1,190 @@
+package pagination_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSpec = pagination.Spec{
+	Sorts: map[string]pagination.Field{
+		"created_at": {Column: "created_at", Type: pagination.Time},
+		"name":       {Column: "name", Type: pagination.String},
+		"id":         {Column: "id", Type: pagination.UUID},
+	},
+	Filters: map[string]pagination.Field{
+		"status": {Column: "status", Type: pagination.String},
+		"active": {Column: "is_active", Type: pagination.Bool},
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+type item struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+func (i item) SortValues() map[string]any {
+	return map[string]any{"id": i.ID, "name": i.Name, "created_at": i.CreatedAt}
+}
+
+// cursorAfter : the next cursor of a page of one item ending with last
+func cursorAfter(t *testing.T, sort string, last item) string {
+	q, err := pagination.Parse(url.Values{"sort": {sort}, "limit": {"1"}}, testSpec)
+	require.NoError(t, err)
+	page := pagination.NewPage(q, []item{last, {}})
+	require.NotEmpty(t, page.Meta.NextCursor)
+	return page.Meta.NextCursor
+}
+
+func TestClause(t *testing.T) {
+	type testCase struct {
+		name     string
+		values   url.Values
+		expected pagination.Clause
+	}
+
+	// Not UTC, with nanoseconds: the cursor keeps the instant
+	createdAt := time.Date(2026, 3, 1, 9, 30, 15, 123456789, time.FixedZone("ICT", 7*60*60))
+	last := item{ID: "0b5b6f3e-2d9c-4f61-9d3c-6b1f0a2e4c11", Name: "acme", CreatedAt: createdAt}
+
+	testCases := []testCase{
+		{
+			name:   "success default sort",
+			values: url.Values{},
+			expected: pagination.Clause{
+				Filter:     "TRUE",
+				FilterArgs: nil,
+				Where:      "TRUE",
+				OrderBy:    "created_at DESC, id DESC",
+				Limit:      "LIMIT $2",
+				Args:       []any{21},
+			},
+		},
+		{
+			name: "success mixed directions after cursor",
+			values: url.Values{
+				"sort":           {"-created_at,name"},
+				"limit":          {"10"},
+				"filter[status]": {"pending,accepted"},
+				"filter[active]": {"true"},
+				"cursor":         {cursorAfter(t, "-created_at,name", last)},
+			},
+			expected: pagination.Clause{
+				Filter:     "is_active = $2 AND status IN ($3, $4)",
+				FilterArgs: []any{true, "pending", "accepted"},
+				Where: "is_active = $2 AND status IN ($3, $4) AND (" +
+					"(created_at < $5) OR " +
+					"(created_at = $6 AND name > $7) OR " +
+					"(created_at = $8 AND name = $9 AND id > $10))",
+				OrderBy: "created_at DESC, name ASC, id ASC",
+				Limit:   "LIMIT $11",
+				Args: []any{
+					true, "pending", "accepted",
+					createdAt.UTC(),
+					createdAt.UTC(), "acme",
+					createdAt.UTC(), "acme", last.ID,
+					11,
+				},
+			},
+		},
+		{
+			name:   "success offset page",
+			values: url.Values{"page": {"3"}, "limit": {"10"}, "filter[status]": {"pending"}},
+			expected: pagination.Clause{
+				Filter:     "status = $2",
+				FilterArgs: []any{"pending"},
+				Where:      "status = $2",
+				OrderBy:    "created_at DESC, id DESC",
+				Limit:      "LIMIT $3 OFFSET $4",
+				Args:       []any{"pending", 11, 20},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		q, err := pagination.Parse(tc.values, testSpec)
+		require.NoError(t, err, tc.name)
+
+		assert.Equal(t, tc.expected, q.Clause(1), tc.name)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 9, 30, 15, 123456789, time.FixedZone("ICT", 7*60*60))
+	last := item{ID: "0b5b6f3e-2d9c-4f61-9d3c-6b1f0a2e4c11", Name: "acme", CreatedAt: createdAt}
+
+	q, err := pagination.Parse(url.Values{"cursor": {cursorAfter(t, "-created_at", last)}}, testSpec)
+	require.NoError(t, err)
+
+	// (created_at < $1) OR (created_at = $2 AND id < $3)
+	args := q.Clause(0).Args
+	require.Len(t, args, 4)
+	after, ok := args[0].(time.Time)
+	require.True(t, ok)
+	assert.True(t, createdAt.Equal(after), "nanoseconds are kept")
+	assert.Equal(t, last.ID, args[2])
+}
+
+func TestParseErrors(t *testing.T) {
+	type testCase struct {
+		name        string
+		values      url.Values
+		expectedErr error
+	}
+
+	last := item{ID: "0b5b6f3e-2d9c-4f61-9d3c-6b1f0a2e4c11", Name: "acme", CreatedAt: time.Now()}
+
+	testCases := []testCase{
+		{name: "fail unknown sort", values: url.Values{"sort": {"password"}}},
+		{name: "fail repeated sort", values: url.Values{"sort": {"name,-name"}}},
+		{name: "fail unknown filter", values: url.Values{"filter[password]": {"x"}}},
+		{name: "fail filter without closing bracket", values: url.Values{"filter[status": {"pending"}}},
+		{name: "fail filter value of wrong type", values: url.Values{"filter[active]": {"yes please"}}},
+		{name: "fail limit over max", values: url.Values{"limit": {"101"}}},
+		{name: "fail page zero", values: url.Values{"page": {"0"}}},
+		{name: "fail page past max offset", values: url.Values{"page": {"502"}, "limit": {"20"}}},
+		{name: "fail page overflowing offset", values: url.Values{"page": {"9223372036854775807"}, "limit": {"100"}}},
+		{name: "fail cursor and page", values: url.Values{"page": {"2"}, "cursor": {cursorAfter(t, "-created_at", last)}}},
+		{
+			name:        "fail cursor from another sort",
+			values:      url.Values{"sort": {"name"}, "cursor": {cursorAfter(t, "-created_at", last)}},
+			expectedErr: pagination.ErrInvalidCursor,
+		},
+		{
+			name:        "fail cursor from the other direction",
+			values:      url.Values{"sort": {"created_at"}, "cursor": {cursorAfter(t, "-created_at", last)}},
+			expectedErr: pagination.ErrInvalidCursor,
+		},
+		{name: "fail garbage cursor", values: url.Values{"cursor": {"not-a-cursor"}}, expectedErr: pagination.ErrInvalidCursor},
+	}
+
+	for _, tc := range testCases {
+		q, err := pagination.Parse(tc.values, testSpec)
+
+		assert.Nil(t, q, tc.name)
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr, tc.name)
+			continue
+		}
+		assert.Error(t, err, tc.name)
+	}
+}
+
+func TestParseMaxPage(t *testing.T) {
+	// 500 pages of 20 skip 10 000 rows, the last one allowed
+	q, err := pagination.Parse(url.Values{"page": {"501"}, "limit": {"20"}}, testSpec)
+	require.NoError(t, err)
+	assert.Equal(t, []any{21, 10_000}, q.Clause(0).Args)
+
+	spec := testSpec
+	spec.MaxOffset = 100
+	_, err = pagination.Parse(url.Values{"page": {"7"}, "limit": {"20"}}, spec)
+	assert.EqualError(t, err, "page must be between 1 and 6")
+}
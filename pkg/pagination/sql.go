@@ -0,0 +1,111 @@
+package pagination
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Clause : the SQL of a Query, for a repository to put in its own statement. Columns come
+// from the Spec, values are always placeholders, numbered after the statement's own.
+//
+//	c := q.Clause(1) // $1 is org_id
+//	query := `SELECT ... FROM invitations WHERE org_id = $1 AND ` + c.Where +
+//		` ORDER BY ` + c.OrderBy + ` ` + c.Limit
+//	rows, err := db.QueryContext(ctx, query, append([]any{orgID}, c.Args...)...)
+type Clause struct {
+	// Filter : the filters only, for COUNT(*) with FilterArgs. TRUE without any.
+	Filter     string
+	FilterArgs []any
+
+	// Where : the filters and the start of the keyset page, with Args
+	Where   string
+	OrderBy string
+	// Limit : one row more than the page, to know if there is a next one (see NewPage)
+	Limit string
+	Args  []any
+}
+
+// Clause builds the SQL of q, with placeholders starting at $(n+1).
+func (q *Query) Clause(n int) Clause {
+	b := &clauseBuilder{n: n}
+
+	var filters []string
+	for _, f := range q.filters {
+		if len(f.values) == 1 {
+			filters = append(filters, f.column+" = "+b.arg(f.values[0]))
+			continue
+		}
+		placeholders := make([]string, len(f.values))
+		for i, v := range f.values {
+			placeholders[i] = b.arg(v)
+		}
+		filters = append(filters, f.column+" IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	c := Clause{
+		Filter:     and(filters),
+		FilterArgs: slices.Clip(b.args),
+		OrderBy:    q.orderBy(),
+	}
+
+	where := filters
+	if q.after != nil && q.Page == 0 {
+		where = append(where, q.keyset(b))
+	}
+	c.Where = and(where)
+
+	c.Limit = "LIMIT " + b.arg(q.Limit+1)
+	if q.Page > 0 {
+		c.Limit += " OFFSET " + b.arg((q.Page-1)*q.Limit)
+	}
+	c.Args = b.args
+	return c
+}
+
+func (q *Query) orderBy() string {
+	columns := make([]string, len(q.sort))
+	for i, f := range q.sort {
+		columns[i] = f.Column + " ASC"
+		if f.desc {
+			columns[i] = f.Column + " DESC"
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// keyset : the rows after the cursor in the sort order. Written out column by column,
+// (a > $1) OR (a = $1 AND b < $2), as a row comparison cannot mix directions.
+func (q *Query) keyset(b *clauseBuilder) string {
+	ors := make([]string, len(q.sort))
+	for i, f := range q.sort {
+		ands := make([]string, 0, i+1)
+		for j := range i {
+			ands = append(ands, q.sort[j].Column+" = "+b.arg(q.after[j]))
+		}
+		op := " > "
+		if f.desc {
+			op = " < "
+		}
+		ands = append(ands, f.Column+op+b.arg(q.after[i]))
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	return "(" + strings.Join(ors, " OR ") + ")"
+}
+
+type clauseBuilder struct {
+	n    int
+	args []any
+}
+
+func (b *clauseBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", b.n+len(b.args))
+}
+
+func and(conditions []string) string {
+	if len(conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,23 @@
+package ratelimit
+
+import "time"
+
+// Test hooks for the ratelimit_test package
+
+var Evaluate = evaluate
+
+// NewMemoryStoreAt : a memory store reading the time from now
+func NewMemoryStoreAt(now func() time.Time) Store {
+	return &memoryStore{
+		counters: make(map[string]*counter),
+		now:      now,
+	}
+}
+
+// Counters : how many keys the memory store still holds
+func Counters(s Store) int {
+	m := s.(*memoryStore)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.counters)
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type counter struct {
+	start time.Time
+	prev  int
+	curr  int
+	// expires : when both windows are over and the counter can be dropped
+	expires time.Time
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore returns a process-local Store. Use NewPostgresStore when running several instances.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		counters: make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+func (s *memoryStore) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	start := windowStart(now, limit.Window)
+	c, ok := s.counters[key]
+	if !ok {
+		c = &counter{start: start}
+		s.counters[key] = c
+	}
+
+	// Roll the windows forward
+	switch {
+	case c.start.Equal(start):
+	case c.start.Add(limit.Window).Equal(start):
+		c.prev, c.curr = c.curr, 0
+		c.start = start
+	default:
+		c.prev, c.curr = 0, 0
+		c.start = start
+	}
+
+	c.curr++
+	c.expires = start.Add(2 * limit.Window)
+	return evaluate(limit, now, start, c.prev, c.curr), nil
+}
+
+// sweep : drop expired counters at most once a minute
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	for k, c := range s.counters {
+		if !now.Before(c.expires) {
+			delete(s.counters, k)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sweepInterval : how often an instance deletes expired rows
+const sweepInterval = time.Minute
+
+type postgresStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewPostgresStore returns a Store shared by every instance using the same database
+// (table rate_limits, see migrations).
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (s *postgresStore) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.now()
+	start := windowStart(now, limit.Window)
+	s.sweep(ctx, now)
+
+	// One round trip: count this request, read the previous window
+	query := `
+		WITH curr AS (
+			INSERT INTO rate_limits (key, window_start, count, expires_at)
+			VALUES ($1, $2, 1, $4)
+			ON CONFLICT (key, window_start) DO UPDATE SET count = rate_limits.count + 1
+			RETURNING count
+		)
+		SELECT
+			(SELECT count FROM curr),
+			COALESCE((SELECT count FROM rate_limits WHERE key = $1 AND window_start = $3), 0)
+	`
+	var prev, curr int
+	if err := s.db.QueryRowContext(
+		ctx,
+		query,
+		key,
+		start,
+		start.Add(-limit.Window),
+		start.Add(2*limit.Window),
+	).Scan(&curr, &prev); err != nil {
+		return Result{}, err
+	}
+	return evaluate(limit, now, start, prev, curr), nil
+}
+
+// sweep : delete expired windows at most once per sweepInterval, best effort
+func (s *postgresStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	query := `DELETE FROM rate_limits WHERE expires_at < $1`
+	if _, err := s.db.ExecContext(ctx, query, now); err != nil {
+		slog.WarnContext(ctx, "rate limit sweep failed", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests per Window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func PerSecond(n int) Limit { return Limit{Requests: n, Window: time.Second} }
+func PerMinute(n int) Limit { return Limit{Requests: n, Window: time.Minute} }
+func PerHour(n int) Limit   { return Limit{Requests: n, Window: time.Hour} }
+
+// Result of one request against a limit.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset : time until the current fixed window ends
+	Reset time.Duration
+	// RetryAfter : time until a request would be allowed again, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store counts requests per key with a sliding window counter: the previous fixed window
+// is weighted by how much of it still overlaps the sliding window. Every request is
+// counted, rejected ones included, so a client that keeps hammering stays limited.
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// windowStart : start of the fixed window containing now
+func windowStart(now time.Time, window time.Duration) time.Time {
+	return now.Truncate(window)
+}
+
+// evaluate turns the counts of the previous and current fixed windows into a Result.
+func evaluate(limit Limit, now, start time.Time, prev, curr int) Result {
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(limit.Window)
+	used := float64(prev)*weight + float64(curr)
+
+	res := Result{
+		Allowed:   used <= float64(limit.Requests),
+		Limit:     limit.Requests,
+		Remaining: max(limit.Requests-int(math.Ceil(used)), 0),
+		Reset:     limit.Window - elapsed,
+	}
+
+	if !res.Allowed {
+		res.RetryAfter = retryAfter(limit, elapsed, prev, curr)
+	}
+	return res
+}
+
+// retryAfter : how long until the weighted count drops below the limit again, assuming
+// the client stops sending. Within this window only the previous window's share decays.
+func retryAfter(limit Limit, elapsed time.Duration, prev, curr int) time.Duration {
+	room := float64(limit.Requests - curr)
+	if room >= 1 && prev > 0 {
+		// prev * (1 - t/window) <= room - 1  =>  t >= window * (1 - (room-1)/prev)
+		t := time.Duration(float64(limit.Window) * (1 - (room-1)/float64(prev)))
+		if t > elapsed {
+			return t - elapsed
+		}
+		return 0
+	}
+	// The current window alone is over the limit: it becomes "previous" at the next
+	// window start and decays from there
+	next := limit.Window - elapsed
+	if curr > 0 {
+		t := time.Duration(float64(limit.Window) * (1 - float64(limit.Requests-1)/float64(curr)))
+		return next + max(t, 0)
+	}
+	return next
+}
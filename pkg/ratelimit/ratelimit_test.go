@@ -0,0 +1,120 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	type testCase struct {
+		name     string
+		elapsed  time.Duration
+		prev     int
+		curr     int
+		expected ratelimit.Result
+	}
+
+	limit := ratelimit.PerMinute(10)
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []testCase{
+		{
+			name:     "success previous window half decayed",
+			elapsed:  30 * time.Second,
+			prev:     10,
+			curr:     0,
+			expected: ratelimit.Result{Allowed: true, Limit: 10, Remaining: 5, Reset: 30 * time.Second},
+		},
+		{
+			name:     "success at the limit",
+			elapsed:  30 * time.Second,
+			prev:     10,
+			curr:     5,
+			expected: ratelimit.Result{Allowed: true, Limit: 10, Remaining: 0, Reset: 30 * time.Second},
+		},
+		{
+			name:     "success partial request rounds up",
+			elapsed:  15 * time.Second,
+			prev:     3,
+			curr:     1,
+			expected: ratelimit.Result{Allowed: true, Limit: 10, Remaining: 6, Reset: 45 * time.Second},
+		},
+		{
+			// 10 * 0.5 + 6 = 11; at 42s 10 * 0.3 + 7 = 10 fits again
+			name:     "fail over limit, previous window decays",
+			elapsed:  30 * time.Second,
+			prev:     10,
+			curr:     6,
+			expected: ratelimit.Result{Allowed: false, Limit: 10, Remaining: 0, Reset: 30 * time.Second, RetryAfter: 12 * time.Second},
+		},
+		{
+			name:     "fail over limit at window start",
+			elapsed:  0,
+			prev:     10,
+			curr:     1,
+			expected: ratelimit.Result{Allowed: false, Limit: 10, Remaining: 0, Reset: time.Minute, RetryAfter: 12 * time.Second},
+		},
+		{
+			// Next window: 12 * (1 - t/60s) + 1 <= 10 from t = 15s
+			name:     "fail current window alone over limit",
+			elapsed:  30 * time.Second,
+			prev:     0,
+			curr:     12,
+			expected: ratelimit.Result{Allowed: false, Limit: 10, Remaining: 0, Reset: 30 * time.Second, RetryAfter: 45 * time.Second},
+		},
+	}
+
+	for _, tc := range testCases {
+		res := ratelimit.Evaluate(limit, start.Add(tc.elapsed), start, tc.prev, tc.curr)
+
+		// Float weights: RetryAfter may be off by a rounding error
+		assert.InDelta(t, tc.expected.RetryAfter, res.RetryAfter, float64(time.Millisecond), tc.name)
+		tc.expected.RetryAfter, res.RetryAfter = 0, 0
+		assert.Equal(t, tc.expected, res, tc.name)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	limit := ratelimit.PerMinute(3)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := ratelimit.NewMemoryStoreAt(func() time.Time { return now })
+
+	allow := func(key string) ratelimit.Result {
+		res, err := store.Allow(ctx, key, limit)
+		require.NoError(t, err)
+		return res
+	}
+
+	for i := range 3 {
+		res := allow("ip:10.0.0.1")
+		assert.True(t, res.Allowed, "request %d", i+1)
+		assert.Equal(t, 2-i, res.Remaining, "request %d", i+1)
+	}
+	denied := allow("ip:10.0.0.1")
+	assert.False(t, denied.Allowed)
+	assert.Positive(t, denied.RetryAfter)
+
+	// Keys are counted apart
+	assert.True(t, allow("ip:10.0.0.2").Allowed)
+
+	// Halfway into the next window the previous 4 (the rejected one included) weigh 2
+	now = now.Add(90 * time.Second)
+	assert.True(t, allow("ip:10.0.0.1").Allowed)
+	assert.False(t, allow("ip:10.0.0.1").Allowed)
+
+	// Two windows later nothing is left
+	now = now.Add(2 * time.Minute)
+	res := allow("ip:10.0.0.1")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+
+	// Expired counters are swept, the other key's included
+	assert.Equal(t, 1, ratelimit.Counters(store))
+}
@@ -0,0 +1,93 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+)
+
+const (
+	HeaderName        = "X-Request-ID"
+	TraceParentHeader = "traceparent"
+
+	maxLength = 128
+)
+
+// New returns a random 128-bit ID, hex encoded (the same shape as a W3C trace ID).
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid accepts client IDs that are safe to log and echo: printable ASCII, no spaces.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceParent returns the trace ID of a W3C traceparent header
+// ("00-<32 hex trace-id>-<16 hex parent-id>-<2 hex flags>").
+func ParseTraceParent(header string) (traceID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || parts[0] == "ff" || !isHex(parts[0], 2) {
+		return "", false
+	}
+	// Version 00 has exactly four fields, later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return "", false
+	}
+	traceID, parentID, flags := parts[1], parts[2], parts[3]
+	if !isHex(traceID, 32) || !isHex(parentID, 16) || !isHex(flags, 2) {
+		return "", false
+	}
+	if isZero(traceID) || isZero(parentID) {
+		return "", false
+	}
+	return traceID, true
+}
+
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, config.ContextRequestIDKey, id)
+}
+
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(config.ContextRequestIDKey).(string)
+	return id
+}
+
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, config.ContextTraceIDKey, traceID)
+}
+
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(config.ContextTraceIDKey).(string)
+	return traceID
+}
+
+// isHex : exactly n lower-case hex digits, as W3C requires
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,64 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":            tls.NoClientCert,
+	"request":         tls.RequestClientCert,
+	"verify_if_given": tls.VerifyClientCertIfGiven,
+	"require":         tls.RequireAndVerifyClientCert,
+}
+
+// NewServerTLS builds the server TLS config, verifying client certificates against ClientCAFile when set.
+func NewServerTLS(cfg config.TLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate failed: %w", err)
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.NoClientCert,
+	}
+
+	if cfg.ClientCAFile == "" {
+		return tlsCfg, nil
+	}
+
+	pool, err := LoadCertPool(cfg.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth, ok := clientAuthTypes[cfg.ClientAuth]
+	if !ok {
+		return nil, fmt.Errorf("invalid client auth type: %q", cfg.ClientAuth)
+	}
+	tlsCfg.ClientCAs = pool
+	tlsCfg.ClientAuth = clientAuth
+
+	return tlsCfg, nil
+}
+
+// LoadCertPool reads a PEM bundle of CA certificates.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ca bundle failed: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("ca bundle contains no certificates")
+	}
+	return pool, nil
+}
@@ -0,0 +1,19 @@
+// Package tracingtest holds tracing helpers for tests, kept out of pkg/tracing so the
+// SDK test exporter is not linked into the app.
+package tracingtest
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// NewInMemory installs a global provider that keeps every span in memory.
+// Spans are exported synchronously, so they can be read as soon as they end.
+func NewInMemory() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return exporter
+}
@@ -1,8 +1,15 @@
 package password
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/metrics"
+	"golang.org/x/crypto/bcrypt"
+)
 
 func GenerateHashPassword(pwd string) (string, error) {
+	defer observe("hash", time.Now())
+
 	hashed, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
@@ -11,6 +18,12 @@ func GenerateHashPassword(pwd string) (string, error) {
 }
 
 func CompareHashedPassword(hashed, pwd string) bool {
+	defer observe("compare", time.Now())
+
 	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(pwd))
 	return err == nil
-}
\ No newline at end of file
+}
+
+func observe(op string, start time.Time) {
+	metrics.PasswordHashDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
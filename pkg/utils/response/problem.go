@@ -0,0 +1,53 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/pkg/requestid"
+	"github.com/codepnw/go-starter-kit/pkg/utils/validate"
+	"github.com/gin-gonic/gin"
+)
+
+const ContentTypeProblem = "application/problem+json"
+
+// problemKey : gin key set by UseProblem, holds the type base URL
+const problemKey = "response.problem"
+
+// Problem : RFC 7807 problem details. Code, Errors and RequestID are extension members.
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      string                `json:"code"`
+	Errors    []validate.FieldError `json:"errors,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+}
+
+// UseProblem makes ResponseError answer this request with application/problem+json. The
+// problem type is typeBaseURL followed by the error code, or about:blank without one.
+func UseProblem(c *gin.Context, typeBaseURL string) {
+	c.Set(problemKey, typeBaseURL)
+}
+
+func writeProblem(c *gin.Context, code int, appErr *errs.AppError, fields []validate.FieldError, typeBaseURL string) {
+	problemType := "about:blank"
+	if typeBaseURL != "" {
+		problemType = strings.TrimSuffix(typeBaseURL, "/") + "/" + appErr.Code
+	}
+
+	c.Header("Content-Type", ContentTypeProblem)
+	c.JSON(code, Problem{
+		Type:      problemType,
+		Title:     http.StatusText(code),
+		Status:    code,
+		Detail:    appErr.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      appErr.Code,
+		Errors:    fields,
+		RequestID: requestid.FromContext(c.Request.Context()),
+	})
+}
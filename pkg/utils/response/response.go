@@ -1,6 +1,16 @@
 package response
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/pkg/pagination"
+	"github.com/codepnw/go-starter-kit/pkg/requestid"
+	"github.com/codepnw/go-starter-kit/pkg/utils/validate"
 	"github.com/gin-gonic/gin"
 )
 
@@ -10,10 +20,20 @@ type responseSuccess struct {
 	Data    any  `json:"data"`
 }
 
+type responsePage struct {
+	Success bool            `json:"success"`
+	Code    int             `json:"code"`
+	Data    any             `json:"data"`
+	Meta    pagination.Meta `json:"meta"`
+}
+
 type responseError struct {
-	Success bool   `json:"success"`
-	Code    int    `json:"code"`
-	Error   string `json:"error"`
+	Success   bool                  `json:"success"`
+	Code      int                   `json:"code"`
+	Error     string                `json:"error"`
+	ErrorCode string                `json:"error_code,omitempty"`
+	Errors    []validate.FieldError `json:"errors,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
 }
 
 func ResponseSuccess(c *gin.Context, code int, data any) {
@@ -24,10 +44,100 @@ func ResponseSuccess(c *gin.Context, code int, data any) {
 	})
 }
 
+// ResponsePage answers 200 with the page items as data, its meta and a Link header.
+func ResponsePage[T any](c *gin.Context, page *pagination.Page[T]) {
+	c.Header("Link", page.Meta.Links(c.Request.URL))
+	c.JSON(http.StatusOK, responsePage{
+		Success: true,
+		Code:    http.StatusOK,
+		Data:    page.Items,
+		Meta:    page.Meta,
+	})
+}
+
+// ResponseError answers err with status code, as problem+json when UseProblem was called
+// for the request, in the envelope otherwise. Only the public part of err is shown:
+// the Message of an errs.AppError, or "internal server error" for any other 5xx. Invalid
+// fields are listed under errors, in the language of the Accept-Language header.
 func ResponseError(c *gin.Context, code int, err error) {
+	code, err = requestError(c, code, err)
+	appErr := publicError(c, code, err)
+
+	var fields []validate.FieldError
+	if code < http.StatusInternalServerError {
+		fields = validate.Fields(err, c.GetHeader("Accept-Language"))
+	}
+
+	if typeBaseURL, ok := c.Get(problemKey); ok {
+		writeProblem(c, code, appErr, fields, typeBaseURL.(string))
+		return
+	}
 	c.JSON(code, responseError{
-		Success: false,
-		Code:    code,
-		Error:   err.Error(),
+		Success:   false,
+		Code:      code,
+		Error:     appErr.Message,
+		ErrorCode: appErr.Code,
+		Errors:    fields,
+		RequestID: requestid.FromContext(c.Request.Context()),
 	})
 }
+
+// Error answers err with the status of its errs.AppError, 500 for anything else.
+func Error(c *gin.Context, err error) {
+	code := http.StatusInternalServerError
+	if appErr := errs.From(err); appErr != nil {
+		code = appErr.Status
+	}
+	ResponseError(c, code, err)
+}
+
+// publicError : what the client may see of err. Server errors are logged with their cause.
+func publicError(c *gin.Context, code int, err error) *errs.AppError {
+	appErr := errs.From(err)
+	if code >= http.StatusInternalServerError && (appErr == nil || appErr.Err != nil) {
+		slog.ErrorContext(c.Request.Context(), "request error",
+			slog.Int("status", code),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	switch {
+	case appErr != nil:
+		return appErr
+	case code >= http.StatusInternalServerError:
+		// Never show raw driver or library errors
+		return errs.ErrInternal
+	default:
+		// Middleware rejecting the request (bad header, expired token...) say why
+		return errs.New(statusCode(code), code, err.Error())
+	}
+}
+
+// statusCode : machine code for errors without one, from the status text (bad_request)
+func statusCode(code int) string {
+	text := http.StatusText(code)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// requestError : a body over the route limit or a passed deadline reach handlers as plain
+// errors, usually answered with 400 or 500. Give them their own status instead.
+func requestError(c *gin.Context, code int, err error) (int, error) {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return http.StatusRequestEntityTooLarge, errs.ErrRequestTooLarge
+	}
+	if code < http.StatusInternalServerError {
+		return code, err
+	}
+
+	switch c.Request.Context().Err() {
+	case context.DeadlineExceeded:
+		return http.StatusGatewayTimeout, errs.ErrRequestTimeout
+	case context.Canceled:
+		return http.StatusServiceUnavailable, errs.ErrRequestCanceled
+	}
+	return code, err
+}
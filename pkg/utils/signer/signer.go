@@ -0,0 +1,71 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("signature expired")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Sign returns a URL-safe token binding payload to expiresAt with an HMAC-SHA256 signature.
+func Sign(key []byte, payload string, expiresAt time.Time) string {
+	body := payload + "|" + strconv.FormatInt(expiresAt.Unix(), 10)
+	return encoding.EncodeToString([]byte(body)) + "." + encoding.EncodeToString(mac(key, body))
+}
+
+// Verify checks the signature and expiry of token and returns its payload.
+func Verify(key []byte, token string) (string, error) {
+	encBody, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidSignature
+	}
+
+	body, err := encoding.DecodeString(encBody)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	sig, err := encoding.DecodeString(encSig)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	if !hmac.Equal(sig, mac(key, string(body))) {
+		return "", ErrInvalidSignature
+	}
+
+	idx := strings.LastIndexByte(string(body), '|')
+	if idx < 0 {
+		return "", ErrInvalidSignature
+	}
+	exp, err := strconv.ParseInt(string(body[idx+1:]), 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	if time.Now().After(time.Unix(exp, 0)) {
+		return "", ErrExpired
+	}
+	return string(body[:idx]), nil
+}
+
+// Hash returns the hex SHA-256 of token, suitable for storing instead of the token itself.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func mac(key []byte, body string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
@@ -0,0 +1,76 @@
+package validate
+
+import (
+	"net/mail"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// maxEmailLength : RFC 5321 path limit
+	maxEmailLength = 254
+
+	PasswordMinLength = 8
+	// PasswordMaxLength : bcrypt ignores anything past 72 bytes
+	PasswordMaxLength = 72
+)
+
+// registerRules panics like registerTranslations: a rule that fails to register is a
+// programming error, and skipping it would let every value through
+func registerRules(v *validator.Validate) {
+	rules := map[string]validator.Func{
+		"email":      isEmail, // replaces the built-in rule
+		"password":   isStrongPassword,
+		"singleline": isSingleLine,
+	}
+	for tag, fn := range rules {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			panic("validate: register " + tag + " rule: " + err.Error())
+		}
+	}
+}
+
+// isEmail : a bare address (no display name) with a dotted domain
+func isEmail(fl validator.FieldLevel) bool {
+	s := fl.Field().String()
+	if len(s) > maxEmailLength {
+		return false
+	}
+
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	_, domain, _ := strings.Cut(s, "@")
+	return strings.Contains(strings.Trim(domain, "."), ".")
+}
+
+// isSingleLine : no line breaks or other control characters, for names that end up in
+// headers (an organization name in a mail subject)
+func isSingleLine(fl validator.FieldLevel) bool {
+	return !strings.ContainsFunc(fl.Field().String(), unicode.IsControl)
+}
+
+// isStrongPassword : PasswordMinLength to PasswordMaxLength bytes, with an upper case
+// letter, a lower case letter and a digit
+func isStrongPassword(fl validator.FieldLevel) bool {
+	s := fl.Field().String()
+	if len(s) < PasswordMinLength || len(s) > PasswordMaxLength {
+		return false
+	}
+
+	var upper, lower, digit bool
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
@@ -0,0 +1,136 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/th"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	th_translations "github.com/go-playground/validator/v10/translations/th"
+	"golang.org/x/text/language"
+)
+
+// FieldError : one invalid field of a request, named as the client sent it
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ruleType : a value of the wrong JSON type, which fails before any rule runs
+const ruleType = "type"
+
+var (
+	uni = ut.New(en.New(), en.New(), th.New())
+
+	// languages : the ones with translations, the first is the fallback
+	languages = []language.Tag{language.English, language.Thai}
+	matcher   = language.NewMatcher(languages)
+)
+
+// translations : messages for the custom rules, by locale
+var translations = map[string]map[string]string{
+	"en": {
+		"password":   "{0} must be 8 to 72 characters with an upper case letter, a lower case letter and a digit",
+		"singleline": "{0} must be a single line of text",
+		ruleType:     "{0} must be of type {1}",
+	},
+	"th": {
+		"password":   "{0} ต้องมีความยาว 8 ถึง 72 ตัวอักษร และมีตัวพิมพ์ใหญ่ ตัวพิมพ์เล็ก และตัวเลข",
+		"singleline": "{0} ต้องเป็นข้อความบรรทัดเดียว",
+		ruleType:     "{0} ต้องเป็นชนิด {1}",
+	},
+}
+
+func registerTranslations(v *validator.Validate) {
+	register := map[string]func(*validator.Validate, ut.Translator) error{
+		"en": en_translations.RegisterDefaultTranslations,
+		"th": th_translations.RegisterDefaultTranslations,
+	}
+
+	for locale, registerDefaults := range register {
+		trans, _ := uni.GetTranslator(locale)
+		if err := registerDefaults(v, trans); err != nil {
+			panic("validate: register " + locale + " translations: " + err.Error())
+		}
+		for rule, text := range translations[locale] {
+			if err := trans.Add(rule, text, true); err != nil {
+				panic("validate: register " + locale + " translations: " + err.Error())
+			}
+		}
+		for _, rule := range []string{"password", "singleline"} {
+			if err := v.RegisterTranslation(rule, trans, noopRegister, translateRule); err != nil {
+				panic("validate: register " + locale + " translations: " + err.Error())
+			}
+		}
+	}
+}
+
+// noopRegister : the text is added in registerTranslations
+func noopRegister(ut.Translator) error { return nil }
+
+func translateRule(trans ut.Translator, fe validator.FieldError) string {
+	msg, err := trans.T(fe.Tag(), fe.Field())
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
+
+// translator : the best match for an Accept-Language header, English when nothing matches
+func translator(acceptLanguage string) ut.Translator {
+	tag, _ := language.MatchStrings(matcher, acceptLanguage)
+	base, _ := tag.Base()
+	if trans, ok := uni.GetTranslator(base.String()); ok {
+		return trans
+	}
+	return uni.GetFallback()
+}
+
+// Fields lists what is wrong with each field in err, a validation or JSON type error,
+// with messages in the language of acceptLanguage. Any other error gives nil.
+func Fields(err error, acceptLanguage string) []FieldError {
+	trans := translator(acceptLanguage)
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: fe.Translate(trans),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		msg, tErr := trans.T(ruleType, typeErr.Field, typeErr.Type.String())
+		if tErr != nil {
+			msg = typeErr.Error()
+		}
+		return []FieldError{{Field: typeErr.Field, Rule: ruleType, Message: msg}}
+	}
+	return nil
+}
+
+// HasFields reports whether Fields lists anything for err.
+func HasFields(err error) bool {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &validationErrs) || errors.As(err, &typeErr) && typeErr.Field != ""
+}
+
+// fieldPath : the namespace without the top struct, e.g. "redirect_uris[0]"
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
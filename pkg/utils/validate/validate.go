@@ -1,9 +1,82 @@
 package validate
 
-import "github.com/go-playground/validator/v10"
+import (
+	"reflect"
+	"strings"
 
-var v = validator.New()
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// v : the one validator, used by Struct and by Gin binding (see Gin). Rules come from
+// the `validate` tag, fields are named after their json, uri or form tag.
+var v = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(fieldName)
+	registerRules(v)
+	registerTranslations(v)
+	return v
+}
 
 func Struct(input any) error {
 	return v.Struct(input)
 }
+
+// fieldName : the name clients know the field by, the Go name when there is none
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "uri", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Gin returns the shared validator for binding.Validator, so ShouldBindJSON and
+// ShouldBindUri check the same `validate` rules as Struct.
+func Gin() binding.StructValidator {
+	return ginValidator{}
+}
+
+type ginValidator struct{}
+
+// ValidateStruct : the same kinds as Gin's default validator, structs and slices of them
+func (g ginValidator) ValidateStruct(obj any) error {
+	if obj == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(obj)
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.Elem().Kind() != reflect.Struct {
+			return g.ValidateStruct(value.Elem().Interface())
+		}
+		return v.Struct(obj)
+	case reflect.Struct:
+		return v.Struct(obj)
+	case reflect.Slice, reflect.Array:
+		var errs binding.SliceValidationError
+		for i := range value.Len() {
+			if err := g.ValidateStruct(value.Index(i).Interface()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	default:
+		return nil
+	}
+}
+
+func (ginValidator) Engine() any {
+	return v
+}
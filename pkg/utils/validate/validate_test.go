@@ -0,0 +1,136 @@
+package validate_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/pkg/utils/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signupReq struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,password"`
+	OrgName  string `json:"org_name" validate:"omitempty,singleline"`
+}
+
+func TestRules(t *testing.T) {
+	type testCase struct {
+		name          string
+		req           signupReq
+		expectedRules []string
+	}
+
+	valid := signupReq{Email: "john@mail.com", Password: "Password1", OrgName: "Acme Co."}
+	with := func(fn func(r *signupReq)) signupReq {
+		r := valid
+		fn(&r)
+		return r
+	}
+
+	testCases := []testCase{
+		{name: "success valid", req: valid},
+		{name: "success thai org name", req: with(func(r *signupReq) { r.OrgName = "บริษัท เอ็กซ์ จำกัด" })},
+		{name: "success subdomain email", req: with(func(r *signupReq) { r.Email = "john.doe+tag@mail.example.co.th" })},
+		{name: "fail email without dotted domain", req: with(func(r *signupReq) { r.Email = "john@localhost" }), expectedRules: []string{"email"}},
+		{name: "fail email with display name", req: with(func(r *signupReq) { r.Email = "John <john@mail.com>" }), expectedRules: []string{"email"}},
+		{name: "fail email without at", req: with(func(r *signupReq) { r.Email = "john.mail.com" }), expectedRules: []string{"email"}},
+		{name: "fail email too long", req: with(func(r *signupReq) { r.Email = strings.Repeat("a", 250) + "@mail.com" }), expectedRules: []string{"email"}},
+		{name: "success password at max length", req: with(func(r *signupReq) { r.Password = "Aa1" + strings.Repeat("x", 69) })},
+		{name: "fail password too short", req: with(func(r *signupReq) { r.Password = "Passw1" }), expectedRules: []string{"password"}},
+		{name: "fail password over bcrypt limit", req: with(func(r *signupReq) { r.Password = "Aa1" + strings.Repeat("x", 70) }), expectedRules: []string{"password"}},
+		{name: "fail password without upper case", req: with(func(r *signupReq) { r.Password = "password1" }), expectedRules: []string{"password"}},
+		{name: "fail password without lower case", req: with(func(r *signupReq) { r.Password = "PASSWORD1" }), expectedRules: []string{"password"}},
+		{name: "fail password without digit", req: with(func(r *signupReq) { r.Password = "Passwords" }), expectedRules: []string{"password"}},
+		{name: "fail org name with line break", req: with(func(r *signupReq) { r.OrgName = "Acme\r\nBcc: evil@mail.com" }), expectedRules: []string{"singleline"}},
+		{name: "fail org name with tab", req: with(func(r *signupReq) { r.OrgName = "Acme\tCo" }), expectedRules: []string{"singleline"}},
+		{name: "fail required", req: signupReq{}, expectedRules: []string{"required", "required"}},
+	}
+
+	for _, tc := range testCases {
+		err := validate.Struct(tc.req)
+
+		if len(tc.expectedRules) == 0 {
+			assert.NoError(t, err, tc.name)
+			continue
+		}
+		var rules []string
+		for _, f := range validate.Fields(err, "") {
+			rules = append(rules, f.Rule)
+		}
+		assert.Equal(t, tc.expectedRules, rules, tc.name)
+	}
+}
+
+func TestFields(t *testing.T) {
+	type testCase struct {
+		name           string
+		acceptLanguage string
+		expected       []validate.FieldError
+	}
+
+	req := signupReq{Email: "john@mail.com", Password: "password", OrgName: "Acme\nCo"}
+
+	testCases := []testCase{
+		{
+			name: "success english by default",
+			expected: []validate.FieldError{
+				{Field: "password", Rule: "password", Message: "password must be 8 to 72 characters with an upper case letter, a lower case letter and a digit"},
+				{Field: "org_name", Rule: "singleline", Message: "org_name must be a single line of text"},
+			},
+		},
+		{
+			name:           "success thai",
+			acceptLanguage: "th-TH,th;q=0.9,en;q=0.8",
+			expected: []validate.FieldError{
+				{Field: "password", Rule: "password", Message: "password ต้องมีความยาว 8 ถึง 72 ตัวอักษร และมีตัวพิมพ์ใหญ่ ตัวพิมพ์เล็ก และตัวเลข"},
+				{Field: "org_name", Rule: "singleline", Message: "org_name ต้องเป็นข้อความบรรทัดเดียว"},
+			},
+		},
+		{
+			name:           "success unsupported language falls back to english",
+			acceptLanguage: "fr-FR",
+			expected: []validate.FieldError{
+				{Field: "password", Rule: "password", Message: "password must be 8 to 72 characters with an upper case letter, a lower case letter and a digit"},
+				{Field: "org_name", Rule: "singleline", Message: "org_name must be a single line of text"},
+			},
+		},
+	}
+
+	err := validate.Struct(req)
+	require.Error(t, err)
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, validate.Fields(err, tc.acceptLanguage), tc.name)
+	}
+}
+
+func TestFieldsBuiltinRules(t *testing.T) {
+	err := validate.Struct(signupReq{})
+	require.Error(t, err)
+
+	en := validate.Fields(err, "en")
+	th := validate.Fields(err, "th")
+	require.Len(t, en, 2)
+	require.Len(t, th, 2)
+
+	// The built-in rules are translated too
+	assert.Equal(t, "email is a required field", en[0].Message)
+	assert.NotEqual(t, en[0].Message, th[0].Message)
+	assert.Contains(t, th[0].Message, "email")
+}
+
+func TestFieldsTypeError(t *testing.T) {
+	var req signupReq
+	err := json.Unmarshal([]byte(`{"email":42}`), &req)
+	require.Error(t, err)
+
+	assert.Equal(t, []validate.FieldError{
+		{Field: "email", Rule: "type", Message: "email must be of type string"},
+	}, validate.Fields(err, ""))
+	assert.Equal(t, "email ต้องเป็นชนิด string", validate.Fields(err, "th")[0].Message)
+	assert.True(t, validate.HasFields(err))
+	assert.False(t, validate.HasFields(assert.AnError))
+}